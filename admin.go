@@ -118,6 +118,15 @@ type ClusterAdmin interface {
 	// This operation is supported by brokers with version 0.11.0.0 or higher.
 	DeleteACL(filter AclFilter, validateOnly bool) ([]MatchingAcl, error)
 
+	// DescribeAuthorizedOperations returns the operations the principal of the
+	// client is authorized to perform on the cluster and on the given topics
+	// and groups, see AuthorizedOperations.Check to fail fast when some are
+	// missing. With nil or empty topics, only the operations on the cluster
+	// and on the groups are reported. The operations are reported by the brokers since 2.3, older
+	// brokers require the Describe operation on the cluster to compute them
+	// from the ACLs, see AuthorizedOperationsOptions.
+	DescribeAuthorizedOperations(topics, groups []string, options *AuthorizedOperationsOptions) (*AuthorizedOperations, error)
+
 	// ElectLeaders allows to trigger the election of preferred leaders for a set of partitions.
 	ElectLeaders(ElectionType, map[string][]int32) (map[string]map[int32]*PartitionResult, error)
 
@@ -150,6 +159,14 @@ type ClusterAdmin interface {
 	// This operation is supported by brokers with version 0.10.1.0 or higher.
 	ListOffsets(partitions map[string]map[int32]int64, options *ListOffsetsOptions) (map[string]map[int32]*OffsetResult, error)
 
+	// ListConsumerGroupLag computes the lag of a consumer group's committed
+	// offsets behind the end of each partition, using ListConsumerGroupOffsets
+	// and ListOffsets. A nil topicPartitions computes the lag of every
+	// partition the group has committed offsets for. Set
+	// options.IsolationLevel to ReadCommitted to measure the lag against the
+	// last stable offset rather than the high water mark.
+	ListConsumerGroupLag(group string, topicPartitions map[string][]int32, options *ListOffsetsOptions) (map[string]map[int32]*ConsumerGroupLag, error)
+
 	// AlterConsumerGroupOffsets alters offsets for the specified group by committing the provided offsets and metadata.
 	// The request targets the group's coordinator and returns per-partition results in the response.
 	// This operation is not transactional so it may succeed for some partitions while fail for others.
@@ -334,7 +351,7 @@ func (ca *clusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateO
 	}
 
 	request := NewCreateTopicsRequest(
-		ca.conf.Version,
+		ca.conf.version(),
 		topicDetails,
 		ca.conf.Admin.Timeout,
 		validateOnly,
@@ -374,7 +391,7 @@ func (ca *clusterAdmin) DescribeTopics(topics []string) (metadata []*TopicMetada
 		if err != nil {
 			return err
 		}
-		request := NewMetadataRequest(ca.conf.Version, topics)
+		request := NewMetadataRequest(ca.conf.version(), topics)
 		response, err = controller.GetMetadata(request)
 		if isRetriableControllerError(err) {
 			_, _ = ca.refreshController()
@@ -388,7 +405,7 @@ func (ca *clusterAdmin) DescribeTopics(topics []string) (metadata []*TopicMetada
 }
 
 func (ca *clusterAdmin) DescribeCluster() (brokers []*Broker, controllerID int32, err error) {
-	if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+	if ca.conf.version().IsAtLeast(V2_8_0_0) {
 		brokers, controllerID, err = ca.describeClusterUsingAPI()
 		if err == nil {
 			return brokers, controllerID, nil
@@ -408,7 +425,7 @@ func (ca *clusterAdmin) describeClusterUsingAPI() (brokers []*Broker, controller
 			return err
 		}
 
-		request := NewDescribeClusterRequest(ca.conf.Version)
+		request := NewDescribeClusterRequest(ca.conf.version())
 		response, err = controller.DescribeCluster(request)
 		if err != nil {
 			return err
@@ -440,7 +457,7 @@ func (ca *clusterAdmin) describeClusterUsingMetadata() (brokers []*Broker, contr
 			return err
 		}
 
-		request := NewMetadataRequest(ca.conf.Version, nil)
+		request := NewMetadataRequest(ca.conf.version(), nil)
 		response, err = controller.GetMetadata(request)
 		if isRetriableControllerError(err) {
 			_, _ = ca.refreshController()
@@ -508,7 +525,7 @@ func (ca *clusterAdmin) ListTopics() (map[string]TopicDetail, error) {
 		}
 		_ = b.Open(ca.client.Config())
 
-		metadataReq := NewMetadataRequest(ca.conf.Version, nil)
+		metadataReq := NewMetadataRequest(ca.conf.version(), nil)
 		metadataResp, err := b.GetMetadata(metadataReq)
 		if err != nil {
 			if isTimeoutError(err) {
@@ -545,13 +562,13 @@ func (ca *clusterAdmin) ListTopics() (map[string]TopicDetail, error) {
 			Resources: describeConfigsResources,
 		}
 
-		if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+		if ca.conf.version().IsAtLeast(V2_8_0_0) {
 			describeConfigsReq.Version = 4
-		} else if ca.conf.Version.IsAtLeast(V2_6_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_6_0_0) {
 			describeConfigsReq.Version = 3
-		} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 			describeConfigsReq.Version = 2
-		} else if ca.conf.Version.IsAtLeast(V1_1_0_0) {
+		} else if ca.conf.version().IsAtLeast(V1_1_0_0) {
 			describeConfigsReq.Version = 1
 		}
 
@@ -594,7 +611,7 @@ func (ca *clusterAdmin) DeleteTopic(topic string) error {
 	}
 
 	request := NewDeleteTopicsRequest(
-		ca.conf.Version,
+		ca.conf.version(),
 		[]string{topic},
 		ca.conf.Admin.Timeout,
 	)
@@ -643,11 +660,11 @@ func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [
 		Timeout:         ca.conf.Admin.Timeout,
 		ValidateOnly:    validateOnly,
 	}
-	if ca.conf.Version.IsAtLeast(V2_7_0_0) {
+	if ca.conf.version().IsAtLeast(V2_7_0_0) {
 		request.Version = 3
-	} else if ca.conf.Version.IsAtLeast(V2_5_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_5_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -788,9 +805,9 @@ func (ca *clusterAdmin) DeleteRecords(topic string, partitionOffsets map[int32]i
 			Topics:  topics,
 			Timeout: ca.conf.Admin.Timeout,
 		}
-		if ca.conf.Version.IsAtLeast(V2_6_0_0) {
+		if ca.conf.version().IsAtLeast(V2_6_0_0) {
 			request.Version = 2
-		} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 			request.Version = 1
 		}
 		rsp, err := broker.DeleteRecords(request)
@@ -912,13 +929,13 @@ func (ca *clusterAdmin) DescribeConfigs(resources []*ConfigResource, options Des
 			IncludeDocumentation: options.IncludeDocumentation,
 		}
 
-		if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+		if ca.conf.version().IsAtLeast(V2_8_0_0) {
 			request.Version = 4
-		} else if ca.conf.Version.IsAtLeast(V2_6_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_6_0_0) {
 			request.Version = 3
-		} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 			request.Version = 2
-		} else if ca.conf.Version.IsAtLeast(V1_1_0_0) {
+		} else if ca.conf.version().IsAtLeast(V1_1_0_0) {
 			request.Version = 1
 		}
 
@@ -965,10 +982,10 @@ func (ca *clusterAdmin) AlterConfig(resourceType ConfigResourceType, name string
 		Resources:    resources,
 		ValidateOnly: validateOnly,
 	}
-	if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+	if ca.conf.version().IsAtLeast(V2_8_0_0) {
 		// Version 2 enables flexible versions.
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1021,7 +1038,7 @@ func (ca *clusterAdmin) IncrementalAlterConfig(resourceType ConfigResourceType,
 		ValidateOnly: validateOnly,
 	}
 
-	if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+	if ca.conf.version().IsAtLeast(V2_4_0_0) {
 		request.Version = 1
 	}
 
@@ -1070,9 +1087,9 @@ func (ca *clusterAdmin) CreateACL(resource Resource, acl Acl) error {
 	acls = append(acls, &AclCreation{resource, acl})
 	request := &CreateAclsRequest{AclCreations: acls}
 
-	if ca.conf.Version.IsAtLeast(V2_5_0_0) {
+	if ca.conf.version().IsAtLeast(V2_5_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1099,7 +1116,7 @@ func (ca *clusterAdmin) CreateACLs(resourceACLs []*ResourceAcls) error {
 	}
 	request := &CreateAclsRequest{AclCreations: acls}
 
-	if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1120,9 +1137,9 @@ func (ca *clusterAdmin) CreateACLs(resourceACLs []*ResourceAcls) error {
 func (ca *clusterAdmin) ListAcls(filter AclFilter) ([]ResourceAcls, error) {
 	request := &DescribeAclsRequest{AclFilter: filter}
 
-	if ca.conf.Version.IsAtLeast(V2_5_0_0) {
+	if ca.conf.version().IsAtLeast(V2_5_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1158,9 +1175,9 @@ func (ca *clusterAdmin) DeleteACL(filter AclFilter, validateOnly bool) ([]Matchi
 	filters = append(filters, &filter)
 	request := &DeleteAclsRequest{Filters: filters}
 
-	if ca.conf.Version.IsAtLeast(V2_5_0_0) {
+	if ca.conf.version().IsAtLeast(V2_5_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1200,9 +1217,9 @@ func (ca *clusterAdmin) ElectLeaders(electionType ElectionType, partitions map[s
 		TimeoutMs:       int32(60000),
 	}
 
-	if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+	if ca.conf.version().IsAtLeast(V2_4_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V0_11_0_0) {
+	} else if ca.conf.version().IsAtLeast(V0_11_0_0) {
 		request.Version = 1
 	}
 
@@ -1247,17 +1264,17 @@ func (ca *clusterAdmin) DescribeConsumerGroups(groups []string) (result []*Group
 			Groups: brokerGroups,
 		}
 
-		if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+		if ca.conf.version().IsAtLeast(V2_4_0_0) {
 			// Starting in version 4, the response will include group.instance.id info for members.
 			// Starting in version 5, the response uses flexible encoding
 			describeReq.Version = 5
-		} else if ca.conf.Version.IsAtLeast(V2_3_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_3_0_0) {
 			// Starting in version 3, authorized operations can be requested.
 			describeReq.Version = 3
-		} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 			// Version 2 is the same as version 0.
 			describeReq.Version = 2
-		} else if ca.conf.Version.IsAtLeast(V1_1_0_0) {
+		} else if ca.conf.version().IsAtLeast(V1_1_0_0) {
 			// Version 1 is the same as version 0.
 			describeReq.Version = 1
 		}
@@ -1287,19 +1304,19 @@ func (ca *clusterAdmin) ListConsumerGroups() (allGroups map[string]string, err e
 			_ = b.Open(conf) // Ensure that broker is opened
 
 			request := &ListGroupsRequest{}
-			if ca.conf.Version.IsAtLeast(V3_8_0_0) {
+			if ca.conf.version().IsAtLeast(V3_8_0_0) {
 				// Version 5 adds the TypesFilter field (KIP-848).
 				request.Version = 5
-			} else if ca.conf.Version.IsAtLeast(V2_6_0_0) {
+			} else if ca.conf.version().IsAtLeast(V2_6_0_0) {
 				// Version 4 adds the StatesFilter field (KIP-518).
 				request.Version = 4
-			} else if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+			} else if ca.conf.version().IsAtLeast(V2_4_0_0) {
 				// Version 3 is the first flexible version.
 				request.Version = 3
-			} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+			} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 				// Version 2 is the same as version 0.
 				request.Version = 2
-			} else if ca.conf.Version.IsAtLeast(V0_11_0_0) {
+			} else if ca.conf.version().IsAtLeast(V0_11_0_0) {
 				// Version 1 is the same as version 0.
 				request.Version = 1
 			}
@@ -1329,7 +1346,7 @@ func (ca *clusterAdmin) ListConsumerGroups() (allGroups map[string]string, err e
 
 func (ca *clusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error) {
 	var response *OffsetFetchResponse
-	request := NewOffsetFetchRequest(ca.conf.Version, group, topicPartitions)
+	request := NewOffsetFetchRequest(ca.conf.version(), group, topicPartitions)
 	err := ca.retryOnError(isRetriableGroupCoordinatorError, func() (err error) {
 		defer func() {
 			if err != nil && isRetriableGroupCoordinatorError(err) {
@@ -1391,7 +1408,7 @@ func (ca *clusterAdmin) ListConsumerGroupOffsetsBatch(groupTopics map[string]map
 
 		clear(result)
 		for _, batch := range batches {
-			req := NewOffsetFetchRequest(ca.conf.Version, "", nil)
+			req := NewOffsetFetchRequest(ca.conf.version(), "", nil)
 			req.Groups = batch.groups
 			if _, ok := batch.broker.negotiateApiVersion(req, 8); !ok {
 				return ErrUnsupportedVersion
@@ -1460,9 +1477,9 @@ func (ca *clusterAdmin) DeleteConsumerGroup(group string) error {
 		Groups: []string{group},
 	}
 
-	if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+	if ca.conf.version().IsAtLeast(V2_4_0_0) {
 		request.Version = 2
-	} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 		request.Version = 1
 	}
 
@@ -1518,13 +1535,13 @@ func (ca *clusterAdmin) DescribeLogDirs(brokerIds []int32) (allLogDirs map[int32
 			_ = b.Open(conf) // Ensure that broker is opened
 
 			request := &DescribeLogDirsRequest{}
-			if ca.conf.Version.IsAtLeast(V3_3_0_0) {
+			if ca.conf.version().IsAtLeast(V3_3_0_0) {
 				request.Version = 4
-			} else if ca.conf.Version.IsAtLeast(V3_2_0_0) {
+			} else if ca.conf.version().IsAtLeast(V3_2_0_0) {
 				request.Version = 3
-			} else if ca.conf.Version.IsAtLeast(V2_6_0_0) {
+			} else if ca.conf.version().IsAtLeast(V2_6_0_0) {
 				request.Version = 2
-			} else if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+			} else if ca.conf.version().IsAtLeast(V2_0_0_0) {
 				request.Version = 1
 			}
 			response, err := b.DescribeLogDirs(request)
@@ -1665,7 +1682,7 @@ func (ca *clusterAdmin) UpdateFeatures(featureUpdates []FeatureUpdate) ([]Updata
 // Contains only components: strict = true
 func (ca *clusterAdmin) DescribeClientQuotas(components []QuotaFilterComponent, strict bool) ([]DescribeClientQuotasEntry, error) {
 	request := NewDescribeClientQuotasRequest(
-		ca.conf.Version,
+		ca.conf.version(),
 		components,
 		strict,
 	)
@@ -1700,7 +1717,7 @@ func (ca *clusterAdmin) AlterClientQuotas(entity []QuotaEntityComponent, op Clie
 		Entries:      []AlterClientQuotasEntry{entry},
 		ValidateOnly: validateOnly,
 	}
-	if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+	if ca.conf.version().IsAtLeast(V2_8_0_0) {
 		// Version 1 enables flexible versions.
 		request.Version = 1
 	}
@@ -1728,7 +1745,7 @@ func (ca *clusterAdmin) AlterClientQuotas(entity []QuotaEntityComponent, op Clie
 }
 
 func (ca *clusterAdmin) RemoveMemberFromConsumerGroup(group string, groupInstanceIds []string) (*LeaveGroupResponse, error) {
-	if !ca.conf.Version.IsAtLeast(V2_4_0_0) {
+	if !ca.conf.version().IsAtLeast(V2_4_0_0) {
 		return nil, ConfigurationError("Removing members from a consumer group headers requires Kafka version of at least v2.4.0")
 	}
 	var response *LeaveGroupResponse
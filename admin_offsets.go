@@ -30,6 +30,21 @@ type OffsetAndMetadata struct {
 	LeaderEpoch int32
 }
 
+// ConsumerGroupLag describes the lag of a consumer group's committed offset
+// for a single partition.
+type ConsumerGroupLag struct {
+	// CommittedOffset is the group's committed offset, or -1 if the group has
+	// not committed an offset for the partition.
+	CommittedOffset int64
+	// EndOffset is the high water mark (or last stable offset when using
+	// ReadCommitted) of the partition.
+	EndOffset int64
+	// Lag is EndOffset minus CommittedOffset, or -1 if either is unknown.
+	Lag int64
+	// Err is set if either offset could not be retrieved.
+	Err error
+}
+
 // AlterConsumerGroupOffsetsOptions configures how offsets are committed.
 // It is currently empty and reserved for future Kafka protocol options
 type AlterConsumerGroupOffsetsOptions struct{}
@@ -83,7 +98,7 @@ func (ca *clusterAdmin) ListOffsets(partitions map[string]map[int32]int64, optio
 			req := requests[broker]
 			if req == nil {
 				req = &brokerOffsetRequest{
-					request: NewOffsetRequest(ca.conf.Version),
+					request: NewOffsetRequest(ca.conf.version()),
 				}
 				req.request.IsolationLevel = options.IsolationLevel
 				requests[broker] = req
@@ -206,3 +221,54 @@ func (ca *clusterAdmin) AlterConsumerGroupOffsets(group string, offsets map[stri
 
 	return response, err
 }
+
+func (ca *clusterAdmin) ListConsumerGroupLag(group string, topicPartitions map[string][]int32, options *ListOffsetsOptions) (map[string]map[int32]*ConsumerGroupLag, error) {
+	committed, err := ca.ListConsumerGroupOffsets(group, topicPartitions)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := committed.Blocks
+	if g := committed.GetGroup(group); g != nil {
+		blocks = g.Blocks
+	}
+
+	result := make(map[string]map[int32]*ConsumerGroupLag, len(blocks))
+	query := make(map[string]map[int32]int64, len(blocks))
+	for topic, partitions := range blocks {
+		result[topic] = make(map[int32]*ConsumerGroupLag, len(partitions))
+		query[topic] = make(map[int32]int64, len(partitions))
+		for partition, block := range partitions {
+			lag := &ConsumerGroupLag{CommittedOffset: block.Offset, EndOffset: -1, Lag: -1}
+			if !errors.Is(block.Err, ErrNoError) {
+				lag.Err = block.Err
+			}
+			result[topic][partition] = lag
+			query[topic][partition] = OffsetNewest
+		}
+	}
+	if len(query) == 0 {
+		return result, nil
+	}
+
+	ends, err := ca.ListOffsets(query, options)
+	for topic, partitions := range result {
+		for partition, lag := range partitions {
+			end := ends[topic][partition]
+			switch {
+			case end == nil:
+				continue
+			case end.Err != nil && !errors.Is(end.Err, ErrNoError):
+				if lag.Err == nil {
+					lag.Err = end.Err
+				}
+				continue
+			}
+			lag.EndOffset = end.Offset
+			if lag.Err == nil && lag.CommittedOffset >= 0 {
+				lag.Lag = max(lag.EndOffset-lag.CommittedOffset, 0)
+			}
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,445 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// authorizedOperationsOmitted is the value of the authorized operations of a
+// resource when they were not requested or could not be computed.
+const authorizedOperationsOmitted = math.MinInt32
+
+// AuthorizedOperationsOptions configures DescribeAuthorizedOperations.
+type AuthorizedOperationsOptions struct {
+	// Principal is the principal of the client, e.g. "User:alice". It is only
+	// used with brokers older than 2.3, which cannot report the authorized
+	// operations of a resource: they are then computed from the ACLs
+	// described with DescribeAcls, which requires the Describe operation on
+	// the cluster. It defaults to "User:" followed by Net.SASL.User with the
+	// PLAIN and SCRAM mechanisms, and must be set with any other mechanism or
+	// with a Net.SASL.CredentialsProvider, as the principal cannot be told
+	// from the configuration then.
+	Principal string
+}
+
+// ResourceOperations are the operations the principal of the client is
+// authorized to perform on a resource.
+type ResourceOperations struct {
+	// Operations are the authorized operations, in ascending order.
+	Operations []AclOperation
+	// Err is the error reported for the resource, e.g.
+	// ErrTopicAuthorizationFailed if the principal cannot describe the topic
+	// or ErrUnknownTopicOrPartition if it does not exist.
+	Err error
+}
+
+// Allows reports whether operation is authorized.
+func (r *ResourceOperations) Allows(operation AclOperation) bool {
+	return r != nil && r.Err == nil && slices.Contains(r.Operations, operation)
+}
+
+// AuthorizedOperations are the operations the principal of the client is
+// authorized to perform, see ClusterAdmin.DescribeAuthorizedOperations.
+type AuthorizedOperations struct {
+	Cluster *ResourceOperations
+	Topics  map[string]*ResourceOperations
+	Groups  map[string]*ResourceOperations
+}
+
+// PermissionRequirements are the operations a service needs, see
+// AuthorizedOperations.Check.
+type PermissionRequirements struct {
+	Cluster []AclOperation
+	Topics  map[string][]AclOperation
+	Groups  map[string][]AclOperation
+}
+
+// MissingPermission is an operation required on a resource that the
+// principal of the client is not authorized to perform.
+type MissingPermission struct {
+	ResourceType AclResourceType
+	ResourceName string
+	Operation    AclOperation
+	// Err is the error reported for the resource, if any.
+	Err error
+}
+
+// MissingPermissionsError is returned by AuthorizedOperations.Check when some
+// required operations are not authorized. It matches
+// ErrClusterAuthorizationFailed, ErrTopicAuthorizationFailed and
+// ErrGroupAuthorizationFailed with errors.Is, depending on the resources.
+type MissingPermissionsError struct {
+	Missing []MissingPermission
+}
+
+func (e *MissingPermissionsError) Error() string {
+	missing := make([]string, 0, len(e.Missing))
+	for _, m := range e.Missing {
+		s := fmt.Sprintf("%s on %s %q", m.Operation.String(), m.ResourceType.String(), m.ResourceName)
+		if m.Err != nil {
+			s += fmt.Sprintf(" (%v)", m.Err)
+		}
+		missing = append(missing, s)
+	}
+	return "kafka: missing permissions: " + strings.Join(missing, ", ")
+}
+
+// Unwrap returns the authorization errors of the resource types with missing
+// permissions.
+func (e *MissingPermissionsError) Unwrap() []error {
+	var errs []error
+	for _, m := range e.Missing {
+		err := authorizationError(m.ResourceType)
+		if !slices.Contains(errs, err) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func authorizationError(resourceType AclResourceType) error {
+	switch resourceType {
+	case AclResourceTopic:
+		return ErrTopicAuthorizationFailed
+	case AclResourceGroup:
+		return ErrGroupAuthorizationFailed
+	default:
+		return ErrClusterAuthorizationFailed
+	}
+}
+
+// Check returns a MissingPermissionsError listing the required operations
+// that are not authorized, or nil if they all are.
+func (a *AuthorizedOperations) Check(requirements PermissionRequirements) error {
+	var missing []MissingPermission
+	check := func(resourceType AclResourceType, name string, resource *ResourceOperations, operations []AclOperation) {
+		for _, operation := range operations {
+			if resource.Allows(operation) {
+				continue
+			}
+			m := MissingPermission{ResourceType: resourceType, ResourceName: name, Operation: operation}
+			if resource == nil {
+				m.Err = fmt.Errorf("the operations authorized on %s %q were not described", resourceType.String(), name)
+			} else {
+				m.Err = resource.Err
+			}
+			missing = append(missing, m)
+		}
+	}
+
+	check(AclResourceCluster, "kafka-cluster", a.Cluster, requirements.Cluster)
+	for _, topic := range sortedKeys(requirements.Topics) {
+		check(AclResourceTopic, topic, a.Topics[topic], requirements.Topics[topic])
+	}
+	for _, group := range sortedKeys(requirements.Groups) {
+		check(AclResourceGroup, group, a.Groups[group], requirements.Groups[group])
+	}
+	if len(missing) > 0 {
+		return &MissingPermissionsError{Missing: missing}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (ca *clusterAdmin) DescribeAuthorizedOperations(topics, groups []string, options *AuthorizedOperationsOptions) (*AuthorizedOperations, error) {
+	if options == nil {
+		options = &AuthorizedOperationsOptions{}
+	}
+	result := &AuthorizedOperations{
+		Topics: make(map[string]*ResourceOperations, len(topics)),
+		Groups: make(map[string]*ResourceOperations, len(groups)),
+	}
+
+	// resources whose authorized operations are computed from the ACLs
+	var fromAcls []*Resource
+	var fromAclsOps []*ResourceOperations
+
+	// an empty list of topics would describe all the topics
+	var metadata *MetadataResponse
+	if len(topics) > 0 {
+		var err error
+		metadata, err = ca.describeMetadataAuthorizedOperations(topics)
+		if err != nil {
+			return nil, err
+		}
+		for _, topic := range metadata.Topics {
+			ops := &ResourceOperations{}
+			if !errors.Is(topic.Err, ErrNoError) {
+				ops.Err = topic.Err
+			} else if topic.TopicAuthorizedOperations == authorizedOperationsOmitted || metadata.Version < 8 {
+				fromAcls = append(fromAcls, &Resource{ResourceType: AclResourceTopic, ResourceName: topic.Name})
+				fromAclsOps = append(fromAclsOps, ops)
+			} else {
+				ops.Operations = decodeAuthorizedOperations(topic.TopicAuthorizedOperations)
+			}
+			result.Topics[topic.Name] = ops
+		}
+	}
+
+	clusterOps, err := ca.describeClusterAuthorizedOperations(metadata)
+	if err != nil {
+		return nil, err
+	}
+	result.Cluster = &ResourceOperations{}
+	if clusterOps == authorizedOperationsOmitted {
+		fromAcls = append(fromAcls, &Resource{ResourceType: AclResourceCluster, ResourceName: "kafka-cluster"})
+		fromAclsOps = append(fromAclsOps, result.Cluster)
+	} else {
+		result.Cluster.Operations = decodeAuthorizedOperations(clusterOps)
+	}
+
+	for _, group := range groups {
+		ops := &ResourceOperations{}
+		result.Groups[group] = ops
+		bits, err := ca.describeGroupAuthorizedOperations(group)
+		switch {
+		case err != nil:
+			ops.Err = err
+		case bits == authorizedOperationsOmitted:
+			fromAcls = append(fromAcls, &Resource{ResourceType: AclResourceGroup, ResourceName: group})
+			fromAclsOps = append(fromAclsOps, ops)
+		default:
+			ops.Operations = decodeAuthorizedOperations(bits)
+		}
+	}
+
+	if len(fromAcls) == 0 {
+		return result, nil
+	}
+	principal := options.Principal
+	if principal == "" {
+		principal = ca.saslPrincipal()
+	}
+	if principal == "" {
+		return nil, ConfigurationError("AuthorizedOperationsOptions.Principal is required to compute the authorized operations from the ACLs of brokers older than 2.3")
+	}
+	acls, err := ca.ListAcls(AclFilter{
+		ResourceType:              AclResourceAny,
+		ResourcePatternTypeFilter: AclPatternAny,
+		Operation:                 AclOperationAny,
+		PermissionType:            AclPermissionAny,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the ACLs: %w", err)
+	}
+	for i, resource := range fromAcls {
+		fromAclsOps[i].Operations = authorizedOperationsFromAcls(acls, resource, principal)
+	}
+	return result, nil
+}
+
+// saslPrincipal returns the principal brokers map the SASL user to, or "" if
+// it cannot be told from the configuration: GSSAPI and OAUTHBEARER principals
+// are derived by the brokers from the ticket or the token, and the user of a
+// CredentialsProvider is only known once connected.
+func (ca *clusterAdmin) saslPrincipal() string {
+	sasl := &ca.conf.Net.SASL
+	if !sasl.Enable || sasl.User == "" || sasl.CredentialsProvider != nil {
+		return ""
+	}
+	switch sasl.Mechanism {
+	case "", SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512:
+		return "User:" + sasl.User
+	}
+	return ""
+}
+
+// describeMetadataAuthorizedOperations requests the metadata of topics along
+// with the authorized operations, of all the topics if topics is empty. The
+// encoder drops the cluster operations from version 11, which moved them to
+// DescribeCluster.
+func (ca *clusterAdmin) describeMetadataAuthorizedOperations(topics []string) (*MetadataResponse, error) {
+	request := NewMetadataRequest(ca.conf.version(), topics)
+	if request.Version >= 8 {
+		request.IncludeTopicAuthorizedOperations = len(topics) > 0
+		request.IncludeClusterAuthorizedOperations = true
+	}
+
+	var response *MetadataResponse
+	err := ca.retryOnError(isRetriableControllerError, func() error {
+		controller, err := ca.Controller()
+		if err != nil {
+			return err
+		}
+		response, err = controller.GetMetadata(request)
+		if isRetriableControllerError(err) {
+			_, _ = ca.refreshController()
+		}
+		return err
+	})
+	return response, err
+}
+
+// describeClusterAuthorizedOperations returns the operations authorized on
+// the cluster, from the metadata of the topics if they were described with a
+// version that has them.
+func (ca *clusterAdmin) describeClusterAuthorizedOperations(metadata *MetadataResponse) (int32, error) {
+	if metadata == nil {
+		if ca.conf.version().IsAtLeast(V2_8_0_0) {
+			operations, err := ca.describeClusterAPIAuthorizedOperations()
+			if !errors.Is(err, ErrUnsupportedVersion) {
+				return operations, err
+			}
+		}
+		// older brokers only return them along with the metadata, which is
+		// then that of all the topics
+		var err error
+		if metadata, err = ca.describeMetadataAuthorizedOperations(nil); err != nil {
+			return 0, err
+		}
+	}
+	switch {
+	case metadata.Version < 8:
+		return authorizedOperationsOmitted, nil
+	case metadata.Version <= 10:
+		return metadata.ClusterAuthorizedOperations, nil
+	}
+	return ca.describeClusterAPIAuthorizedOperations()
+}
+
+func (ca *clusterAdmin) describeClusterAPIAuthorizedOperations() (int32, error) {
+	request := NewDescribeClusterRequest(ca.conf.version())
+	request.IncludeClusterAuthorizedOperations = true
+	var operations int32
+	err := ca.retryOnError(isRetriableControllerError, func() error {
+		controller, err := ca.Controller()
+		if err != nil {
+			return err
+		}
+		response, err := controller.DescribeCluster(request)
+		if err != nil {
+			return err
+		}
+		if err := ca.controllerError(response.Err, response.ErrorMessage); err != nil {
+			return err
+		}
+		operations = response.ClusterAuthorizedOperations
+		return nil
+	})
+	return operations, err
+}
+
+func (ca *clusterAdmin) describeGroupAuthorizedOperations(group string) (int32, error) {
+	if !ca.conf.version().IsAtLeast(V2_3_0_0) {
+		return authorizedOperationsOmitted, nil
+	}
+	coordinator, err := ca.client.Coordinator(group)
+	if err != nil {
+		return 0, err
+	}
+	request := &DescribeGroupsRequest{Version: 3, Groups: []string{group}, IncludeAuthorizedOperations: true}
+	if ca.conf.version().IsAtLeast(V2_4_0_0) {
+		request.Version = 5
+	}
+	response, err := coordinator.DescribeGroups(request)
+	if err != nil {
+		return 0, err
+	}
+	if len(response.Groups) != 1 {
+		return 0, fmt.Errorf("unexpected DescribeGroups response with %d groups", len(response.Groups))
+	}
+	description := response.Groups[0]
+	if !errors.Is(description.Err, ErrNoError) {
+		return 0, description.Err
+	}
+	if response.Version < 3 {
+		// negotiated down to a version without the authorized operations
+		return authorizedOperationsOmitted, nil
+	}
+	return description.AuthorizedOperations, nil
+}
+
+// decodeAuthorizedOperations decodes the bitfield of the authorized
+// operations of a resource, where bit n is set if the operation whose code is
+// n is authorized.
+func decodeAuthorizedOperations(bits int32) []AclOperation {
+	var operations []AclOperation
+	for operation := AclOperationRead; operation <= AclOperationIdempotentWrite; operation++ {
+		if bits&(1<<operation) != 0 {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// aclResourceOperations are the operations applicable to each resource type.
+var aclResourceOperations = map[AclResourceType][]AclOperation{
+	AclResourceTopic: {
+		AclOperationRead, AclOperationWrite, AclOperationCreate, AclOperationDelete, AclOperationAlter,
+		AclOperationDescribe, AclOperationDescribeConfigs, AclOperationAlterConfigs,
+	},
+	AclResourceGroup: {AclOperationRead, AclOperationDelete, AclOperationDescribe},
+	AclResourceCluster: {
+		AclOperationCreate, AclOperationAlter, AclOperationDescribe, AclOperationClusterAction,
+		AclOperationDescribeConfigs, AclOperationAlterConfigs, AclOperationIdempotentWrite,
+	},
+}
+
+// authorizedOperationsFromAcls computes the operations authorized to a
+// principal on a resource like the standard authorizer of Kafka: an operation
+// is authorized if an ACL allows it and none denies it, All covers every
+// operation, and Describe and DescribeConfigs are implied by the operations
+// needing them. As the address of the client is not known, ACLs restricted to
+// some hosts are assumed to apply.
+func authorizedOperationsFromAcls(acls []ResourceAcls, resource *Resource, principal string) []AclOperation {
+	var operations []AclOperation
+	for _, operation := range aclResourceOperations[resource.ResourceType] {
+		if aclsAuthorize(acls, resource, principal, operation) {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+func aclsAuthorize(acls []ResourceAcls, resource *Resource, principal string, operation AclOperation) bool {
+	granting := []AclOperation{operation, AclOperationAll}
+	switch operation {
+	case AclOperationDescribe:
+		granting = append(granting, AclOperationRead, AclOperationWrite, AclOperationDelete, AclOperationAlter)
+	case AclOperationDescribeConfigs:
+		granting = append(granting, AclOperationAlterConfigs)
+	}
+
+	allowed := false
+	for _, resourceAcls := range acls {
+		if !aclResourceMatches(&resourceAcls.Resource, resource) {
+			continue
+		}
+		for _, acl := range resourceAcls.Acls {
+			if acl.Principal != principal && acl.Principal != "User:*" {
+				continue
+			}
+			switch {
+			case acl.PermissionType == AclPermissionDeny && (acl.Operation == operation || acl.Operation == AclOperationAll):
+				return false
+			case acl.PermissionType == AclPermissionAllow && slices.Contains(granting, acl.Operation):
+				allowed = true
+			}
+		}
+	}
+	return allowed
+}
+
+// aclResourceMatches reports whether the pattern of an ACL matches a resource.
+func aclResourceMatches(pattern, resource *Resource) bool {
+	if pattern.ResourceType != resource.ResourceType {
+		return false
+	}
+	switch pattern.ResourcePatternType {
+	case AclPatternPrefixed:
+		return strings.HasPrefix(resource.ResourceName, pattern.ResourceName)
+	default:
+		return pattern.ResourceName == resource.ResourceName || pattern.ResourceName == "*"
+	}
+}
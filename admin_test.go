@@ -2185,6 +2185,46 @@ func mockMetadataFor(t *testing.T, controller *MockBroker, brokers ...*MockBroke
 	return m
 }
 
+func TestListConsumerGroupLag(t *testing.T) {
+	const (
+		group = "my-group"
+		topic = "my-topic"
+	)
+
+	broker := newMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]MockResponse{
+		"OffsetFetchRequest": NewMockOffsetFetchResponse(t).
+			SetOffset(group, topic, 0, 40, "", ErrNoError).
+			SetOffset(group, topic, 1, -1, "", ErrNoError).
+			SetError(ErrNoError),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset(topic, 0, OffsetNewest, 100).
+			SetOffset(topic, 1, OffsetNewest, 7),
+		"MetadataRequest": mockMetadataFor(t, broker).
+			SetLeader(topic, 0, broker.BrokerID()).
+			SetLeader(topic, 1, broker.BrokerID()),
+		"FindCoordinatorRequest": mockGroupCoordinators(t, broker, group),
+	})
+
+	result, err := newTestAdmin(t, broker).ListConsumerGroupLag(group, map[string][]int32{topic: {0, 1}}, nil)
+	require.NoError(t, err)
+	require.Contains(t, result, topic)
+
+	committed := result[topic][0]
+	require.NotNil(t, committed)
+	assert.NoError(t, committed.Err)
+	assert.Equal(t, int64(40), committed.CommittedOffset)
+	assert.Equal(t, int64(100), committed.EndOffset)
+	assert.Equal(t, int64(60), committed.Lag)
+
+	uncommitted := result[topic][1]
+	require.NotNil(t, uncommitted)
+	assert.NoError(t, uncommitted.Err)
+	assert.Equal(t, int64(-1), uncommitted.CommittedOffset)
+	assert.Equal(t, int64(7), uncommitted.EndOffset)
+	assert.Equal(t, int64(-1), uncommitted.Lag)
+}
+
 func TestListOffsets(t *testing.T) {
 	const topic = "my-topic"
 
@@ -2676,3 +2716,322 @@ func TestClusterAdminUpdateFeatures(t *testing.T) {
 		require.Equal(t, []UpdatableFeatureResult{{Feature: "metadata.version"}}, results)
 	})
 }
+
+func authorizedOperationsBits(operations ...AclOperation) int32 {
+	var bits int32
+	for _, operation := range operations {
+		bits |= 1 << operation
+	}
+	return bits
+}
+
+func TestClusterAdminDescribeAuthorizedOperations(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"MetadataRequest": func(req *request) encoderWithHeader {
+			metadata := req.body.(*MetadataRequest)
+			res := &MetadataResponse{Version: metadata.Version, ControllerID: broker.BrokerID()}
+			res.AddBroker(broker.Addr(), broker.BrokerID())
+			if metadata.IncludeClusterAuthorizedOperations {
+				res.ClusterAuthorizedOperations = authorizedOperationsBits(AclOperationDescribe, AclOperationIdempotentWrite)
+			}
+			for _, topic := range metadata.Topics {
+				switch topic {
+				case "orders":
+					res.AddTopic(topic, ErrNoError).TopicAuthorizedOperations = authorizedOperationsBits(
+						AclOperationRead, AclOperationWrite, AclOperationDescribe)
+				case "secret":
+					res.AddTopic(topic, ErrTopicAuthorizationFailed).TopicAuthorizedOperations = authorizedOperationsOmitted
+				}
+			}
+			return res
+		},
+		"FindCoordinatorRequest": func(req *request) encoderWithHeader {
+			return NewMockFindCoordinatorResponse(t).SetCoordinator(CoordinatorGroup, "payments", broker).For(req.body)
+		},
+		"DescribeGroupsRequest": func(req *request) encoderWithHeader {
+			describe := req.body.(*DescribeGroupsRequest)
+			assert.True(t, describe.IncludeAuthorizedOperations)
+			return &DescribeGroupsResponse{Version: describe.Version, Groups: []*GroupDescription{{
+				Version:              describe.Version,
+				GroupId:              "payments",
+				State:                "Stable",
+				AuthorizedOperations: authorizedOperationsBits(AclOperationRead, AclOperationDescribe),
+			}}}
+		},
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_4_0_0
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	operations, err := admin.DescribeAuthorizedOperations([]string{"orders", "secret"}, []string{"payments"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []AclOperation{AclOperationDescribe, AclOperationIdempotentWrite}, operations.Cluster.Operations)
+	assert.Equal(t, []AclOperation{AclOperationRead, AclOperationWrite, AclOperationDescribe}, operations.Topics["orders"].Operations)
+	assert.ErrorIs(t, operations.Topics["secret"].Err, ErrTopicAuthorizationFailed)
+	assert.Equal(t, []AclOperation{AclOperationRead, AclOperationDescribe}, operations.Groups["payments"].Operations)
+
+	assert.NoError(t, operations.Check(PermissionRequirements{
+		Cluster: []AclOperation{AclOperationIdempotentWrite},
+		Topics:  map[string][]AclOperation{"orders": {AclOperationWrite, AclOperationDescribe}},
+		Groups:  map[string][]AclOperation{"payments": {AclOperationRead}},
+	}))
+
+	err = operations.Check(PermissionRequirements{
+		Topics: map[string][]AclOperation{
+			"orders": {AclOperationRead, AclOperationDelete},
+			"secret": {AclOperationWrite},
+		},
+		Groups: map[string][]AclOperation{"payments": {AclOperationRead}},
+	})
+	var missing *MissingPermissionsError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, []MissingPermission{
+		{ResourceType: AclResourceTopic, ResourceName: "orders", Operation: AclOperationDelete},
+		{ResourceType: AclResourceTopic, ResourceName: "secret", Operation: AclOperationWrite, Err: ErrTopicAuthorizationFailed},
+	}, missing.Missing)
+	assert.ErrorIs(t, err, ErrTopicAuthorizationFailed)
+	assert.NotErrorIs(t, err, ErrGroupAuthorizationFailed)
+	assert.EqualError(t, err, `kafka: missing permissions: Delete on Topic "orders", Write on Topic "secret" (`+ErrTopicAuthorizationFailed.Error()+`)`)
+}
+
+func TestClusterAdminDescribeAuthorizedOperationsFromAcls(t *testing.T) {
+	acls := []*ResourceAcls{
+		{
+			Resource: Resource{ResourceType: AclResourceTopic, ResourceName: "orders-", ResourcePatternType: AclPatternPrefixed},
+			Acls:     []*Acl{{Principal: "User:alice", Host: "*", Operation: AclOperationWrite, PermissionType: AclPermissionAllow}},
+		},
+		{
+			Resource: Resource{ResourceType: AclResourceTopic, ResourceName: "*", ResourcePatternType: AclPatternLiteral},
+			Acls:     []*Acl{{Principal: "User:*", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow}},
+		},
+		{
+			Resource: Resource{ResourceType: AclResourceTopic, ResourceName: "orders-eu", ResourcePatternType: AclPatternLiteral},
+			Acls:     []*Acl{{Principal: "User:*", Host: "*", Operation: AclOperationWrite, PermissionType: AclPermissionDeny}},
+		},
+		{
+			Resource: Resource{ResourceType: AclResourceCluster, ResourceName: "kafka-cluster", ResourcePatternType: AclPatternLiteral},
+			Acls:     []*Acl{{Principal: "User:alice", Host: "10.0.0.1", Operation: AclOperationAll, PermissionType: AclPermissionAllow}},
+		},
+		{
+			Resource: Resource{ResourceType: AclResourceGroup, ResourceName: "payments", ResourcePatternType: AclPatternLiteral},
+			Acls:     []*Acl{{Principal: "User:bob", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow}},
+		},
+	}
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"MetadataRequest": func(req *request) encoderWithHeader {
+			return NewMockMetadataResponse(t).
+				SetController(broker.BrokerID()).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("orders-us", 0, broker.BrokerID()).
+				SetLeader("orders-eu", 0, broker.BrokerID()).
+				For(req.body)
+		},
+		"DescribeAclsRequest": func(req *request) encoderWithHeader {
+			return &DescribeAclsResponse{Version: req.body.version(), ResourceAcls: acls}
+		},
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_0_0_0
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	_, err = admin.DescribeAuthorizedOperations([]string{"orders-us"}, nil, nil)
+	var configErr ConfigurationError
+	require.ErrorAs(t, err, &configErr, "the principal is required with brokers older than 2.3")
+
+	operations, err := admin.DescribeAuthorizedOperations([]string{"orders-us", "orders-eu"}, []string{"payments"},
+		&AuthorizedOperationsOptions{Principal: "User:alice"})
+	require.NoError(t, err)
+	assert.Equal(t, []AclOperation{AclOperationRead, AclOperationWrite, AclOperationDescribe}, operations.Topics["orders-us"].Operations)
+	assert.Equal(t, []AclOperation{AclOperationRead, AclOperationDescribe}, operations.Topics["orders-eu"].Operations, "deny wins over allow")
+	assert.Equal(t, aclResourceOperations[AclResourceCluster], operations.Cluster.Operations, "All covers every operation")
+	assert.Empty(t, operations.Groups["payments"].Operations)
+	assert.NoError(t, operations.Groups["payments"].Err)
+}
+
+func TestClusterAdminDescribeAuthorizedOperationsWithoutTopics(t *testing.T) {
+	for _, version := range []KafkaVersion{V2_4_0_0, V2_8_0_0} {
+		t.Run(version.String(), func(t *testing.T) {
+			clusterBits := authorizedOperationsBits(AclOperationDescribe, AclOperationAlter)
+			broker := NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+				"MetadataRequest": func(req *request) encoderWithHeader {
+					metadata := req.body.(*MetadataRequest)
+					assert.False(t, metadata.IncludeTopicAuthorizedOperations, "no topic should be described")
+					res := &MetadataResponse{Version: metadata.Version, ControllerID: broker.BrokerID()}
+					res.AddBroker(broker.Addr(), broker.BrokerID())
+					res.AddTopic("orders", ErrNoError)
+					if metadata.IncludeClusterAuthorizedOperations {
+						res.ClusterAuthorizedOperations = clusterBits
+					}
+					return res
+				},
+				"DescribeClusterRequest": func(req *request) encoderWithHeader {
+					describe := req.body.(*DescribeClusterRequest)
+					assert.True(t, describe.IncludeClusterAuthorizedOperations)
+					return &DescribeClusterResponse{
+						Version:                     describe.Version,
+						ControllerID:                broker.BrokerID(),
+						ClusterAuthorizedOperations: clusterBits,
+					}
+				},
+				"FindCoordinatorRequest": func(req *request) encoderWithHeader {
+					return NewMockFindCoordinatorResponse(t).SetCoordinator(CoordinatorGroup, "payments", broker).For(req.body)
+				},
+				"DescribeGroupsRequest": func(req *request) encoderWithHeader {
+					describe := req.body.(*DescribeGroupsRequest)
+					return &DescribeGroupsResponse{Version: describe.Version, Groups: []*GroupDescription{{
+						Version:              describe.Version,
+						GroupId:              "payments",
+						State:                "Stable",
+						AuthorizedOperations: authorizedOperationsBits(AclOperationRead),
+					}}}
+				},
+			})
+
+			config := NewTestConfig()
+			config.Version = version
+			admin, err := NewClusterAdmin([]string{broker.Addr()}, config)
+			require.NoError(t, err)
+			defer admin.Close()
+
+			// empty topics report only the cluster and the groups, not every topic
+			for _, topics := range [][]string{nil, {}} {
+				operations, err := admin.DescribeAuthorizedOperations(topics, []string{"payments"}, nil)
+				require.NoError(t, err)
+				assert.Empty(t, operations.Topics)
+				assert.Equal(t, []AclOperation{AclOperationAlter, AclOperationDescribe}, operations.Cluster.Operations)
+				assert.Equal(t, []AclOperation{AclOperationRead}, operations.Groups["payments"].Operations)
+			}
+		})
+	}
+}
+
+func TestClusterAdminDescribeAuthorizedOperationsClusterFromDescribeCluster(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"MetadataRequest": func(req *request) encoderWithHeader {
+			metadata := req.body.(*MetadataRequest)
+			res := &MetadataResponse{Version: metadata.Version, ControllerID: broker.BrokerID()}
+			res.AddBroker(broker.Addr(), broker.BrokerID())
+			if len(metadata.Topics) > 0 {
+				assert.True(t, metadata.IncludeTopicAuthorizedOperations)
+				res.AddTopic("orders", ErrNoError).TopicAuthorizedOperations = authorizedOperationsBits(AclOperationRead)
+			}
+			return res
+		},
+		"DescribeClusterRequest": func(req *request) encoderWithHeader {
+			return &DescribeClusterResponse{
+				Version:                     req.body.version(),
+				ControllerID:                broker.BrokerID(),
+				ClusterAuthorizedOperations: authorizedOperationsBits(AclOperationIdempotentWrite),
+			}
+		},
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_8_0_0
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	operations, err := admin.DescribeAuthorizedOperations([]string{"orders"}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []AclOperation{AclOperationRead}, operations.Topics["orders"].Operations)
+	assert.Equal(t, []AclOperation{AclOperationIdempotentWrite}, operations.Cluster.Operations,
+		"the cluster operations are described with DescribeCluster from metadata v11")
+}
+
+func TestClusterAdminDescribeAuthorizedOperationsGroupNegotiatedDown(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"ApiVersionsRequest": func(req *request) encoderWithHeader {
+			return NewMockApiVersionsResponse(t).SetApiKeys([]ApiVersionsResponseKey{
+				{ApiKey: apiKeyMetadata, MinVersion: 0, MaxVersion: 9},
+				{ApiKey: apiKeyFindCoordinator, MinVersion: 0, MaxVersion: 2},
+				{ApiKey: apiKeyDescribeGroups, MinVersion: 0, MaxVersion: 2},
+				{ApiKey: apiKeyDescribeAcls, MinVersion: 0, MaxVersion: 1},
+			}).For(req.body)
+		},
+		"MetadataRequest": func(req *request) encoderWithHeader {
+			return NewMockMetadataResponse(t).
+				SetController(broker.BrokerID()).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				For(req.body)
+		},
+		"FindCoordinatorRequest": func(req *request) encoderWithHeader {
+			return NewMockFindCoordinatorResponse(t).SetCoordinator(CoordinatorGroup, "payments", broker).For(req.body)
+		},
+		"DescribeGroupsRequest": func(req *request) encoderWithHeader {
+			describe := req.body.(*DescribeGroupsRequest)
+			assert.Equal(t, int16(2), describe.Version)
+			return &DescribeGroupsResponse{Version: describe.Version, Groups: []*GroupDescription{{
+				Version: describe.Version,
+				GroupId: "payments",
+				State:   "Stable",
+			}}}
+		},
+		"DescribeAclsRequest": func(req *request) encoderWithHeader {
+			return &DescribeAclsResponse{Version: req.body.version(), ResourceAcls: []*ResourceAcls{{
+				Resource: Resource{ResourceType: AclResourceGroup, ResourceName: "payments", ResourcePatternType: AclPatternLiteral},
+				Acls:     []*Acl{{Principal: "User:alice", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow}},
+			}}}
+		},
+	})
+
+	config := NewTestConfig()
+	config.ApiVersionsRequest = true
+	config.Version = V2_4_0_0
+	admin, err := NewClusterAdmin([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	operations, err := admin.DescribeAuthorizedOperations(nil, []string{"payments"},
+		&AuthorizedOperationsOptions{Principal: "User:alice"})
+	require.NoError(t, err)
+	assert.Equal(t, []AclOperation{AclOperationRead, AclOperationDescribe}, operations.Groups["payments"].Operations,
+		"the operations are computed from the ACLs when DescribeGroups is negotiated below v3")
+}
+
+func TestClusterAdminSASLPrincipal(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		configure func(*Config)
+		expected  string
+	}{
+		{"without SASL", func(*Config) {}, ""},
+		{"PLAIN", func(c *Config) { c.Net.SASL.Mechanism = SASLTypePlaintext }, "User:alice"},
+		{"SCRAM", func(c *Config) { c.Net.SASL.Mechanism = SASLTypeSCRAMSHA512 }, "User:alice"},
+		{"OAUTHBEARER", func(c *Config) { c.Net.SASL.Mechanism = SASLTypeOAuth }, ""},
+		{"GSSAPI", func(c *Config) { c.Net.SASL.Mechanism = SASLTypeGSSAPI }, ""},
+		{"CredentialsProvider", func(c *Config) {
+			c.Net.SASL.Mechanism = SASLTypePlaintext
+			c.Net.SASL.CredentialsProvider = SASLCredentialsProviderFunc(func() (*SASLCredentials, error) {
+				return &SASLCredentials{User: "bob", Password: "secret"}, nil
+			})
+		}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewTestConfig()
+			if tt.name != "without SASL" {
+				config.Net.SASL.Enable = true
+				config.Net.SASL.User = "alice"
+			}
+			tt.configure(config)
+			admin := &clusterAdmin{conf: config}
+			assert.Equal(t, tt.expected, admin.saslPrincipal())
+		})
+	}
+}
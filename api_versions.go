@@ -1,5 +1,7 @@
 package sarama
 
+import "fmt"
+
 type apiVersionRange struct {
 	minVersion int16
 	maxVersion int16
@@ -7,9 +9,17 @@ type apiVersionRange struct {
 
 type apiVersionMap map[int16]*apiVersionRange
 
+// minEncodableVersioner is implemented by protocol bodies whose content
+// cannot be encoded below a given version, such as produce requests carrying
+// record batches.
+type minEncodableVersioner interface {
+	minEncodableVersion() int16
+}
+
 // restrictApiVersion selects the appropriate API version for a given protocol body according to
 // the client and broker version ranges. By default, it selects the maximum version supported by both
-// client and broker, capped by the maximum Kafka version from Config.
+// client and broker, capped by the maximum Kafka version from Config (the highest version Sarama
+// implements when Config.Version is unset).
 // It then calls setVersion() on the protocol body.
 // If no valid version is found, an error is returned.
 func restrictApiVersion(pb protocolBody, brokerVersions apiVersionMap) error {
@@ -22,6 +32,12 @@ func restrictApiVersion(pb protocolBody, brokerVersions apiVersionMap) error {
 		// Select the maximum version that both client and server support
 		// Clamp to the client max to respect user preference above broker advertised version range
 		pb.setVersion(min(clientMax, max(min(clientMax, brokerVersionRange.maxVersion), brokerVersionRange.minVersion)))
+		if m, ok := pb.(minEncodableVersioner); ok && pb.version() < m.minEncodableVersion() {
+			// the broker is older than the content of the request, negotiating
+			// down would send something it cannot read
+			pb.setVersion(clientMax)
+			return ErrUnsupportedVersion
+		}
 		return nil
 	}
 
@@ -86,4 +102,76 @@ const (
 	apiKeyDescribeProducers            = 61
 	apiKeyDescribeTransactions         = 65
 	apiKeyListTransactions             = 66
+	apiKeyGetTelemetrySubscriptions    = 71
+	apiKeyPushTelemetry                = 72
 )
+
+// apiKeyNames maps API keys to their name in the Kafka protocol.
+var apiKeyNames = map[int16]string{
+	apiKeyProduce:                      "Produce",
+	apiKeyFetch:                        "Fetch",
+	apiKeyListOffsets:                  "ListOffsets",
+	apiKeyMetadata:                     "Metadata",
+	apiKeyLeaderAndIsr:                 "LeaderAndIsr",
+	apiKeyStopReplica:                  "StopReplica",
+	apiKeyUpdateMetadata:               "UpdateMetadata",
+	apiKeyControlledShutdown:           "ControlledShutdown",
+	apiKeyOffsetCommit:                 "OffsetCommit",
+	apiKeyOffsetFetch:                  "OffsetFetch",
+	apiKeyFindCoordinator:              "FindCoordinator",
+	apiKeyJoinGroup:                    "JoinGroup",
+	apiKeyHeartbeat:                    "Heartbeat",
+	apiKeyLeaveGroup:                   "LeaveGroup",
+	apiKeySyncGroup:                    "SyncGroup",
+	apiKeyDescribeGroups:               "DescribeGroups",
+	apiKeyListGroups:                   "ListGroups",
+	apiKeySaslHandshake:                "SaslHandshake",
+	apiKeyApiVersions:                  "ApiVersions",
+	apiKeyCreateTopics:                 "CreateTopics",
+	apiKeyDeleteTopics:                 "DeleteTopics",
+	apiKeyDeleteRecords:                "DeleteRecords",
+	apiKeyInitProducerId:               "InitProducerId",
+	apiKeyOffsetForLeaderEpoch:         "OffsetForLeaderEpoch",
+	apiKeyAddPartitionsToTxn:           "AddPartitionsToTxn",
+	apiKeyAddOffsetsToTxn:              "AddOffsetsToTxn",
+	apiKeyEndTxn:                       "EndTxn",
+	apiKeyWriteTxnMarkers:              "WriteTxnMarkers",
+	apiKeyTxnOffsetCommit:              "TxnOffsetCommit",
+	apiKeyDescribeAcls:                 "DescribeAcls",
+	apiKeyCreateAcls:                   "CreateAcls",
+	apiKeyDeleteAcls:                   "DeleteAcls",
+	apiKeyDescribeConfigs:              "DescribeConfigs",
+	apiKeyAlterConfigs:                 "AlterConfigs",
+	apiKeyAlterReplicaLogDirs:          "AlterReplicaLogDirs",
+	apiKeyDescribeLogDirs:              "DescribeLogDirs",
+	apiKeySASLAuth:                     "SaslAuthenticate",
+	apiKeyCreatePartitions:             "CreatePartitions",
+	apiKeyCreateDelegationToken:        "CreateDelegationToken",
+	apiKeyRenewDelegationToken:         "RenewDelegationToken",
+	apiKeyExpireDelegationToken:        "ExpireDelegationToken",
+	apiKeyDescribeDelegationToken:      "DescribeDelegationToken",
+	apiKeyDeleteGroups:                 "DeleteGroups",
+	apiKeyElectLeaders:                 "ElectLeaders",
+	apiKeyIncrementalAlterConfigs:      "IncrementalAlterConfigs",
+	apiKeyAlterPartitionReassignments:  "AlterPartitionReassignments",
+	apiKeyListPartitionReassignments:   "ListPartitionReassignments",
+	apiKeyOffsetDelete:                 "OffsetDelete",
+	apiKeyDescribeClientQuotas:         "DescribeClientQuotas",
+	apiKeyAlterClientQuotas:            "AlterClientQuotas",
+	apiKeyDescribeUserScramCredentials: "DescribeUserScramCredentials",
+	apiKeyAlterUserScramCredentials:    "AlterUserScramCredentials",
+	apiKeyUpdateFeatures:               "UpdateFeatures",
+	apiKeyDescribeCluster:              "DescribeCluster",
+	apiKeyDescribeProducers:            "DescribeProducers",
+	apiKeyDescribeTransactions:         "DescribeTransactions",
+	apiKeyListTransactions:             "ListTransactions",
+	apiKeyGetTelemetrySubscriptions:    "GetTelemetrySubscriptions",
+	apiKeyPushTelemetry:                "PushTelemetry",
+}
+
+func apiKeyName(key int16) string {
+	if name, ok := apiKeyNames[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("ApiKey(%d)", key)
+}
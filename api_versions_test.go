@@ -1,6 +1,9 @@
 package sarama
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestRestrictApiVersionLowersVersionToBrokerMax(t *testing.T) {
 	request := NewMetadataRequest(V2_8_0_0, []string{"test-topic"})
@@ -95,3 +98,108 @@ func TestRestrictApiVersionDoesNothingIfBrokerVersionRangeMissing(t *testing.T)
 		t.Errorf("Expected version to remain %d, got %d", originalVersion, request.version())
 	}
 }
+
+func TestRestrictApiVersionStartsFromHighestImplementedVersion(t *testing.T) {
+	// an unset Config.Version caps nothing, so requests are built for the
+	// highest version Sarama implements and negotiated down per broker
+	config := NewConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	request := NewMetadataRequest(config.version(), []string{"test-topic"})
+	if request.version() != 11 {
+		t.Errorf("Expected MetadataRequest version to be 11, got %d", request.version())
+	}
+
+	for _, brokerMax := range []int16{4, 9, 11} {
+		request := NewMetadataRequest(config.version(), []string{"test-topic"})
+		brokerVersions := apiVersionMap{
+			apiKeyMetadata: &apiVersionRange{
+				minVersion: 0,
+				maxVersion: brokerMax,
+			},
+		}
+		if err := restrictApiVersion(request, brokerVersions); err != nil {
+			t.Errorf("restrictApiVersion returned unexpected error: %v", err)
+		}
+		if request.version() != brokerMax {
+			t.Errorf("Expected version to be negotiated to %d, got %d", brokerMax, request.version())
+		}
+	}
+}
+
+func TestRestrictApiVersionRejectsRecordBatchesForOldBrokers(t *testing.T) {
+	brokerVersions := apiVersionMap{
+		apiKeyProduce: &apiVersionRange{
+			minVersion: 0,
+			maxVersion: 2,
+		},
+	}
+
+	request := &ProduceRequest{Version: 7}
+	request.AddBatch("topic", 0, &RecordBatch{Version: 2})
+	if err := restrictApiVersion(request, brokerVersions); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+	if request.version() != 7 {
+		t.Errorf("Expected version to remain 7, got %d", request.version())
+	}
+
+	request = &ProduceRequest{Version: 7}
+	request.AddMessage("topic", 0, &Message{Value: []byte("v")})
+	if err := restrictApiVersion(request, brokerVersions); err != nil {
+		t.Errorf("restrictApiVersion returned unexpected error: %v", err)
+	}
+	if request.version() != 2 {
+		t.Errorf("Expected version to be restricted to 2, got %d", request.version())
+	}
+}
+
+func TestRestrictApiVersionRejectsZSTDBelowProduceV7(t *testing.T) {
+	brokerVersions := apiVersionMap{
+		apiKeyProduce: &apiVersionRange{
+			minVersion: 0,
+			maxVersion: 6,
+		},
+	}
+
+	request := &ProduceRequest{Version: 9}
+	request.AddBatch("topic", 0, &RecordBatch{Version: 2, Codec: CompressionZSTD})
+	if err := restrictApiVersion(request, brokerVersions); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+	if request.version() != 9 {
+		t.Errorf("Expected version to remain 9, got %d", request.version())
+	}
+
+	request = &ProduceRequest{Version: 9}
+	request.AddBatch("topic", 0, &RecordBatch{Version: 2, Codec: CompressionGZIP})
+	if err := restrictApiVersion(request, brokerVersions); err != nil {
+		t.Errorf("restrictApiVersion returned unexpected error: %v", err)
+	}
+	if request.version() != 6 {
+		t.Errorf("Expected version to be restricted to 6, got %d", request.version())
+	}
+}
+
+func TestRestrictApiVersionRejectsReadCommittedBelowFetchV4(t *testing.T) {
+	brokerVersions := apiVersionMap{
+		apiKeyFetch: &apiVersionRange{
+			minVersion: 0,
+			maxVersion: 3,
+		},
+	}
+
+	request := &FetchRequest{Version: 11, Isolation: ReadCommitted}
+	if err := restrictApiVersion(request, brokerVersions); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	request = &FetchRequest{Version: 11, Isolation: ReadUncommitted}
+	if err := restrictApiVersion(request, brokerVersions); err != nil {
+		t.Errorf("restrictApiVersion returned unexpected error: %v", err)
+	}
+	if request.version() != 3 {
+		t.Errorf("Expected version to be restricted to 3, got %d", request.version())
+	}
+}
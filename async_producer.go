@@ -648,7 +648,7 @@ func (p *asyncProducer) dispatcher() {
 		}
 
 		version := 1
-		if p.conf.Version.IsAtLeast(V0_11_0_0) {
+		if p.conf.version().IsAtLeast(V0_11_0_0) {
 			version = 2
 		} else if msg.Headers != nil {
 			p.returnError(msg, ConfigurationError("Producing headers requires Kafka at least v0.11"))
@@ -1384,7 +1384,7 @@ func (bp *brokerProducer) handleSuccess(sent *produceSet, response *ProduceRespo
 		switch block.Err {
 		// Success
 		case ErrNoError:
-			if bp.parent.conf.Version.IsAtLeast(V0_10_0_0) && !block.Timestamp.IsZero() {
+			if bp.parent.conf.version().IsAtLeast(V0_10_0_0) && !block.Timestamp.IsZero() {
 				for _, msg := range pSet.msgs {
 					msg.Timestamp = block.Timestamp
 				}
@@ -1595,7 +1595,7 @@ func (p *asyncProducer) retryHandler() {
 	}
 
 	version := 1
-	if p.conf.Version.IsAtLeast(V0_11_0_0) {
+	if p.conf.version().IsAtLeast(V0_11_0_0) {
 		version = 2
 	}
 
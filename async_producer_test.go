@@ -2218,6 +2218,7 @@ func (c *stubLeaderClient) TransactionCoordinator(string) (*Broker, error)   { r
 func (c *stubLeaderClient) RefreshTransactionCoordinator(string) error       { return nil }
 func (c *stubLeaderClient) InitProducerID() (*InitProducerIDResponse, error) { return nil, nil }
 func (c *stubLeaderClient) LeastLoadedBroker() *Broker                       { return c.leader }
+func (c *stubLeaderClient) SubscribeMetadata() *MetadataSubscription         { return nil }
 func (c *stubLeaderClient) PartitionNotReadable(string, int32) bool          { return false }
 func (c *stubLeaderClient) Close() error                                     { return nil }
 func (c *stubLeaderClient) Closed() bool                                     { return false }
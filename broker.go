@@ -34,10 +34,21 @@ type Broker struct {
 	responses     chan *responsePromise
 	done          chan bool
 
+	lanes  atomic.Pointer[brokerLanes] // dedicated connections, see Config.Net.DedicatedConnections
+	isLane bool
+
+	lastUsed   atomic.Int64           // unix nanos, see Config.Net.MaxIdleTime
+	idleTimer  *time.Timer            // protected by lock
+	reopenConf atomic.Pointer[Config] // set while closed for being idle
+
+	backoff *reconnectBackoff // shared reconnect backoff state, set before Open or protected by lock
+
 	metricRegistry             metrics.Registry
 	incomingByteRate           metrics.Meter
 	requestRate                metrics.Meter
 	fetchRate                  metrics.Meter
+	fetchSameRackByteRate      metrics.Meter
+	fetchCrossRackByteRate     metrics.Meter
 	requestSize                metrics.Histogram
 	requestLatency             metrics.Histogram
 	outgoingByteRate           metrics.Meter
@@ -78,6 +89,9 @@ const (
 	// SASLTypeSCRAMSHA512 represents the SCRAM-SHA-512 mechanism.
 	SASLTypeSCRAMSHA512 = "SCRAM-SHA-512"
 	SASLTypeGSSAPI      = "GSSAPI"
+	// SASLTypeAWSMSKIAM represents the AWS_MSK_IAM mechanism of Amazon MSK,
+	// see Config.Net.SASL.AWSMSKIAM.
+	SASLTypeAWSMSKIAM = "AWS_MSK_IAM"
 	// SASLHandshakeV0 is v0 of the Kafka SASL handshake protocol. Client and
 	// server negotiate SASL auth using opaque packets.
 	SASLHandshakeV0 = int16(0)
@@ -114,6 +128,33 @@ type AccessTokenProvider interface {
 	Token() (*AccessToken, error)
 }
 
+// SASLCredentials are the user and password presented for SASL/PLAIN and
+// SASL/SCRAM authentication.
+type SASLCredentials struct {
+	User     string
+	Password string // #nosec G117 -- public SASL config schema; callers set this credential explicitly.
+}
+
+// SASLCredentialsProvider is the interface that encapsulates how implementers
+// can supply SASL/PLAIN and SASL/SCRAM credentials that change over time,
+// such as secrets rotated by a vault.
+type SASLCredentialsProvider interface {
+	// Credentials returns the current credentials. It is called every time
+	// a connection authenticates, including KIP-368 re-authentications, so
+	// it should return quickly, caching the credentials if fetching them is
+	// expensive.
+	Credentials() (*SASLCredentials, error)
+}
+
+// SASLCredentialsProviderFunc adapts a function to the
+// SASLCredentialsProvider interface.
+type SASLCredentialsProviderFunc func() (*SASLCredentials, error)
+
+// Credentials calls f.
+func (f SASLCredentialsProviderFunc) Credentials() (*SASLCredentials, error) {
+	return f()
+}
+
 // SCRAMClient is a an interface to a SCRAM
 // client implementation.
 type SCRAMClient interface {
@@ -131,6 +172,8 @@ type SCRAMClient interface {
 type responsePromise struct {
 	requestTime   time.Time
 	correlationID int32
+	apiKey        int16
+	timeout       time.Duration
 	response      protocolBody
 	handler       func([]byte, error)
 	packets       chan []byte
@@ -185,7 +228,9 @@ func (b *Broker) getSockError() error {
 // waiting for the connection to complete. This means that any subsequent operations on the broker will
 // block waiting for the connection to succeed or fail. To get the effect of a fully synchronous Open call,
 // follow it by a call to Connected(). The only errors Open will return directly are ConfigurationError or
-// AlreadyConnected. If conf is nil, the result of NewConfig() is used.
+// AlreadyConnected. If conf is nil, the result of NewConfig() is used. A connection attempt made sooner
+// than conf.Net.ReconnectBackoff after a failed one fails immediately with an error wrapping
+// ErrNotConnected.
 func (b *Broker) Open(conf *Config) error {
 	if !b.opened.CompareAndSwap(false, true) {
 		return ErrAlreadyConnected
@@ -206,11 +251,28 @@ func (b *Broker) Open(conf *Config) error {
 		b.metricRegistry = newCleanupRegistry(conf.MetricRegistry)
 	}
 
+	// dedicated connections of a previous connection
+	b.closeLanes()
+
 	go withRecover(func() {
 		defer b.lock.Unlock()
 
-		dialer := conf.getDialer()
-		b.conn, b.connErr = dialer.Dial("tcp", b.addr)
+		backoff := b.reconnectBackoff()
+		if b.connErr = backoff.check(b.addr); b.connErr != nil {
+			DebugLogger.Println(b.connErr)
+			b.opened.Store(false)
+			return
+		}
+		defer func() { backoff.update(conf, b.conn != nil) }()
+
+		addr, dialer, err := b.route(conf)
+		if err != nil {
+			Logger.Printf("Failed to resolve broker %s: %s\n", b.addr, err)
+			b.connErr = err
+			b.opened.Store(false)
+			return
+		}
+		b.conn, b.connErr = dialer.Dial("tcp", addr)
 		if b.connErr != nil {
 			Logger.Printf("Failed to connect to broker %s: %s\n", b.addr, b.connErr)
 			b.conn = nil
@@ -218,7 +280,22 @@ func (b *Broker) Open(conf *Config) error {
 			return
 		}
 		if conf.Net.TLS.Enable {
-			b.conn = tls.Client(b.conn, validServerNameTLS(b.addr, conf.Net.TLS.Config))
+			tlsConfig := conf.Net.TLS.Config
+			if conf.Net.TLS.ConfigProvider != nil {
+				tlsConfig, b.connErr = conf.Net.TLS.ConfigProvider.TLSConfig()
+				if b.connErr != nil {
+					Logger.Printf("Failed to get the TLS configuration for broker %s: %s\n", b.addr, b.connErr)
+					_ = b.conn.Close()
+					b.conn = nil
+					b.opened.Store(false)
+					return
+				}
+			}
+			if notAfter, err := clientCertificateNotAfter(tlsConfig); err == nil {
+				metrics.GetOrRegisterGauge("tls-client-certificate-not-after", b.metricRegistry).
+					Update(notAfter.Unix())
+			}
+			b.conn = tls.Client(b.conn, validServerNameTLS(b.addr, tlsConfig))
 		}
 
 		b.conn = newBufConn(b.conn)
@@ -234,6 +311,10 @@ func (b *Broker) Open(conf *Config) error {
 		b.responseRate = metrics.GetOrRegisterMeter("response-rate", b.metricRegistry)
 		b.responseSize = getOrRegisterHistogram("response-size", b.metricRegistry)
 		b.requestsInFlight = metrics.GetOrRegisterCounter("requests-in-flight", b.metricRegistry)
+		if conf.RackID != "" {
+			b.fetchSameRackByteRate = metrics.GetOrRegisterMeter("consumer-fetch-same-rack-byte-rate", b.metricRegistry)
+			b.fetchCrossRackByteRate = metrics.GetOrRegisterMeter("consumer-fetch-cross-rack-byte-rate", b.metricRegistry)
+		}
 		b.protocolRequestsRate = map[int16]metrics.Meter{}
 		// Do not gather metrics for seeded broker (only used during bootstrap) because they share
 		// the same id (-1) and are already exposed through the global metrics above
@@ -247,7 +328,9 @@ func (b *Broker) Open(conf *Config) error {
 		// This should happen before SASL authentication: https://kafka.apache.org/42/design/protocol/#retrieving-supported-api-versions
 		if conf.ApiVersionsRequest {
 			// v4 additionally includes supported features whose min version is 0,
-			// which v0-v3 omit from the response
+			// which v0-v3 omit from the response. It is only sent when Version
+			// is explicitly set to 3.9 or later: older brokers reject it, which
+			// would cost every connection an extra round-trip when it is unset.
 			apiVersionsVersion := int16(3)
 			if conf.Version.IsAtLeast(V3_9_0_0) {
 				apiVersionsVersion = 4
@@ -259,7 +342,12 @@ func (b *Broker) Open(conf *Config) error {
 					return
 				}
 
-				Logger.Printf("Error while sending ApiVersionsRequest V%d to broker %s: %s\n", apiVersionsVersion, b.addr, err)
+				if errors.Is(err, ErrUnsupportedVersion) {
+					// expected from brokers older than the requested version
+					DebugLogger.Printf("ApiVersionsRequest V%d is not supported by broker %s, retrying with a lower version\n", apiVersionsVersion, b.addr)
+				} else {
+					Logger.Printf("Error while sending ApiVersionsRequest V%d to broker %s: %s\n", apiVersionsVersion, b.addr, err)
+				}
 				// send a lower version request in case remote cluster is <= 2.4.0.0
 				maxVersion := int16(0)
 				if apiVersionsResponse != nil {
@@ -289,7 +377,7 @@ func (b *Broker) Open(conf *Config) error {
 			}
 		}
 
-		if conf.Net.SASL.Mechanism == SASLTypeOAuth && conf.Net.SASL.Version == SASLHandshakeV0 {
+		if (conf.Net.SASL.Mechanism == SASLTypeOAuth || conf.Net.SASL.Mechanism == SASLTypeAWSMSKIAM) && conf.Net.SASL.Version == SASLHandshakeV0 {
 			conf.Net.SASL.Version = SASLHandshakeV1
 		}
 
@@ -326,6 +414,13 @@ func (b *Broker) Open(conf *Config) error {
 				return
 			}
 		}
+		b.startIdleTimer()
+		// dedicated connections are only dialed once the primary one is
+		// established, so that a broker that is down is dialed once per
+		// attempt and the reconnect backoff applies to them too
+		if !b.isLane {
+			b.lanes.Store(newBrokerLanes(b, conf))
+		}
 		if b.id >= 0 {
 			DebugLogger.Printf("Connected to broker at %s (registered as #%d)\n", b.addr, b.id)
 		} else {
@@ -376,6 +471,7 @@ func (b *Broker) Close() error {
 	defer b.lock.Unlock()
 
 	b.connErr = nil
+	b.reopenConf.Store(nil)
 	return b.closeLocked()
 }
 
@@ -394,6 +490,10 @@ func (b *Broker) maybeCloseLocked(err error) bool {
 // closeLocked closes the broker connection and resets state.
 // NOTE: caller must hold b.lock.
 func (b *Broker) closeLocked() error {
+	b.closeLanes()
+	b.stopIdleTimer()
+	b.kerberosAuthenticator.destroyClient()
+
 	if b.conn == nil {
 		return ErrNotConnected
 	}
@@ -411,7 +511,10 @@ func (b *Broker) closeLocked() error {
 	b.responses = nil
 	b.done = nil
 
-	b.metricRegistry.UnregisterAll()
+	// lanes share their metrics with the primary connection
+	if !b.isLane {
+		b.metricRegistry.UnregisterAll()
+	}
 
 	if err == nil {
 		DebugLogger.Printf("Closed connection to broker %s\n", b.addr)
@@ -468,6 +571,32 @@ func (b *Broker) DescribeCluster(request *DescribeClusterRequest) (*DescribeClus
 	return response, nil
 }
 
+// GetTelemetrySubscriptions sends a get telemetry subscriptions request and
+// returns a get telemetry subscriptions response or error
+func (b *Broker) GetTelemetrySubscriptions(request *GetTelemetrySubscriptionsRequest) (*GetTelemetrySubscriptionsResponse, error) {
+	response := new(GetTelemetrySubscriptionsResponse)
+	response.Version = request.Version
+
+	if err := b.sendAndReceive(request, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// PushTelemetry sends a push telemetry request and returns a push telemetry
+// response or error
+func (b *Broker) PushTelemetry(request *PushTelemetryRequest) (*PushTelemetryResponse, error) {
+	response := new(PushTelemetryResponse)
+	response.Version = request.Version
+
+	if err := b.sendAndReceive(request, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
 // GetConsumerMetadata send a consumer metadata request and returns a consumer metadata response or error
 func (b *Broker) GetConsumerMetadata(request *ConsumerMetadataRequest) (*ConsumerMetadataResponse, error) {
 	response := new(ConsumerMetadataResponse)
@@ -521,6 +650,11 @@ type ProduceCallback func(*ProduceResponse, error)
 //
 // Make sure not to Close the broker in the callback as it will lead to a deadlock.
 func (b *Broker) AsyncProduce(request *ProduceRequest, cb ProduceCallback) error {
+	b.reopenIfReaped()
+	if lane := b.lane(request); lane != nil {
+		return lane.AsyncProduce(request, cb)
+	}
+
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
@@ -1087,7 +1221,11 @@ func (b *Broker) AlterClientQuotas(request *AlterClientQuotasRequest) (*AlterCli
 // readFull ensures the conn ReadDeadline has been setup before making a
 // call to io.ReadFull
 func (b *Broker) readFull(buf []byte) (n int, err error) {
-	if err := b.conn.SetReadDeadline(time.Now().Add(b.conf.Net.ReadTimeout)); err != nil {
+	return b.readFullWithTimeout(buf, b.conf.Net.ReadTimeout)
+}
+
+func (b *Broker) readFullWithTimeout(buf []byte, timeout time.Duration) (n int, err error) {
+	if err := b.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 		return 0, err
 	}
 
@@ -1171,7 +1309,7 @@ func (b *Broker) sendInternal(rb protocolBody, promise *responsePromise) error {
 		promise.response.setVersion(rb.version())
 	}
 
-	if !b.conf.Version.IsAtLeast(rb.requiredVersion()) {
+	if !b.conf.version().IsAtLeast(rb.requiredVersion()) {
 		return ErrUnsupportedVersion
 	}
 
@@ -1195,6 +1333,7 @@ func (b *Broker) sendInternal(rb protocolBody, promise *responsePromise) error {
 		return err
 	}
 	b.correlationID++
+	b.touch()
 
 	if promise == nil {
 		// Record request latency without the response
@@ -1204,12 +1343,19 @@ func (b *Broker) sendInternal(rb protocolBody, promise *responsePromise) error {
 
 	promise.requestTime = requestTime
 	promise.correlationID = req.correlationID
+	promise.apiKey = rb.key()
+	promise.timeout = requestTimeout(b.conf, rb)
 	b.responses <- promise
 
 	return nil
 }
 
 func (b *Broker) sendAndReceive(req protocolBody, res protocolBody) error {
+	b.reopenIfReaped()
+	if lane := b.lane(req); lane != nil {
+		return lane.sendAndReceive(req, res)
+	}
+
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
@@ -1337,12 +1483,12 @@ func (b *Broker) responseReceiver() {
 		headerLength := getHeaderLength(promise.response.headerVersion())
 		header := make([]byte, headerLength)
 
-		bytesReadHeader, err := b.readFull(header)
+		bytesReadHeader, err := b.readFullWithTimeout(header, promise.timeout)
 		requestLatency := time.Since(promise.requestTime)
 		if err != nil {
 			b.updateIncomingCommunicationMetrics(bytesReadHeader, requestLatency)
 			dead = err
-			promise.handle(nil, err)
+			promise.handle(nil, wrapRequestTimeout(promise, err))
 			continue
 		}
 
@@ -1364,13 +1510,17 @@ func (b *Broker) responseReceiver() {
 		}
 
 		buf := make([]byte, decodedHeader.length-int32(headerLength)+4)
-		bytesReadBody, err := b.readFull(buf)
+		bytesReadBody, err := b.readFullWithTimeout(buf, promise.timeout)
 		b.updateIncomingCommunicationMetrics(bytesReadHeader+bytesReadBody, requestLatency)
 		if err != nil {
 			dead = err
-			promise.handle(nil, err)
+			promise.handle(nil, wrapRequestTimeout(promise, err))
 			continue
 		}
+		b.touch()
+		if promise.apiKey == apiKeyFetch {
+			b.updateFetchRackMetrics(bytesReadHeader + bytesReadBody)
+		}
 
 		promise.handle(buf, nil)
 	}
@@ -1556,6 +1706,8 @@ func (b *Broker) authenticateViaSASLv1() error {
 	case SASLTypeOAuth:
 		provider := b.conf.Net.SASL.TokenProvider
 		return b.sendAndReceiveSASLOAuth(authSendReceiver, provider)
+	case SASLTypeAWSMSKIAM:
+		return b.sendAndReceiveSASLAWSMSKIAM(authSendReceiver)
 	case SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512:
 		return b.sendAndReceiveSASLSCRAMv1(authSendReceiver, b.conf.Net.SASL.SCRAMClientGeneratorFunc())
 	default:
@@ -1659,10 +1811,15 @@ func (b *Broker) sendAndReceiveSASLPlainAuthV0() error {
 		}
 	}
 
-	length := len(b.conf.Net.SASL.AuthIdentity) + 1 + len(b.conf.Net.SASL.User) + 1 + len(b.conf.Net.SASL.Password)
+	user, password, err := b.saslCredentials()
+	if err != nil {
+		return err
+	}
+
+	length := len(b.conf.Net.SASL.AuthIdentity) + 1 + len(user) + 1 + len(password)
 	authBytes := make([]byte, length+4) // 4 byte length header + auth data
 	binary.BigEndian.PutUint32(authBytes, uint32(length))
-	copy(authBytes[4:], b.conf.Net.SASL.AuthIdentity+"\x00"+b.conf.Net.SASL.User+"\x00"+b.conf.Net.SASL.Password)
+	copy(authBytes[4:], b.conf.Net.SASL.AuthIdentity+"\x00"+user+"\x00"+password)
 
 	requestTime := time.Now()
 	// Will be decremented in updateIncomingCommunicationMetrics (except error)
@@ -1693,11 +1850,34 @@ func (b *Broker) sendAndReceiveSASLPlainAuthV0() error {
 // wraps the SASL flow in the Kafka protocol, which allows for returning
 // meaningful errors on authentication failure.
 func (b *Broker) sendAndReceiveSASLPlainAuthV1(authSendReceiver func(authBytes []byte) (*SaslAuthenticateResponse, error)) error {
-	authBytes := []byte(b.conf.Net.SASL.AuthIdentity + "\x00" + b.conf.Net.SASL.User + "\x00" + b.conf.Net.SASL.Password)
-	_, err := authSendReceiver(authBytes)
+	user, password, err := b.saslCredentials()
+	if err != nil {
+		return err
+	}
+	authBytes := []byte(b.conf.Net.SASL.AuthIdentity + "\x00" + user + "\x00" + password)
+	_, err = authSendReceiver(authBytes)
 	return err
 }
 
+// saslCredentials returns the user and password for SASL/PLAIN and
+// SASL/SCRAM, asking Net.SASL.CredentialsProvider for the current ones if
+// set. It is called each time a connection authenticates, including KIP-368
+// re-authentications, so that rotated credentials are picked up.
+func (b *Broker) saslCredentials() (string, string, error) {
+	provider := b.conf.Net.SASL.CredentialsProvider
+	if provider == nil {
+		return b.conf.Net.SASL.User, b.conf.Net.SASL.Password, nil
+	}
+	credentials, err := provider.Credentials()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to retrieve SASL credentials: %w", err)
+	}
+	if credentials == nil || credentials.User == "" || credentials.Password == "" {
+		return "", "", errors.New("SASL credentials provider returned an empty user or password")
+	}
+	return credentials.User, credentials.Password, nil
+}
+
 func currentUnixMilli() int64 {
 	return time.Now().UnixNano() / int64(time.Millisecond)
 }
@@ -1723,7 +1903,7 @@ func (b *Broker) sendAndReceiveSASLOAuth(authSendReceiver func(authBytes []byte)
 
 	if isChallenge {
 		// Abort the token exchange. The broker returns the failure code.
-		_, err = authSendReceiver([]byte(`\x01`))
+		_, err = authSendReceiver([]byte{0x01})
 	}
 	return err
 }
@@ -1734,7 +1914,11 @@ func (b *Broker) sendAndReceiveSASLSCRAMv0() error {
 	}
 
 	scramClient := b.conf.Net.SASL.SCRAMClientGeneratorFunc()
-	if err := scramClient.Begin(b.conf.Net.SASL.User, b.conf.Net.SASL.Password, b.conf.Net.SASL.SCRAMAuthzID); err != nil {
+	user, password, err := b.saslCredentials()
+	if err != nil {
+		return err
+	}
+	if err := scramClient.Begin(user, password, b.conf.Net.SASL.SCRAMAuthzID); err != nil {
 		return fmt.Errorf("failed to start SCRAM exchange with the server: %w", err)
 	}
 
@@ -1790,7 +1974,11 @@ func (b *Broker) sendAndReceiveSASLSCRAMv0() error {
 }
 
 func (b *Broker) sendAndReceiveSASLSCRAMv1(authSendReceiver func(authBytes []byte) (*SaslAuthenticateResponse, error), scramClient SCRAMClient) error {
-	if err := scramClient.Begin(b.conf.Net.SASL.User, b.conf.Net.SASL.Password, b.conf.Net.SASL.SCRAMAuthzID); err != nil {
+	user, password, err := b.saslCredentials()
+	if err != nil {
+		return err
+	}
+	if err := scramClient.Begin(user, password, b.conf.Net.SASL.SCRAMAuthzID); err != nil {
 		return fmt.Errorf("failed to start SCRAM exchange with the server: %w", err)
 	}
 
@@ -1819,9 +2007,9 @@ func (b *Broker) sendAndReceiveSASLSCRAMv1(authSendReceiver func(authBytes []byt
 
 func (b *Broker) createSaslAuthenticateRequest(msg []byte) *SaslAuthenticateRequest {
 	authenticateRequest := SaslAuthenticateRequest{SaslAuthBytes: msg}
-	if b.conf.Version.IsAtLeast(V2_5_0_0) {
+	if b.conf.version().IsAtLeast(V2_5_0_0) {
 		authenticateRequest.Version = 2
-	} else if b.conf.Version.IsAtLeast(V2_2_0_0) {
+	} else if b.conf.version().IsAtLeast(V2_2_0_0) {
 		authenticateRequest.Version = 1
 	}
 
@@ -1900,6 +2088,20 @@ func (b *Broker) updateIncomingCommunicationMetrics(bytes int, requestLatency ti
 	}
 }
 
+// updateFetchRackMetrics attributes the bytes of a fetch response to the
+// consumer's rack or to another one, to measure cross-rack data transfer. It
+// is a no-op when either rack is unknown.
+func (b *Broker) updateFetchRackMetrics(bytes int) {
+	if b.fetchSameRackByteRate == nil || b.rack == nil || *b.rack == "" {
+		return
+	}
+	if *b.rack == b.conf.RackID {
+		b.fetchSameRackByteRate.Mark(int64(bytes))
+	} else {
+		b.fetchCrossRackByteRate.Mark(int64(bytes))
+	}
+}
+
 func (b *Broker) updateRequestLatencyAndInFlightMetrics(requestLatency time.Duration) {
 	requestLatencyInMs := int64(requestLatency / time.Millisecond)
 	b.requestLatency.Update(requestLatencyInMs)
@@ -0,0 +1,149 @@
+package sarama
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// RequestClass groups the requests sent to a broker so that they can be routed
+// to dedicated connections, see Config.Net.DedicatedConnections.
+type RequestClass int
+
+const (
+	// RequestClassDefault covers every request that does not belong to
+	// another class, such as metadata and admin requests. They are always
+	// sent on the broker's primary connection.
+	RequestClassDefault RequestClass = iota
+	// RequestClassProduce covers Produce requests.
+	RequestClassProduce
+	// RequestClassFetch covers Fetch requests.
+	RequestClassFetch
+	// RequestClassGroup covers the group coordination requests: JoinGroup,
+	// SyncGroup, Heartbeat, LeaveGroup, OffsetCommit and OffsetFetch.
+	RequestClassGroup
+
+	numRequestClasses
+)
+
+func (c RequestClass) String() string {
+	switch c {
+	case RequestClassDefault:
+		return "default"
+	case RequestClassProduce:
+		return "produce"
+	case RequestClassFetch:
+		return "fetch"
+	case RequestClassGroup:
+		return "group"
+	}
+	return "unknown"
+}
+
+func requestClassOf(rb protocolBody) RequestClass {
+	switch rb.key() {
+	case apiKeyProduce:
+		return RequestClassProduce
+	case apiKeyFetch:
+		return RequestClassFetch
+	case apiKeyJoinGroup, apiKeySyncGroup, apiKeyHeartbeat, apiKeyLeaveGroup, apiKeyOffsetCommit, apiKeyOffsetFetch:
+		return RequestClassGroup
+	}
+	return RequestClassDefault
+}
+
+// brokerLanes holds the additional connections of a Broker. Each lane is a
+// Broker of its own sharing the id, address and metrics of its parent, so
+// requests sent on a given lane keep the ordering guarantees of a single
+// connection. A lane closed after a transport error is reopened the next time
+// a request is routed to it.
+type brokerLanes struct {
+	conf  *Config
+	lanes [numRequestClasses][]*Broker
+	next  [numRequestClasses]atomic.Uint32
+
+	lock   sync.Mutex // serializes reopening lanes with closing them
+	closed bool
+}
+
+func newBrokerLanes(b *Broker, conf *Config) *brokerLanes {
+	if len(conf.Net.DedicatedConnections) == 0 {
+		return nil
+	}
+
+	l := &brokerLanes{conf: conf}
+	for class, n := range conf.Net.DedicatedConnections {
+		for range n {
+			lane := &Broker{
+				id: b.id, addr: b.addr, rack: b.rack, metricRegistry: b.metricRegistry, isLane: true,
+				backoff: b.reconnectBackoff(),
+			}
+			_ = lane.Open(conf)
+			l.lanes[class] = append(l.lanes[class], lane)
+		}
+	}
+	return l
+}
+
+// pick returns the lane for the request, or nil if it must be sent on the
+// primary connection. Requests of a class with several lanes are spread
+// round-robin.
+func (l *brokerLanes) pick(rb protocolBody) *Broker {
+	if l == nil {
+		return nil
+	}
+	class := requestClassOf(rb)
+	lanes := l.lanes[class]
+	var lane *Broker
+	switch len(lanes) {
+	case 0:
+		return nil
+	case 1:
+		lane = lanes[0]
+	default:
+		lane = lanes[int(l.next[class].Add(1)-1)%len(lanes)]
+	}
+	if !lane.opened.Load() && !l.reopen(lane) {
+		// the primary connection is being closed along with its lanes
+		return nil
+	}
+	return lane
+}
+
+// reopen reopens a lane closed after a transport error, the request then
+// waits for the new connection like on a freshly opened broker. It returns
+// false if the lanes have been closed.
+func (l *brokerLanes) reopen(lane *Broker) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return false
+	}
+	if err := lane.Open(l.conf); err == nil {
+		DebugLogger.Printf("Reopening dedicated connection to broker %s\n", lane.addr)
+	}
+	return true
+}
+
+func (l *brokerLanes) close() {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	l.closed = true
+	l.lock.Unlock()
+	for _, lanes := range l.lanes {
+		for _, lane := range lanes {
+			_ = lane.Close()
+		}
+	}
+}
+
+// lane returns the dedicated connection to send rb on, if any.
+func (b *Broker) lane(rb protocolBody) *Broker {
+	return b.lanes.Load().pick(rb)
+}
+
+// b.lock must be held by caller
+func (b *Broker) closeLanes() {
+	b.lanes.Swap(nil).close()
+}
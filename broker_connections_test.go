@@ -0,0 +1,202 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestRequestClassOf(t *testing.T) {
+	assert.Equal(t, RequestClassProduce, requestClassOf(&ProduceRequest{}))
+	assert.Equal(t, RequestClassFetch, requestClassOf(&FetchRequest{}))
+	assert.Equal(t, RequestClassGroup, requestClassOf(&HeartbeatRequest{}))
+	assert.Equal(t, RequestClassGroup, requestClassOf(&OffsetCommitRequest{}))
+	assert.Equal(t, RequestClassDefault, requestClassOf(&MetadataRequest{}))
+}
+
+// recordingDialer remembers every connection it opens so that tests can tell
+// which connection a request was written to.
+type recordingDialer struct {
+	lock     sync.Mutex
+	attempts int
+	conns    []*recordingConn
+}
+
+type recordingConn struct {
+	net.Conn
+	lock    sync.Mutex
+	written int
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	c.written += len(b)
+	c.lock.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *recordingConn) bytesWritten() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.written
+}
+
+func (d *recordingDialer) Dial(network, addr string) (net.Conn, error) {
+	d.lock.Lock()
+	d.attempts++
+	d.lock.Unlock()
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &recordingConn{Conn: conn}
+	d.lock.Lock()
+	d.conns = append(d.conns, rc)
+	d.lock.Unlock()
+	return rc, nil
+}
+
+func (d *recordingDialer) active() []*recordingConn {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var active []*recordingConn
+	for _, conn := range d.conns {
+		if conn.bytesWritten() > 0 {
+			active = append(active, conn)
+		}
+	}
+	return active
+}
+
+func TestBrokerDedicatedConnections(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t),
+		"FetchRequest":   NewMockFetchResponse(t, 1),
+	})
+
+	dialer := &recordingDialer{}
+	conf := NewTestConfig()
+	conf.Net.Proxy.Enable = true
+	conf.Net.Proxy.Dialer = dialer
+	conf.Net.DedicatedConnections = map[RequestClass]int{
+		RequestClassProduce: 1,
+		RequestClassFetch:   2,
+	}
+
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	_, err := broker.GetMetadata(&MetadataRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, dialer.active(), 1)
+
+	_, err = broker.Produce(&ProduceRequest{RequiredAcks: WaitForLocal})
+	assert.NoError(t, err)
+	assert.Len(t, dialer.active(), 2)
+
+	// fetches are spread across both fetch connections
+	for range 2 {
+		_, err = broker.Fetch(&FetchRequest{})
+		assert.NoError(t, err)
+	}
+	assert.Len(t, dialer.active(), 4)
+
+	// and nothing else is sent on dedicated connections
+	_, err = broker.GetMetadata(&MetadataRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, dialer.active(), 4)
+	assert.Len(t, dialer.conns, 4)
+
+	assert.NoError(t, broker.Close())
+	assert.Nil(t, broker.lanes.Load())
+}
+
+func TestBrokerDedicatedConnectionReopened(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	dialer := &recordingDialer{}
+	conf := NewTestConfig()
+	conf.Net.Proxy.Enable = true
+	conf.Net.Proxy.Dialer = dialer
+	conf.Net.DedicatedConnections = map[RequestClass]int{RequestClassProduce: 1}
+
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	defer broker.Close()
+	_, err := broker.Produce(&ProduceRequest{RequiredAcks: WaitForLocal})
+	assert.NoError(t, err)
+
+	// a transport error closes the lane but not the primary connection
+	lane := broker.lanes.Load().lanes[RequestClassProduce][0]
+	lane.lock.Lock()
+	assert.True(t, lane.maybeCloseLocked(io.EOF))
+	lane.lock.Unlock()
+	connected, err := broker.Connected()
+	assert.True(t, connected)
+	assert.NoError(t, err)
+
+	_, err = broker.Produce(&ProduceRequest{RequiredAcks: WaitForLocal})
+	assert.NoError(t, err, "the lane should be reopened on demand")
+	assert.Len(t, dialer.conns, 3)
+	assert.Len(t, dialer.active(), 2, "the produce request should be sent on the reopened lane")
+
+	// lanes closed along with the primary connection are not reopened
+	lanes := broker.lanes.Load()
+	assert.NoError(t, broker.Close())
+	assert.Nil(t, lanes.pick(&ProduceRequest{}))
+	assert.False(t, lane.opened.Load())
+}
+
+func TestBrokerDedicatedConnectionsDialedAfterPrimary(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	addr := mb.Addr()
+	mb.Close()
+
+	dialer := &recordingDialer{}
+	conf := NewTestConfig()
+	conf.Net.Proxy.Enable = true
+	conf.Net.Proxy.Dialer = dialer
+	conf.Net.ReconnectBackoff = time.Minute
+	conf.Net.ReconnectBackoffMax = time.Minute
+	conf.Net.DedicatedConnections = map[RequestClass]int{RequestClassProduce: 1, RequestClassFetch: 2}
+
+	broker := NewBroker(addr)
+	for range 2 {
+		assert.NoError(t, broker.Open(conf))
+		connected, err := broker.Connected()
+		assert.False(t, connected)
+		assert.Error(t, err)
+		assert.Nil(t, broker.lanes.Load(), "no lane is left behind by a failed attempt")
+	}
+	assert.Equal(t, 1, dialer.attempts, "only the primary connection is dialed, once per backoff")
+
+	// the lanes share the backoff of the primary connection
+	mb = NewMockBrokerAddr(t, 1, addr)
+	defer mb.Close()
+	broker.backoff = nil
+	assert.NoError(t, broker.Open(conf))
+	defer broker.Close()
+	connected, err := broker.Connected()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+	for _, lanes := range broker.lanes.Load().lanes {
+		for _, lane := range lanes {
+			assert.Same(t, broker.backoff, lane.backoff)
+		}
+	}
+}
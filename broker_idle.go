@@ -0,0 +1,91 @@
+package sarama
+
+import (
+	"net"
+	"time"
+)
+
+// idleRecheckInterval is how soon a busy connection is checked again.
+const idleRecheckInterval = 100 * time.Millisecond
+
+// touch records that the connection has just been used.
+func (b *Broker) touch() {
+	b.lastUsed.Store(time.Now().UnixNano())
+}
+
+// idleFor returns how long neither the connection nor any of its dedicated
+// connections has been used.
+func (b *Broker) idleFor(now time.Time) time.Duration {
+	last := b.lastUsed.Load()
+	if l := b.lanes.Load(); l != nil {
+		for _, lanes := range l.lanes {
+			for _, lane := range lanes {
+				last = max(last, lane.lastUsed.Load())
+			}
+		}
+	}
+	return now.Sub(time.Unix(0, last))
+}
+
+// startIdleTimer arms the idle reaper for a freshly opened connection, see
+// Config.Net.MaxIdleTime.
+// b.lock must be held by caller
+func (b *Broker) startIdleTimer() {
+	maxIdle := b.conf.Net.MaxIdleTime
+	if maxIdle <= 0 {
+		return
+	}
+	b.touch()
+	conn := b.conn
+	b.idleTimer = time.AfterFunc(maxIdle, func() { b.reapIfIdle(conn) })
+}
+
+// b.lock must be held by caller
+func (b *Broker) stopIdleTimer() {
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+		b.idleTimer = nil
+	}
+}
+
+// reapIfIdle closes the connection if it has not been used for
+// Net.MaxIdleTime, otherwise it rearms the timer. The connection is reopened
+// transparently the next time a request is sent through the broker.
+func (b *Broker) reapIfIdle(conn net.Conn) {
+	if !b.lock.TryLock() {
+		// a request is being sent or a response awaited
+		b.rearmIdleTimer(conn, idleRecheckInterval)
+		return
+	}
+	defer b.lock.Unlock()
+
+	if b.conn == nil || b.conn != conn || b.idleTimer == nil {
+		// closed or reopened since the timer was armed
+		return
+	}
+
+	maxIdle := b.conf.Net.MaxIdleTime
+	idle := b.idleFor(time.Now())
+	if idle < maxIdle || len(b.responses) > 0 {
+		b.idleTimer.Reset(max(maxIdle-idle, idleRecheckInterval))
+		return
+	}
+
+	DebugLogger.Printf("Closing connection to broker %s after being idle for %s\n", b.addr, idle.Truncate(time.Millisecond))
+	conf := b.conf
+	b.connErr = nil
+	_ = b.closeLocked()
+	b.reopenConf.Store(conf)
+}
+
+func (b *Broker) rearmIdleTimer(conn net.Conn, d time.Duration) {
+	time.AfterFunc(d, func() { b.reapIfIdle(conn) })
+}
+
+// reopenIfReaped reopens a connection that was closed for being idle.
+func (b *Broker) reopenIfReaped() {
+	if conf := b.reopenConf.Swap(nil); conf != nil {
+		DebugLogger.Printf("Reopening idle connection to broker %s\n", b.addr)
+		_ = b.Open(conf)
+	}
+}
@@ -0,0 +1,67 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestBrokerReapsIdleConnection(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	conf.Net.MaxIdleTime = 100 * time.Millisecond
+
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	defer func() { _ = broker.Close() }()
+
+	// keep the connection busy for longer than the idle time
+	for range 4 {
+		_, err := broker.GetMetadata(&MetadataRequest{})
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+	}
+	connected, err := broker.Connected()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+
+	assert.Eventually(t, func() bool {
+		connected, _ := broker.Connected()
+		return !connected
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the connection is reopened on next use
+	_, err = broker.GetMetadata(&MetadataRequest{})
+	assert.NoError(t, err)
+	connected, err = broker.Connected()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+}
+
+func TestBrokerCloseDoesNotReopenIdleConnection(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+
+	conf := NewTestConfig()
+	conf.Net.MaxIdleTime = 50 * time.Millisecond
+
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	assert.Eventually(t, func() bool {
+		connected, _ := broker.Connected()
+		return !connected
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.ErrorIs(t, broker.Close(), ErrNotConnected)
+	_, err := broker.GetMetadata(&MetadataRequest{})
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
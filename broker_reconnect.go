@@ -0,0 +1,81 @@
+package sarama
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// reconnectJitter is the relative amount of randomness applied to reconnect
+// backoffs, like the JVM client.
+const reconnectJitter = 0.2
+
+// computeReconnectBackoff returns how long to wait before connecting again to
+// a broker after the given number of consecutive failed attempts, see
+// Config.Net.ReconnectBackoff and Config.Net.ReconnectBackoffMax.
+func computeReconnectBackoff(conf *Config, failures int) time.Duration {
+	backoff, maxBackoff := conf.Net.ReconnectBackoff, conf.Net.ReconnectBackoffMax
+	if backoff <= 0 || failures <= 0 {
+		return 0
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	term := float64(backoff)
+	for i := 1; i < failures && term < float64(maxBackoff); i++ {
+		term *= 2
+	}
+	term *= 1 - reconnectJitter + 2*reconnectJitter*rand.Float64()
+	return min(time.Duration(term), maxBackoff)
+}
+
+// reconnectBackoff is the reconnect backoff state of a broker address. A
+// client shares it between the Broker instances it creates for an address,
+// so that re-registering a broker after a metadata refresh does not reset the
+// backoff, and a Broker shares it with its dedicated connections.
+type reconnectBackoff struct {
+	lock     sync.Mutex
+	failures int       // consecutive failed connection attempts
+	next     time.Time // earliest time to connect again
+}
+
+// check fails a connection attempt made too soon after a failed one, without
+// dialing, like the JVM client skips the nodes it backs off from. The error
+// wraps ErrNotConnected so that callers retry it with their own backoff, or
+// pick another broker, instead of sleeping with the broker lock held, which
+// would block every other user of the broker.
+func (r *reconnectBackoff) check(addr string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	wait := time.Until(r.next)
+	if wait <= 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: reconnecting to broker %s in %s after %d failed attempts",
+		ErrNotConnected, addr, wait.Truncate(time.Millisecond), r.failures)
+}
+
+// update records the outcome of a connection attempt.
+func (r *reconnectBackoff) update(conf *Config, connected bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if connected {
+		r.failures = 0
+		r.next = time.Time{}
+		return
+	}
+	r.failures++
+	r.next = time.Now().Add(computeReconnectBackoff(conf, r.failures))
+}
+
+// reconnectBackoff returns the backoff state of the broker, its own one if it
+// was not created by a client.
+// b.lock must be held by caller
+func (b *Broker) reconnectBackoff() *reconnectBackoff {
+	if b.backoff == nil {
+		b.backoff = &reconnectBackoff{}
+	}
+	return b.backoff
+}
@@ -0,0 +1,112 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestComputeReconnectBackoff(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Net.ReconnectBackoff = 100 * time.Millisecond
+	conf.Net.ReconnectBackoffMax = time.Second
+
+	assert.Zero(t, computeReconnectBackoff(conf, 0))
+	for failures, expected := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+	} {
+		for range 100 {
+			backoff := computeReconnectBackoff(conf, failures)
+			assert.GreaterOrEqual(t, backoff, expected*8/10)
+			assert.LessOrEqual(t, backoff, expected*12/10)
+		}
+	}
+	for range 100 {
+		backoff := computeReconnectBackoff(conf, 100)
+		assert.GreaterOrEqual(t, backoff, 800*time.Millisecond)
+		assert.LessOrEqual(t, backoff, time.Second)
+	}
+
+	conf.Net.ReconnectBackoff = 0
+	assert.Zero(t, computeReconnectBackoff(conf, 5))
+}
+
+func TestBrokerReconnectBackoff(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	addr := mb.Addr()
+	mb.Close()
+
+	conf := NewTestConfig()
+	conf.Net.ReconnectBackoff = 200 * time.Millisecond
+	conf.Net.ReconnectBackoffMax = time.Second
+
+	broker := NewBroker(addr)
+	connect := func() error {
+		start := time.Now()
+		assert.NoError(t, broker.Open(conf))
+		connected, err := broker.Connected()
+		assert.False(t, connected)
+		assert.Error(t, err)
+		// attempts never wait for the backoff with the lock held
+		assert.Less(t, time.Since(start), 160*time.Millisecond)
+		return err
+	}
+
+	assert.NotErrorIs(t, connect(), ErrNotConnected, "the first attempt dials")
+	assert.ErrorIs(t, connect(), ErrNotConnected, "attempts during the backoff fail fast")
+	time.Sleep(240 * time.Millisecond)
+	assert.NotErrorIs(t, connect(), ErrNotConnected, "the backoff is over")
+	// the second failure doubles the backoff
+	time.Sleep(240 * time.Millisecond)
+	assert.ErrorIs(t, connect(), ErrNotConnected)
+	time.Sleep(240 * time.Millisecond)
+	assert.NotErrorIs(t, connect(), ErrNotConnected)
+
+	// a successful connection resets the backoff
+	mb = NewMockBrokerAddr(t, 1, addr)
+	defer mb.Close()
+	time.Sleep(time.Second)
+	for range 2 {
+		assert.NoError(t, broker.Open(conf))
+		connected, err := broker.Connected()
+		assert.NoError(t, err)
+		assert.True(t, connected)
+		assert.NoError(t, broker.Close())
+	}
+}
+
+func TestClientSharesReconnectBackoff(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	addr := mb.Addr()
+	mb.Close()
+
+	conf := NewTestConfig()
+	conf.Net.ReconnectBackoff = time.Minute
+	conf.Net.ReconnectBackoffMax = time.Minute
+
+	c := &client{conf: conf, brokers: make(map[int32]*Broker)}
+	first := &Broker{id: 1, addr: addr}
+	c.registerBroker(first)
+	assert.NoError(t, first.Open(conf))
+	_, err := first.Connected()
+	assert.NotErrorIs(t, err, ErrNotConnected, "the first attempt dials")
+	c.deregisterBroker(first)
+
+	// the broker registered again by the next metadata refresh keeps backing off
+	second := &Broker{id: 1, addr: addr}
+	c.registerBroker(second)
+	assert.Same(t, first.backoff, second.backoff)
+	assert.NoError(t, second.Open(conf))
+	_, err = second.Connected()
+	assert.ErrorIs(t, err, ErrNotConnected)
+
+	other := &Broker{id: 2, addr: "localhost:1"}
+	c.registerBroker(other)
+	assert.NotSame(t, first.backoff, other.backoff)
+}
@@ -0,0 +1,236 @@
+package sarama
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// BrokerEndpoint identifies a broker as advertised in the cluster metadata.
+type BrokerEndpoint struct {
+	// ID is the broker id, or -1 for a bootstrap (seed) broker.
+	ID int32
+	// Rack is the broker's rack, empty if it is not known.
+	Rack string
+	// Addr is the advertised "host:port" address of the broker.
+	Addr string
+}
+
+// BrokerRoute describes how to reach a broker.
+type BrokerRoute struct {
+	// Addr is the "host:port" address to dial. If empty the advertised
+	// address is used.
+	Addr string
+	// Dialer is used to open the connection. If nil the dialer derived from
+	// Config.Net is used.
+	Dialer proxy.Dialer
+}
+
+// BrokerResolver rewrites or resolves the address of a broker before a
+// connection to it is opened, see Config.Net.BrokerResolver. It is useful when
+// the advertised listeners are not reachable from the client's network, e.g.
+// behind NAT or a Kubernetes ingress. The broker keeps its advertised address
+// for every other purpose, including the TLS server name.
+type BrokerResolver interface {
+	ResolveBroker(ctx context.Context, endpoint BrokerEndpoint) (BrokerRoute, error)
+}
+
+// BrokerResolverFunc is an adapter to allow the use of an ordinary function as
+// a BrokerResolver.
+type BrokerResolverFunc func(ctx context.Context, endpoint BrokerEndpoint) (BrokerRoute, error)
+
+// ResolveBroker calls f(ctx, endpoint).
+func (f BrokerResolverFunc) ResolveBroker(ctx context.Context, endpoint BrokerEndpoint) (BrokerRoute, error) {
+	return f(ctx, endpoint)
+}
+
+// StaticBrokerResolver maps brokers to fixed addresses. Brokers that are not
+// found in either map keep their advertised address.
+type StaticBrokerResolver struct {
+	// ByID maps a broker id to the "host:port" address to dial.
+	ByID map[int32]string
+	// ByAddr maps an advertised "host:port" address, or just its host, to the
+	// address to dial. When the key is a bare host the value may also be a
+	// bare host, in which case the advertised port is kept.
+	ByAddr map[string]string
+}
+
+// ResolveBroker implements BrokerResolver.
+func (r *StaticBrokerResolver) ResolveBroker(_ context.Context, endpoint BrokerEndpoint) (BrokerRoute, error) {
+	if addr, ok := r.ByID[endpoint.ID]; ok && endpoint.ID >= 0 {
+		return BrokerRoute{Addr: addr}, nil
+	}
+	if addr, ok := r.ByAddr[endpoint.Addr]; ok {
+		return BrokerRoute{Addr: addr}, nil
+	}
+	host, port, err := net.SplitHostPort(endpoint.Addr)
+	if err != nil {
+		return BrokerRoute{}, nil
+	}
+	if addr, ok := r.ByAddr[host]; ok {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, port)
+		}
+		return BrokerRoute{Addr: addr}, nil
+	}
+	return BrokerRoute{}, nil
+}
+
+// SRVBrokerResolver discovers the address of each broker from a DNS SRV
+// record, e.g. "_broker-1._tcp.kafka.example.com". When the record has
+// several targets, one of the lowest priority is picked at random according
+// to their weights, see RFC 2782.
+type SRVBrokerResolver struct {
+	// Name returns the SRV record to look up for a broker. Returning an empty
+	// string keeps the advertised address.
+	Name func(endpoint BrokerEndpoint) string
+	// LookupSRV performs the lookup, it defaults to net.DefaultResolver.LookupSRV.
+	LookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// ResolveBroker implements BrokerResolver.
+func (r *SRVBrokerResolver) ResolveBroker(ctx context.Context, endpoint BrokerEndpoint) (BrokerRoute, error) {
+	if r.Name == nil {
+		return BrokerRoute{}, nil
+	}
+	name := r.Name(endpoint)
+	if name == "" {
+		return BrokerRoute{}, nil
+	}
+	addrs, err := lookupSRVAddrs(ctx, r.LookupSRV, name)
+	if err != nil {
+		return BrokerRoute{}, err
+	}
+	return BrokerRoute{Addr: addrs[0]}, nil
+}
+
+// BootstrapResolver returns the addresses of the brokers used to bootstrap a
+// Client, see Config.Net.BootstrapResolver.
+type BootstrapResolver interface {
+	ResolveBootstrap(ctx context.Context) ([]string, error)
+}
+
+// BootstrapResolverFunc is an adapter to allow the use of an ordinary function
+// as a BootstrapResolver.
+type BootstrapResolverFunc func(ctx context.Context) ([]string, error)
+
+// ResolveBootstrap calls f(ctx).
+func (f BootstrapResolverFunc) ResolveBootstrap(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// SRVBootstrapResolver discovers the bootstrap brokers from the targets of a
+// DNS SRV record, e.g. "_kafka._tcp.example.com".
+type SRVBootstrapResolver struct {
+	// Name is the SRV record to look up.
+	Name string
+	// LookupSRV performs the lookup, it defaults to net.DefaultResolver.LookupSRV.
+	LookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// ResolveBootstrap implements BootstrapResolver.
+func (r *SRVBootstrapResolver) ResolveBootstrap(ctx context.Context) ([]string, error) {
+	return lookupSRVAddrs(ctx, r.LookupSRV, r.Name)
+}
+
+// lookupSRVAddrs returns the "host:port" targets of an SRV record, ordered by
+// priority and weight, see orderSRV.
+func lookupSRVAddrs(ctx context.Context, lookup func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error), name string) ([]string, error) {
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupSRV
+	}
+	_, records, err := lookup(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no SRV records found for %s", name)
+	}
+	addrs := make([]string, 0, len(records))
+	for _, record := range orderSRV(records) {
+		host := strings.TrimSuffix(record.Target, ".")
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return addrs, nil
+}
+
+// orderSRV orders SRV records like RFC 2782: by ascending priority, and the
+// records of a same priority in a random order weighted by their weights,
+// records with a weight of 0 coming last. The records returned by a custom
+// LookupSRV are thus ordered like those of net.LookupSRV.
+func orderSRV(records []*net.SRV) []*net.SRV {
+	ordered := slices.Clone(records)
+	slices.SortStableFunc(ordered, func(a, b *net.SRV) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+	for i := 0; i < len(ordered); {
+		j := i + 1
+		for j < len(ordered) && ordered[j].Priority == ordered[i].Priority {
+			j++
+		}
+		shuffleSRVByWeight(ordered[i:j])
+		i = j
+	}
+	return ordered
+}
+
+// shuffleSRVByWeight orders records of a same priority by repeatedly picking
+// one at random with a probability proportional to its weight.
+func shuffleSRVByWeight(records []*net.SRV) {
+	total := 0
+	for _, record := range records {
+		total += int(record.Weight)
+	}
+	for len(records) > 1 && total > 0 {
+		n := rand.Intn(total)
+		sum := 0
+		for i, record := range records {
+			sum += int(record.Weight)
+			if sum > n {
+				total -= int(record.Weight)
+				records[0], records[i] = records[i], records[0]
+				break
+			}
+		}
+		records = records[1:]
+	}
+}
+
+// route returns the address and dialer to use to connect to the broker.
+func (b *Broker) route(conf *Config) (string, proxy.Dialer, error) {
+	endpoint := BrokerEndpoint{ID: b.id, Rack: b.Rack(), Addr: b.addr}
+	dialer, err := conf.proxyDialer(endpoint)
+	if err != nil {
+		return "", nil, err
+	}
+	addr := b.addr
+	if conf.Net.BrokerResolver == nil {
+		return addr, dialer, nil
+	}
+
+	ctx := context.Background()
+	if conf.Net.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.Net.DialTimeout)
+		defer cancel()
+	}
+	route, err := conf.Net.BrokerResolver.ResolveBroker(ctx, endpoint)
+	if err != nil {
+		return "", nil, err
+	}
+	if route.Addr != "" && route.Addr != addr {
+		DebugLogger.Printf("broker/%d resolved %s to %s\n", b.id, addr, route.Addr)
+		addr = route.Addr
+	}
+	if route.Dialer != nil {
+		dialer = route.Dialer
+	}
+	return addr, dialer, nil
+}
@@ -0,0 +1,175 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestStaticBrokerResolver(t *testing.T) {
+	resolver := &StaticBrokerResolver{
+		ByID: map[int32]string{1: "10.0.0.1:9092"},
+		ByAddr: map[string]string{
+			"kafka-2.internal:9092": "10.0.0.2:19092",
+			"kafka-3.internal":      "10.0.0.3",
+		},
+	}
+
+	for _, tc := range []struct {
+		endpoint BrokerEndpoint
+		expected string
+	}{
+		{BrokerEndpoint{ID: 1, Addr: "kafka-1.internal:9092"}, "10.0.0.1:9092"},
+		{BrokerEndpoint{ID: 2, Addr: "kafka-2.internal:9092"}, "10.0.0.2:19092"},
+		{BrokerEndpoint{ID: 3, Addr: "kafka-3.internal:9094"}, "10.0.0.3:9094"},
+		{BrokerEndpoint{ID: 4, Addr: "kafka-4.internal:9092"}, ""},
+		{BrokerEndpoint{ID: -1, Addr: "kafka-2.internal:9092"}, "10.0.0.2:19092"},
+	} {
+		route, err := resolver.ResolveBroker(context.Background(), tc.endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, route.Addr, "broker %d at %s", tc.endpoint.ID, tc.endpoint.Addr)
+	}
+}
+
+func TestSRVBrokerResolver(t *testing.T) {
+	resolver := &SRVBrokerResolver{
+		Name: func(endpoint BrokerEndpoint) string {
+			if endpoint.ID < 0 {
+				return ""
+			}
+			return "_broker-" + endpoint.Rack + "._tcp.kafka.example.com"
+		},
+		LookupSRV: func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if name != "_broker-eu1._tcp.kafka.example.com" {
+				return "", nil, errors.New("no such host")
+			}
+			return name, []*net.SRV{{Target: "lb.example.com.", Port: 31092}}, nil
+		},
+	}
+
+	route, err := resolver.ResolveBroker(context.Background(), BrokerEndpoint{ID: 1, Rack: "eu1", Addr: "kafka-1:9092"})
+	assert.NoError(t, err)
+	assert.Equal(t, "lb.example.com:31092", route.Addr)
+
+	route, err = resolver.ResolveBroker(context.Background(), BrokerEndpoint{ID: -1, Addr: "seed:9092"})
+	assert.NoError(t, err)
+	assert.Empty(t, route.Addr)
+
+	_, err = resolver.ResolveBroker(context.Background(), BrokerEndpoint{ID: 2, Rack: "us1", Addr: "kafka-2:9092"})
+	assert.Error(t, err)
+}
+
+type countingDialer struct {
+	dials atomic.Int32
+}
+
+func (d *countingDialer) Dial(network, addr string) (net.Conn, error) {
+	d.dials.Add(1)
+	return net.Dial(network, addr)
+}
+
+func TestClientBrokerResolver(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader := NewMockBroker(t, 2)
+	defer leader.Close()
+
+	// the leader advertises an address that cannot be reached
+	advertised := "kafka-2.invalid:9092"
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetBroker(advertised, leader.BrokerID()).
+			SetLeader("my_topic", 0, leader.BrokerID()),
+	})
+	leader.SetHandlerByMap(map[string]MockResponse{
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetNewest, 1234),
+	})
+
+	dialer := &countingDialer{}
+	config := NewTestConfig()
+	config.Metadata.Retry.Max = 0
+	config.Net.BrokerResolver = BrokerResolverFunc(func(_ context.Context, endpoint BrokerEndpoint) (BrokerRoute, error) {
+		if endpoint.Addr != advertised {
+			return BrokerRoute{}, nil
+		}
+		return BrokerRoute{Addr: leader.Addr(), Dialer: dialer}, nil
+	})
+
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	assert.NoError(t, err)
+	defer safeClose(t, client)
+
+	offset, err := client.GetOffset("my_topic", 0, OffsetNewest)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), offset)
+	assert.Equal(t, int32(1), dialer.dials.Load())
+
+	broker, err := client.Leader("my_topic", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, advertised, broker.Addr())
+}
+
+func TestSRVBootstrapResolver(t *testing.T) {
+	resolver := &SRVBootstrapResolver{
+		Name: "_kafka._tcp.example.com",
+		LookupSRV: func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			return name, []*net.SRV{
+				{Target: "kafka-1.example.com.", Port: 9092},
+				{Target: "kafka-2.example.com.", Port: 9093},
+			}, nil
+		},
+	}
+	addrs, err := resolver.ResolveBootstrap(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kafka-1.example.com:9092", "kafka-2.example.com:9093"}, addrs)
+
+	resolver.LookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return name, nil, nil
+	}
+	_, err = resolver.ResolveBootstrap(context.Background())
+	assert.Error(t, err)
+}
+
+func TestOrderSRV(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "backup", Priority: 20, Weight: 100},
+		{Target: "light", Priority: 10, Weight: 1},
+		{Target: "heavy", Priority: 10, Weight: 3},
+		{Target: "spare", Priority: 10, Weight: 0},
+	}
+	first := map[string]int{}
+	for range 4000 {
+		ordered := orderSRV(records)
+		assert.Len(t, ordered, 4)
+		first[ordered[0].Target]++
+		assert.Equal(t, "spare", ordered[2].Target, "records with a weight of 0 come last within their priority")
+		assert.Equal(t, "backup", ordered[3].Target, "higher priorities come last")
+	}
+	assert.Equal(t, "backup", records[0].Target, "the records are left untouched")
+	assert.Len(t, first, 2)
+	assert.InDelta(t, 3000, first["heavy"], 200, "targets are picked according to their weights")
+	assert.InDelta(t, 1000, first["light"], 200)
+}
+
+func TestSRVBrokerResolverPicksLowestPriority(t *testing.T) {
+	resolver := &SRVBrokerResolver{
+		Name: func(BrokerEndpoint) string { return "_broker-1._tcp.example.com" },
+		LookupSRV: func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			return name, []*net.SRV{
+				{Target: "backup.example.com.", Port: 9092, Priority: 20, Weight: 100},
+				{Target: "primary.example.com.", Port: 9092, Priority: 10, Weight: 1},
+			}, nil
+		},
+	}
+	route, err := resolver.ResolveBroker(context.Background(), BrokerEndpoint{ID: 1, Addr: "broker-1:9092"})
+	assert.NoError(t, err)
+	assert.Equal(t, "primary.example.com:9092", route.Addr)
+}
@@ -296,6 +296,40 @@ func TestBrokerOpenApiVersionsTransportError(t *testing.T) {
 	_, _ = broker.Connected()
 }
 
+func TestBrokerOpenApiVersionsVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		version  KafkaVersion
+		expected int16
+	}{
+		{"unset", KafkaVersion{}, 3},
+		{"before 3.9", V3_8_0_0, 3},
+		{"3.9", V3_9_0_0, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mb := NewMockBroker(t, 0)
+			defer mb.Close()
+			versions := make(chan int16, 1)
+			mb.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+				"ApiVersionsRequest": func(req *request) encoderWithHeader {
+					versions <- req.body.version()
+					return NewMockApiVersionsResponse(t).For(req.body)
+				},
+			})
+
+			conf := NewConfig()
+			conf.Version = tt.version
+			broker := NewBroker(mb.Addr())
+			require.NoError(t, broker.Open(conf))
+			defer broker.Close()
+			connected, err := broker.Connected()
+			require.NoError(t, err)
+			require.True(t, connected)
+			require.Equal(t, tt.expected, <-versions)
+		})
+	}
+}
+
 func TestBrokerOpenSASLv1FailThenReopenTransportError(t *testing.T) {
 	t.Parallel()
 
@@ -365,6 +399,42 @@ func TestBrokerFetch(t *testing.T) {
 		})
 		wg.Wait()
 	})
+
+	t.Run("fetched bytes are attributed to the broker's rack", func(t *testing.T) {
+		mb := NewMockBroker(t, 1)
+		defer mb.Close()
+		mb.SetHandlerByMap(map[string]MockResponse{
+			"FetchRequest": NewMockFetchResponse(t, 1),
+		})
+
+		conf := NewTestConfig()
+		conf.Version = V0_11_0_0
+		conf.RackID = "rack-a"
+		req := &FetchRequest{MaxWaitTime: 100, MinBytes: 1, Version: 4}
+
+		broker := NewBroker(mb.Addr())
+		require.NoError(t, broker.Open(conf))
+		defer safeClose(t, broker)
+
+		sameRack := metrics.GetOrRegisterMeter("consumer-fetch-same-rack-byte-rate", conf.MetricRegistry)
+		crossRack := metrics.GetOrRegisterMeter("consumer-fetch-cross-rack-byte-rate", conf.MetricRegistry)
+
+		_, err := broker.Fetch(req)
+		require.NoError(t, err)
+		require.Zero(t, sameRack.Count()+crossRack.Count(), "unknown broker rack")
+
+		rack := "rack-a"
+		broker.rack = &rack
+		_, err = broker.Fetch(req)
+		require.NoError(t, err)
+		require.Positive(t, sameRack.Count())
+		require.Zero(t, crossRack.Count())
+
+		rack = "rack-b"
+		_, err = broker.Fetch(req)
+		require.NoError(t, err)
+		require.Positive(t, crossRack.Count())
+	})
 }
 
 var ErrTokenFailure = errors.New("Failure generating token")
@@ -1059,6 +1129,87 @@ loop:
 	mockBroker.Close()
 }
 
+func TestSASLCredentialsProviderRotation(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"SaslAuthenticateRequest": NewMockSaslAuthenticateResponse(t).SetSessionLifetimeMs(100),
+		"SaslHandshakeRequest":    NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypePlaintext}),
+		"ApiVersionsRequest":      NewMockApiVersionsResponse(t),
+	})
+
+	authenticated := func() (payloads []string) {
+		for _, rr := range mockBroker.History() {
+			if req, ok := rr.Request.(*SaslAuthenticateRequest); ok {
+				payloads = append(payloads, string(req.SaslAuthBytes))
+			}
+		}
+		return payloads
+	}
+
+	var (
+		lock    sync.Mutex
+		version int
+	)
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypePlaintext
+	conf.Net.SASL.Version = SASLHandshakeV1
+	conf.Net.SASL.CredentialsProvider = SASLCredentialsProviderFunc(func() (*SASLCredentials, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		version++
+		return &SASLCredentials{User: "user", Password: fmt.Sprintf("secret-%d", version)}, nil
+	})
+	require.NoError(t, conf.Validate())
+
+	broker := NewBroker(mockBroker.Addr())
+	require.NoError(t, broker.Open(conf))
+	t.Cleanup(func() { _ = broker.Close() })
+	connected, err := broker.Connected()
+	require.NoError(t, err)
+	require.True(t, connected)
+	require.Equal(t, []string{"\x00user\x00secret-1"}, authenticated())
+
+	// the session expires after 100ms and the connection re-authenticates
+	// with the rotated password
+	deadline := time.Now().Add(time.Second)
+	for len(authenticated()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, err = broker.ApiVersions(&ApiVersionsRequest{})
+		require.NoError(t, err)
+	}
+	payloads := authenticated()
+	require.GreaterOrEqual(t, len(payloads), 2, "the connection should have re-authenticated")
+	require.Equal(t, "\x00user\x00secret-2", payloads[1])
+}
+
+func TestSASLCredentialsProviderFailure(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"SaslAuthenticateRequest": NewMockSaslAuthenticateResponse(t),
+		"SaslHandshakeRequest":    NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypePlaintext}),
+	})
+
+	providerErr := errors.New("vault unavailable")
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypePlaintext
+	conf.Net.SASL.Version = SASLHandshakeV1
+	conf.Net.SASL.CredentialsProvider = SASLCredentialsProviderFunc(func() (*SASLCredentials, error) {
+		return nil, providerErr
+	})
+
+	broker := NewBroker(mockBroker.Addr())
+	require.NoError(t, broker.Open(conf))
+	t.Cleanup(func() { _ = broker.Close() })
+	_, err := broker.Connected()
+	require.ErrorIs(t, err, providerErr)
+}
+
 func TestKip368ReAuthenticationFailure(t *testing.T) {
 	sessionLifetimeMs := int64(100)
 
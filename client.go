@@ -114,6 +114,13 @@ type Client interface {
 	// LeastLoadedBroker retrieves broker that has the least responses pending
 	LeastLoadedBroker() *Broker
 
+	// SubscribeMetadata returns a subscription that receives a MetadataEvent
+	// for every difference (topic created or deleted, partition count changed,
+	// leader or epoch changed, broker added or removed, controller changed)
+	// detected by subsequent metadata refreshes. The subscription must be
+	// closed once it is no longer needed.
+	SubscribeMetadata() *MetadataSubscription
+
 	// PartitionNotReadable checks if partition is not readable
 	PartitionNotReadable(topic string, partition int32) bool
 
@@ -127,6 +134,18 @@ type Client interface {
 	Closed() bool
 }
 
+// MetadataRecoveryStrategy selects how a Client recovers once every known
+// broker has failed, see Config.Metadata.RecoveryStrategy.
+type MetadataRecoveryStrategy int
+
+const (
+	// MetadataRecoveryNone keeps retrying the known brokers.
+	MetadataRecoveryNone MetadataRecoveryStrategy = iota
+	// MetadataRecoveryRebootstrap resolves the bootstrap brokers again and
+	// starts over from them (KIP-899).
+	MetadataRecoveryRebootstrap
+)
+
 const (
 	// OffsetNewest stands for the log head offset, i.e. the offset that will be
 	// assigned to the next message that will be produced to the partition. You
@@ -152,8 +171,9 @@ type client struct {
 	// the broker addresses given to us through the constructor are not guaranteed to be returned in
 	// the cluster metadata (I *think* it only returns brokers who are currently leading partitions?)
 	// so we store them separately
-	seedBrokers []*Broker
-	deadSeeds   []*Broker
+	bootstrapAddrs []string
+	seedBrokers    []*Broker
+	deadSeeds      []*Broker
 
 	controllerID            int32                                   // cluster controller broker id
 	brokers                 map[int32]*Broker                       // maps broker ids to brokers
@@ -161,6 +181,7 @@ type client struct {
 	metadataTopics          map[string]none                         // topics that need to collect metadata
 	coordinators            map[string]int32                        // Maps consumer group names to coordinating broker IDs
 	transactionCoordinators map[string]int32                        // Maps transaction ids to coordinating broker IDs
+	reconnectBackoffs       map[string]*reconnectBackoff            // maps broker addresses to their reconnect backoff
 
 	// If the number of partitions is large, we can get some churn calling cachedPartitions,
 	// so the result is cached.  It is important to update this value whenever metadata is changed
@@ -169,6 +190,8 @@ type client struct {
 	lock sync.RWMutex // protects access to the maps that hold cluster state.
 
 	metadataRefresh metadataRefresh
+	metadataEvents  *metadataNotifier
+	telemetry       *telemetryAgent
 }
 
 // NewClient creates a new Client. It connects to one of the given broker addresses
@@ -185,18 +208,19 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		return nil, err
 	}
 
-	if len(addrs) < 1 {
+	if len(addrs) < 1 && conf.Net.BootstrapResolver == nil {
 		return nil, ConfigurationError("You must provide at least one broker address")
 	}
 
-	if strings.Contains(addrs[0], ".servicebus.windows.net") {
-		if conf.Version.IsAtLeast(V1_1_0_0) || !conf.Version.IsAtLeast(V0_11_0_0) {
+	if len(addrs) > 0 && strings.Contains(addrs[0], ".servicebus.windows.net") {
+		if conf.version().IsAtLeast(V1_1_0_0) || !conf.version().IsAtLeast(V0_11_0_0) {
 			Logger.Println("Connecting to Azure Event Hubs, forcing version to V1_0_0_0 for compatibility")
 			conf.Version = V1_0_0_0
 		}
 	}
 	client := &client{
 		conf:                    conf,
+		bootstrapAddrs:          addrs,
 		closer:                  make(chan none),
 		closed:                  make(chan none),
 		brokers:                 make(map[int32]*Broker),
@@ -205,6 +229,7 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		cachedPartitionsResults: make(map[string][maxPartitionIndex][]int32),
 		coordinators:            make(map[string]int32),
 		transactionCoordinators: make(map[string]int32),
+		metadataEvents:          newMetadataNotifier(),
 	}
 	refresh := func(topics []string) error {
 		deadline := time.Time{}
@@ -219,12 +244,9 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		client.metadataRefresh = refresh
 	}
 
-	if conf.Net.ResolveCanonicalBootstrapServers {
-		var err error
-		addrs, err = client.resolveCanonicalNames(addrs)
-		if err != nil {
-			return nil, err
-		}
+	addrs, err := client.bootstrapServers()
+	if err != nil {
+		return nil, err
 	}
 
 	client.randomizeSeedBrokers(addrs)
@@ -244,6 +266,11 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 	}
 	go withRecover(client.backgroundMetadataUpdater)
 
+	if conf.Telemetry.Enabled {
+		client.telemetry = newTelemetryAgent(client)
+		go withRecover(client.telemetry.run)
+	}
+
 	DebugLogger.Println("Successfully initialized new client")
 
 	return client, nil
@@ -280,16 +307,16 @@ func (client *client) InitProducerID() (*InitProducerIDResponse, error) {
 	for broker := client.LeastLoadedBroker(); broker != nil; broker = client.LeastLoadedBroker() {
 		request := &InitProducerIDRequest{}
 
-		if client.conf.Version.IsAtLeast(V2_7_0_0) {
+		if client.conf.version().IsAtLeast(V2_7_0_0) {
 			// Version 4 adds the support for new error code PRODUCER_FENCED.
 			request.Version = 4
-		} else if client.conf.Version.IsAtLeast(V2_5_0_0) {
+		} else if client.conf.version().IsAtLeast(V2_5_0_0) {
 			// Version 3 adds ProducerId and ProducerEpoch, allowing producers to try to resume after an INVALID_PRODUCER_EPOCH error
 			request.Version = 3
-		} else if client.conf.Version.IsAtLeast(V2_4_0_0) {
+		} else if client.conf.version().IsAtLeast(V2_4_0_0) {
 			// Version 2 is the first flexible version.
 			request.Version = 2
-		} else if client.conf.Version.IsAtLeast(V2_0_0_0) {
+		} else if client.conf.version().IsAtLeast(V2_0_0_0) {
 			// Version 1 is the same as version 0.
 			request.Version = 1
 		}
@@ -317,7 +344,8 @@ func (client *client) Close() error {
 		return ErrClosedClient
 	}
 
-	// shutdown and wait for the background thread before we take the lock, to avoid races
+	// shutdown and wait for the background threads before we take the lock, to avoid races
+	client.telemetry.close()
 	close(client.closer)
 	<-client.closed
 
@@ -336,6 +364,7 @@ func (client *client) Close() error {
 	client.brokers = nil
 	client.metadata = nil
 	client.metadataTopics = nil
+	client.metadataEvents.close()
 
 	return nil
 }
@@ -489,6 +518,15 @@ func (client *client) RefreshBrokers(addrs []string) error {
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
+	client.bootstrapAddrs = addrs
+	client.resetBrokers(addrs)
+
+	return nil
+}
+
+// resetBrokers closes every known broker and replaces the seed brokers with
+// the given addresses. You must hold the write lock before calling this function.
+func (client *client) resetBrokers(addrs []string) {
 	for _, broker := range client.brokers {
 		safeAsyncClose(broker)
 	}
@@ -506,8 +544,6 @@ func (client *client) RefreshBrokers(addrs []string) error {
 	client.deadSeeds = nil
 
 	client.randomizeSeedBrokers(addrs)
-
-	return nil
 }
 
 func (client *client) RefreshMetadata(topics ...string) error {
@@ -545,7 +581,7 @@ func (client *client) Controller() (*Broker, error) {
 		return nil, ErrClosedClient
 	}
 
-	if !client.conf.Version.IsAtLeast(V0_10_0_0) {
+	if !client.conf.version().IsAtLeast(V0_10_0_0) {
 		return nil, ErrUnsupportedVersion
 	}
 
@@ -680,8 +716,25 @@ func (client *client) RefreshTransactionCoordinator(transactionID string) error
 func (client *client) randomizeSeedBrokers(addrs []string) {
 	random := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for _, index := range random.Perm(len(addrs)) {
-		client.seedBrokers = append(client.seedBrokers, NewBroker(addrs[index]))
+		broker := NewBroker(addrs[index])
+		client.shareReconnectBackoff(broker)
+		client.seedBrokers = append(client.seedBrokers, broker)
+	}
+}
+
+// shareReconnectBackoff makes broker share the reconnect backoff state of the
+// other brokers created for its address, see Config.Net.ReconnectBackoff.
+// You must hold the write lock before calling this function.
+func (client *client) shareReconnectBackoff(broker *Broker) {
+	if client.reconnectBackoffs == nil {
+		client.reconnectBackoffs = make(map[string]*reconnectBackoff)
 	}
+	backoff := client.reconnectBackoffs[broker.addr]
+	if backoff == nil {
+		backoff = &reconnectBackoff{}
+		client.reconnectBackoffs[broker.addr] = backoff
+	}
+	broker.backoff = backoff
 }
 
 func (client *client) checkSeedBrokersHealth(brokers []*Broker) {
@@ -751,10 +804,12 @@ func (client *client) registerBroker(broker *Broker) {
 	}
 
 	if client.brokers[broker.ID()] == nil {
+		client.shareReconnectBackoff(broker)
 		client.brokers[broker.ID()] = broker
 		DebugLogger.Printf("client/brokers registered new broker #%d at %s", broker.ID(), broker.Addr())
 	} else if broker.Addr() != client.brokers[broker.ID()].Addr() {
 		safeAsyncClose(client.brokers[broker.ID()])
+		client.shareReconnectBackoff(broker)
 		client.brokers[broker.ID()] = broker
 		Logger.Printf("client/brokers replaced registered broker #%d with %s", broker.ID(), broker.Addr())
 	}
@@ -778,6 +833,54 @@ func (client *client) deregisterBroker(broker *Broker) {
 	}
 }
 
+// bootstrapServers returns the addresses of the bootstrap brokers, either the
+// ones given to the client or those returned by Net.BootstrapResolver.
+func (client *client) bootstrapServers() ([]string, error) {
+	client.lock.RLock()
+	addrs := client.bootstrapAddrs
+	client.lock.RUnlock()
+
+	if resolver := client.conf.Net.BootstrapResolver; resolver != nil {
+		ctx := context.Background()
+		if client.conf.Net.DialTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, client.conf.Net.DialTimeout)
+			defer cancel()
+		}
+		var err error
+		if addrs, err = resolver.ResolveBootstrap(ctx); err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, ErrNoBootstrapServers
+		}
+	}
+
+	if client.conf.Net.ResolveCanonicalBootstrapServers {
+		return client.resolveCanonicalNames(addrs)
+	}
+	return addrs, nil
+}
+
+// rebootstrap discards every known broker and starts over from freshly
+// resolved bootstrap brokers, see KIP-899.
+func (client *client) rebootstrap() error {
+	addrs, err := client.bootstrapServers()
+	if err != nil {
+		return err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.brokers == nil {
+		return ErrClosedClient
+	}
+	Logger.Printf("client/brokers rebootstrapping from %v", addrs)
+	client.resetBrokers(addrs)
+	return nil
+}
+
 func (client *client) resurrectDeadBrokers() {
 	client.lock.Lock()
 	defer client.lock.Unlock()
@@ -787,6 +890,10 @@ func (client *client) resurrectDeadBrokers() {
 	client.deadSeeds = nil
 }
 
+func (client *client) SubscribeMetadata() *MetadataSubscription {
+	return client.metadataEvents.subscribe()
+}
+
 // LeastLoadedBroker returns the broker with the least pending requests.
 // Firstly, choose the broker from cached broker list. If the broker list is empty, choose from seed brokers.
 func (client *client) LeastLoadedBroker() *Broker {
@@ -899,7 +1006,7 @@ func (client *client) getOffset(topic string, partitionID int32, timestamp int64
 		return -1, err
 	}
 
-	request := NewOffsetRequest(client.conf.Version)
+	request := NewOffsetRequest(client.conf.version())
 	request.AddBlock(topic, partitionID, timestamp, 1)
 
 	response, err := broker.GetAvailableOffsets(request)
@@ -1013,7 +1120,7 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 			DebugLogger.Printf("client/metadata fetching metadata for all topics from broker %s\n", broker.addr)
 		}
 
-		req := NewMetadataRequest(client.conf.Version, topics)
+		req := NewMetadataRequest(client.conf.version(), topics)
 		req.AllowAutoTopicCreation = allowAutoTopicCreation
 
 		response, err := broker.GetMetadata(req)
@@ -1072,7 +1179,14 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 	}
 
 	Logger.Println("client/metadata no available broker to send metadata request to")
-	client.resurrectDeadBrokers()
+	if client.conf.Metadata.RecoveryStrategy == MetadataRecoveryRebootstrap {
+		if err := client.rebootstrap(); err != nil {
+			Logger.Println("client/metadata failed to rebootstrap:", err)
+			client.resurrectDeadBrokers()
+		}
+	} else {
+		client.resurrectDeadBrokers()
+	}
 	return retry(error)
 }
 
@@ -1102,6 +1216,8 @@ func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bo
 
 	client.controllerID = data.ControllerID
 
+	client.metadataEvents.update(data, allKnownMetaData)
+
 	if allKnownMetaData {
 		client.metadata = make(map[string]map[int32]*PartitionMetadata)
 		client.metadataTopics = make(map[string]none)
@@ -1214,15 +1330,15 @@ func (client *client) findCoordinator(coordinatorKey string, coordinatorType Coo
 		request.CoordinatorType = coordinatorType
 
 		// Version 1 adds KeyType.
-		if client.conf.Version.IsAtLeast(V0_11_0_0) {
+		if client.conf.version().IsAtLeast(V0_11_0_0) {
 			request.Version = 1
 		}
 		// Version 2 is the same as version 1.
-		if client.conf.Version.IsAtLeast(V2_0_0_0) {
+		if client.conf.version().IsAtLeast(V2_0_0_0) {
 			request.Version = 2
 		}
 		// Version 3 is the first flexible version
-		if client.conf.Version.IsAtLeast(V2_4_0_0) {
+		if client.conf.version().IsAtLeast(V2_4_0_0) {
 			request.Version = 3
 		}
 
@@ -1278,20 +1394,6 @@ func (client *client) findCoordinator(coordinatorKey string, coordinatorType Coo
 func (client *client) resolveCanonicalNames(addrs []string) ([]string, error) {
 	ctx := context.Background()
 
-	dialer := client.Config().getDialer()
-	resolver := net.Resolver{
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			// dial func should only be called once, so switching within is acceptable
-			switch d := dialer.(type) {
-			case proxy.ContextDialer:
-				return d.DialContext(ctx, network, address)
-			default:
-				// we have no choice but to ignore the context
-				return d.Dial(network, address)
-			}
-		},
-	}
-
 	canonicalAddrs := make(map[string]struct{}, len(addrs)) // dedupe as we go
 	for _, addr := range addrs {
 		host, port, err := net.SplitHostPort(addr)
@@ -1299,6 +1401,24 @@ func (client *client) resolveCanonicalNames(addrs []string) ([]string, error) {
 			return nil, err // message includes addr
 		}
 
+		// the lookups go through the proxy the seed broker would be dialed with
+		dialer, err := client.Config().proxyDialer(BrokerEndpoint{ID: -1, Addr: addr})
+		if err != nil {
+			return nil, err
+		}
+		resolver := net.Resolver{
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				// dial func should only be called once, so switching within is acceptable
+				switch d := dialer.(type) {
+				case proxy.ContextDialer:
+					return d.DialContext(ctx, network, address)
+				default:
+					// we have no choice but to ignore the context
+					return d.Dial(network, address)
+				}
+			},
+		}
+
 		ips, err := resolver.LookupHost(ctx, host)
 		if err != nil {
 			return nil, err // message includes host
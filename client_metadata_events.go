@@ -0,0 +1,371 @@
+package sarama
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// MetadataEventType identifies the kind of change described by a MetadataEvent.
+type MetadataEventType int
+
+const (
+	// MetadataTopicCreated is emitted when a topic appears in the cluster metadata.
+	MetadataTopicCreated MetadataEventType = iota
+	// MetadataTopicDeleted is emitted when a previously known topic no longer
+	// exists in the cluster.
+	MetadataTopicDeleted
+	// MetadataPartitionCountChanged is emitted when the number of partitions of
+	// a known topic changes.
+	MetadataPartitionCountChanged
+	// MetadataLeaderChanged is emitted when the leader or the leader epoch of a
+	// partition changes.
+	MetadataLeaderChanged
+	// MetadataBrokerAdded is emitted when a broker joins the cluster, or when a
+	// known broker advertises a new address.
+	MetadataBrokerAdded
+	// MetadataBrokerRemoved is emitted when a broker leaves the cluster, or when
+	// a known broker advertises a new address.
+	MetadataBrokerRemoved
+	// MetadataControllerChanged is emitted when the cluster controller moves to
+	// another broker.
+	MetadataControllerChanged
+)
+
+func (t MetadataEventType) String() string {
+	switch t {
+	case MetadataTopicCreated:
+		return "TopicCreated"
+	case MetadataTopicDeleted:
+		return "TopicDeleted"
+	case MetadataPartitionCountChanged:
+		return "PartitionCountChanged"
+	case MetadataLeaderChanged:
+		return "LeaderChanged"
+	case MetadataBrokerAdded:
+		return "BrokerAdded"
+	case MetadataBrokerRemoved:
+		return "BrokerRemoved"
+	case MetadataControllerChanged:
+		return "ControllerChanged"
+	}
+	return "Unknown"
+}
+
+// MetadataEvent describes a single difference detected by a metadata refresh.
+// Only the fields relevant to the event Type are set.
+type MetadataEvent struct {
+	Type MetadataEventType
+
+	// Topic is set for topic, partition count and leader events.
+	Topic string
+	// Partition is set for MetadataLeaderChanged.
+	Partition int32
+
+	// Partitions and PreviousPartitions are the partition counts before and
+	// after the change, set for topic and partition count events.
+	Partitions, PreviousPartitions int
+
+	// Leader and LeaderEpoch are the new leader of the partition, -1 if it
+	// has none, and PreviousLeader and PreviousLeaderEpoch the old one.
+	Leader, LeaderEpoch                 int32
+	PreviousLeader, PreviousLeaderEpoch int32
+
+	// BrokerID and Addr identify the broker for broker events.
+	BrokerID int32
+	Addr     string
+
+	// Controller and PreviousController are set for MetadataControllerChanged.
+	Controller, PreviousController int32
+}
+
+// MetadataSubscription delivers the MetadataEvents detected by a Client, see
+// Client.SubscribeMetadata. Events are queued internally so that a slow reader
+// never delays metadata refreshes; they are delivered in the order they were
+// detected.
+type MetadataSubscription struct {
+	notifier *metadataNotifier
+	events   chan *MetadataEvent
+
+	lock    sync.Mutex
+	queue   []*MetadataEvent
+	pending chan none
+	closing chan none
+	once    sync.Once
+}
+
+func newMetadataSubscription(notifier *metadataNotifier) *MetadataSubscription {
+	sub := &MetadataSubscription{
+		notifier: notifier,
+		events:   make(chan *MetadataEvent),
+		pending:  make(chan none, 1),
+		closing:  make(chan none),
+	}
+	go withRecover(sub.dispatch)
+	return sub
+}
+
+// Events returns the channel on which events are delivered. It is closed once
+// the subscription or its Client is closed.
+func (s *MetadataSubscription) Events() <-chan *MetadataEvent {
+	return s.events
+}
+
+// Close stops the subscription. Events that have not been read yet are
+// discarded.
+func (s *MetadataSubscription) Close() {
+	s.notifier.unsubscribe(s)
+	s.stop()
+}
+
+func (s *MetadataSubscription) stop() {
+	s.once.Do(func() { close(s.closing) })
+}
+
+func (s *MetadataSubscription) publish(events []*MetadataEvent) {
+	s.lock.Lock()
+	s.queue = append(s.queue, events...)
+	s.lock.Unlock()
+
+	select {
+	case s.pending <- none{}:
+	default:
+	}
+}
+
+func (s *MetadataSubscription) dispatch() {
+	defer close(s.events)
+
+	for {
+		select {
+		case <-s.pending:
+		case <-s.closing:
+			return
+		}
+
+		s.lock.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.lock.Unlock()
+
+		for _, event := range queue {
+			select {
+			case s.events <- event:
+			case <-s.closing:
+				return
+			}
+		}
+	}
+}
+
+type partitionLeader struct {
+	leader, epoch int32
+}
+
+// metadataNotifier keeps its own view of the cluster, only updated from
+// conclusive metadata responses, so that transient errors and brokers dropped
+// from the client's cache do not show up as spurious events.
+type metadataNotifier struct {
+	lock        sync.Mutex
+	subscribers map[*MetadataSubscription]none
+	closed      bool
+
+	brokers    map[int32]string
+	topics     map[string]map[int32]partitionLeader
+	controller int32
+}
+
+func newMetadataNotifier() *metadataNotifier {
+	return &metadataNotifier{
+		subscribers: make(map[*MetadataSubscription]none),
+		brokers:     make(map[int32]string),
+		topics:      make(map[string]map[int32]partitionLeader),
+		controller:  -1,
+	}
+}
+
+func (n *metadataNotifier) subscribe() *MetadataSubscription {
+	sub := newMetadataSubscription(n)
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		sub.stop()
+	} else {
+		n.subscribers[sub] = none{}
+	}
+	return sub
+}
+
+func (n *metadataNotifier) unsubscribe(sub *MetadataSubscription) {
+	n.lock.Lock()
+	delete(n.subscribers, sub)
+	n.lock.Unlock()
+}
+
+// close stops every subscription.
+func (n *metadataNotifier) close() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.closed = true
+	for sub := range n.subscribers {
+		sub.stop()
+		delete(n.subscribers, sub)
+	}
+}
+
+// update diffs a metadata response against the previous state and publishes
+// the resulting events to every subscriber.
+func (n *metadataNotifier) update(data *MetadataResponse, allKnownMetaData bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	events := n.diff(data, allKnownMetaData)
+	if len(events) == 0 {
+		return
+	}
+	for sub := range n.subscribers {
+		sub.publish(events)
+	}
+}
+
+func (n *metadataNotifier) diff(data *MetadataResponse, allKnownMetaData bool) []*MetadataEvent {
+	var events []*MetadataEvent
+
+	if len(data.Brokers) > 0 {
+		current := make(map[int32]string, len(data.Brokers))
+		for _, broker := range data.Brokers {
+			if broker != nil {
+				current[broker.ID()] = broker.Addr()
+			}
+		}
+		for _, id := range sortedBrokerIDs(n.brokers) {
+			if addr, ok := current[id]; !ok || addr != n.brokers[id] {
+				events = append(events, &MetadataEvent{Type: MetadataBrokerRemoved, BrokerID: id, Addr: n.brokers[id]})
+			}
+		}
+		for _, id := range sortedBrokerIDs(current) {
+			if addr, ok := n.brokers[id]; !ok || addr != current[id] {
+				events = append(events, &MetadataEvent{Type: MetadataBrokerAdded, BrokerID: id, Addr: current[id]})
+			}
+		}
+		n.brokers = current
+	}
+
+	// the controller is only part of the response from version 1
+	if data.Version >= 1 && data.ControllerID != n.controller {
+		events = append(events, &MetadataEvent{
+			Type:               MetadataControllerChanged,
+			Controller:         data.ControllerID,
+			PreviousController: n.controller,
+		})
+		n.controller = data.ControllerID
+	}
+
+	// follow topic names rather than the response order so that events are
+	// emitted in a stable order
+	topics := slices.Clone(data.Topics)
+	sort.SliceStable(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+
+	seen := make(map[string]none, len(topics))
+	for _, topic := range topics {
+		seen[topic.Name] = none{}
+		previous, known := n.topics[topic.Name]
+
+		switch topic.Err {
+		case ErrNoError, ErrLeaderNotAvailable:
+		case ErrUnknownTopicOrPartition:
+			if known {
+				events = append(events, topicDeletedEvent(topic.Name, previous))
+				delete(n.topics, topic.Name)
+			}
+			continue
+		default:
+			// not conclusive, keep what we knew
+			continue
+		}
+
+		current := make(map[int32]partitionLeader, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			current[partition.ID] = partitionLeader{leader: partition.Leader, epoch: partition.LeaderEpoch}
+		}
+		n.topics[topic.Name] = current
+
+		if !known {
+			events = append(events, &MetadataEvent{
+				Type:       MetadataTopicCreated,
+				Topic:      topic.Name,
+				Partitions: len(current),
+			})
+			continue
+		}
+
+		if len(current) != len(previous) {
+			events = append(events, &MetadataEvent{
+				Type:               MetadataPartitionCountChanged,
+				Topic:              topic.Name,
+				Partitions:         len(current),
+				PreviousPartitions: len(previous),
+			})
+		}
+
+		for _, id := range sortedPartitionIDs(current) {
+			old, ok := previous[id]
+			if !ok {
+				old = partitionLeader{leader: -1, epoch: -1}
+			}
+			if now := current[id]; now != old {
+				events = append(events, &MetadataEvent{
+					Type:                MetadataLeaderChanged,
+					Topic:               topic.Name,
+					Partition:           id,
+					Leader:              now.leader,
+					LeaderEpoch:         now.epoch,
+					PreviousLeader:      old.leader,
+					PreviousLeaderEpoch: old.epoch,
+				})
+			}
+		}
+	}
+
+	if allKnownMetaData {
+		var deleted []string
+		for name := range n.topics {
+			if _, ok := seen[name]; !ok {
+				deleted = append(deleted, name)
+			}
+		}
+		sort.Strings(deleted)
+		for _, name := range deleted {
+			events = append(events, topicDeletedEvent(name, n.topics[name]))
+			delete(n.topics, name)
+		}
+	}
+
+	return events
+}
+
+func topicDeletedEvent(topic string, previous map[int32]partitionLeader) *MetadataEvent {
+	return &MetadataEvent{
+		Type:               MetadataTopicDeleted,
+		Topic:              topic,
+		PreviousPartitions: len(previous),
+	}
+}
+
+func sortedBrokerIDs(brokers map[int32]string) []int32 {
+	ids := make([]int32, 0, len(brokers))
+	for id := range brokers {
+		ids = append(ids, id)
+	}
+	sort.Sort(int32Slice(ids))
+	return ids
+}
+
+func sortedPartitionIDs(partitions map[int32]partitionLeader) []int32 {
+	ids := make([]int32, 0, len(partitions))
+	for id := range partitions {
+		ids = append(ids, id)
+	}
+	sort.Sort(int32Slice(ids))
+	return ids
+}
@@ -4,6 +4,7 @@ package sarama
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -157,6 +158,35 @@ func TestClientSeedBrokers(t *testing.T) {
 	safeClose(t, client)
 }
 
+func TestClientNegotiatesVersionsPerBroker(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t).SetApiKeys([]ApiVersionsResponseKey{
+			{ApiKey: apiKeyMetadata, MinVersion: 0, MaxVersion: 5},
+		}),
+	})
+
+	// Config.Version is left unset
+	config := NewConfig()
+	config.Metadata.Retry.Backoff = 0
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	require.NoError(t, err)
+	safeClose(t, client)
+
+	var versions []int16
+	for _, rr := range seedBroker.History() {
+		if req, ok := rr.Request.(*MetadataRequest); ok {
+			versions = append(versions, req.Version)
+		}
+	}
+	require.NotEmpty(t, versions)
+	for _, v := range versions {
+		assert.Equal(t, int16(5), v, "metadata requests should be negotiated down to the broker maximum")
+	}
+}
+
 func TestClientMetadata(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	leader := NewMockBroker(t, 5)
@@ -1609,3 +1639,146 @@ func TestUpdateBroker(t *testing.T) {
 		assert.Equal(t, "127.0.0.1:19094", c.brokers[2].Addr())
 	})
 }
+
+func TestClientSubscribeMetadata(t *testing.T) {
+	broker1 := NewMockBroker(t, 1)
+	defer broker1.Close()
+	broker2 := NewMockBroker(t, 2)
+	defer broker2.Close()
+
+	setMetadata := func(response *MockMetadataResponse) {
+		broker1.SetHandlerByMap(map[string]MockResponse{"MetadataRequest": response})
+		broker2.SetHandlerByMap(map[string]MockResponse{"MetadataRequest": response})
+	}
+	setMetadata(NewMockMetadataResponse(t).
+		SetController(broker1.BrokerID()).
+		SetBroker(broker1.Addr(), broker1.BrokerID()).
+		SetLeader("a", 0, broker1.BrokerID()))
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	client, err := NewClient([]string{broker1.Addr()}, config)
+	require.NoError(t, err)
+	defer safeClose(t, client)
+
+	sub := client.SubscribeMetadata()
+	defer sub.Close()
+
+	expect := func(expected ...MetadataEvent) {
+		t.Helper()
+		for _, want := range expected {
+			select {
+			case got := <-sub.Events():
+				assert.Equal(t, want, *got)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s event", want.Type)
+			}
+		}
+		// wait a little for events that would be delivered late
+		select {
+		case got := <-sub.Events():
+			t.Fatalf("unexpected event %+v", got)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// refreshing identical metadata emits nothing
+	require.NoError(t, client.RefreshMetadata())
+	expect()
+
+	setMetadata(NewMockMetadataResponse(t).
+		SetController(broker2.BrokerID()).
+		SetBroker(broker1.Addr(), broker1.BrokerID()).
+		SetBroker(broker2.Addr(), broker2.BrokerID()).
+		SetLeader("a", 0, broker2.BrokerID()).
+		SetLeader("a", 1, broker1.BrokerID()).
+		SetLeader("b", 0, broker1.BrokerID()))
+	require.NoError(t, client.RefreshMetadata())
+	expect(
+		MetadataEvent{Type: MetadataBrokerAdded, BrokerID: 2, Addr: broker2.Addr()},
+		MetadataEvent{Type: MetadataControllerChanged, Controller: 2, PreviousController: 1},
+		MetadataEvent{Type: MetadataPartitionCountChanged, Topic: "a", Partitions: 2, PreviousPartitions: 1},
+		MetadataEvent{Type: MetadataLeaderChanged, Topic: "a", Partition: 0, Leader: 2, PreviousLeader: 1},
+		MetadataEvent{Type: MetadataLeaderChanged, Topic: "a", Partition: 1, Leader: 1, PreviousLeader: -1, PreviousLeaderEpoch: -1},
+		MetadataEvent{Type: MetadataTopicCreated, Topic: "b", Partitions: 1},
+	)
+
+	setMetadata(NewMockMetadataResponse(t).
+		SetController(broker1.BrokerID()).
+		SetBroker(broker1.Addr(), broker1.BrokerID()).
+		SetLeader("b", 0, broker1.BrokerID()))
+	require.NoError(t, client.RefreshMetadata())
+	expect(
+		MetadataEvent{Type: MetadataBrokerRemoved, BrokerID: 2, Addr: broker2.Addr()},
+		MetadataEvent{Type: MetadataControllerChanged, Controller: 1, PreviousController: 2},
+		MetadataEvent{Type: MetadataTopicDeleted, Topic: "a", PreviousPartitions: 2},
+	)
+
+	sub.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+}
+
+func TestClientBootstrapResolver(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+	})
+
+	config := NewTestConfig()
+	config.Net.BootstrapResolver = BootstrapResolverFunc(func(context.Context) ([]string, error) {
+		return []string{seedBroker.Addr()}, nil
+	})
+	client, err := NewClient(nil, config)
+	require.NoError(t, err)
+	safeClose(t, client)
+
+	config.Net.BootstrapResolver = BootstrapResolverFunc(func(context.Context) ([]string, error) {
+		return nil, nil
+	})
+	_, err = NewClient(nil, config)
+	require.ErrorIs(t, err, ErrNoBootstrapServers)
+}
+
+func TestClientRebootstrap(t *testing.T) {
+	oldCluster := NewMockBroker(t, 1)
+	newCluster := NewMockBroker(t, 2)
+	defer newCluster.Close()
+
+	for _, b := range []*MockBroker{oldCluster, newCluster} {
+		b.SetHandlerByMap(map[string]MockResponse{
+			"MetadataRequest": NewMockMetadataResponse(t).
+				SetBroker(b.Addr(), b.BrokerID()).
+				SetLeader("my_topic", 0, b.BrokerID()),
+		})
+	}
+
+	var bootstrap atomic.Value
+	bootstrap.Store(oldCluster.Addr())
+
+	config := NewTestConfig()
+	config.Metadata.Retry.Backoff = 0
+	config.Metadata.RecoveryStrategy = MetadataRecoveryRebootstrap
+	config.Net.BootstrapResolver = BootstrapResolverFunc(func(context.Context) ([]string, error) {
+		return []string{bootstrap.Load().(string)}, nil
+	})
+	client, err := NewClient(nil, config)
+	require.NoError(t, err)
+	defer safeClose(t, client)
+
+	leader, err := client.Leader("my_topic", 0)
+	require.NoError(t, err)
+	require.Equal(t, oldCluster.BrokerID(), leader.ID())
+
+	// every known broker goes away, the client must find the new cluster
+	// through the bootstrap resolver
+	oldCluster.Close()
+	bootstrap.Store(newCluster.Addr())
+
+	require.NoError(t, client.RefreshMetadata("my_topic"))
+	leader, err = client.Leader("my_topic", 0)
+	require.NoError(t, err)
+	require.Equal(t, newCluster.BrokerID(), leader.ID())
+}
@@ -48,6 +48,17 @@ type Config struct {
 		// https://kafka.apache.org/28/documentation.html#producerconfigs_max.in.flight.requests.per.connection
 		MaxOpenRequests int
 
+		// DedicatedConnections opens, for each listed RequestClass, the given
+		// number of additional connections to every broker and routes the
+		// requests of that class to them, so that e.g. large fetch responses do
+		// not delay produce acknowledgements or group heartbeats. Requests of a
+		// class with several connections are spread round-robin across them,
+		// MaxOpenRequests applies to each connection. They are opened once the
+		// primary connection is established. RequestClassDefault
+		// requests always use the primary connection (defaults to nil, all
+		// requests share a single connection).
+		DedicatedConnections map[RequestClass]int
+
 		// All three of the below configurations are similar to the
 		// `socket.timeout.ms` setting in JVM kafka. All of them default
 		// to 30 seconds.
@@ -55,15 +66,61 @@ type Config struct {
 		ReadTimeout  time.Duration // How long to wait for a response.
 		WriteTimeout time.Duration // How long to wait for a transmit.
 
+		// RequestTimeouts overrides ReadTimeout for specific requests, keyed
+		// by their API name in the Kafka protocol, e.g. "DeleteRecords" or
+		// "Metadata". The MaxWaitTime of a Fetch request is always added to
+		// its timeout. A request that is not answered in time fails with a
+		// *RequestTimeoutError (defaults to nil, ReadTimeout applies to every
+		// request).
+		RequestTimeouts map[string]time.Duration
+
+		// ReconnectBackoff is how long to wait before connecting again to a
+		// broker after a failed connection attempt, attempts made sooner fail
+		// immediately with an error wrapping ErrNotConnected, which the client,
+		// producers and consumers retry with their own Retry.Backoff. The wait
+		// doubles with each consecutive failure up to ReconnectBackoffMax,
+		// with 20% random jitter so that many clients do not reconnect to a
+		// restarting broker at the same time. The failures are counted per
+		// broker address for all the connections of a client. Similar to
+		// `reconnect.backoff.ms` in the JVM version.
+		// Set to 0 to reconnect immediately. Defaults to 50ms.
+		ReconnectBackoff time.Duration
+		// ReconnectBackoffMax caps ReconnectBackoff. Similar to
+		// `reconnect.backoff.max.ms` in the JVM version. Defaults to 1s.
+		ReconnectBackoffMax time.Duration
+
+		// MaxIdleTime closes connections to brokers that have not been used
+		// for this long, before the broker drops them on its side. They are
+		// reopened transparently on next use. It should be lower than the
+		// broker's `connections.max.idle.ms` (10 minutes by default). Similar
+		// to `connections.max.idle.ms` in the JVM version. Set to 0 to keep
+		// connections open forever. Defaults to 9 minutes.
+		MaxIdleTime time.Duration
+
 		// ResolveCanonicalBootstrapServers turns each bootstrap broker address
 		// into a set of IPs, then does a reverse lookup on each one to get its
 		// canonical hostname. This list of hostnames then replaces the
 		// original address list. Similar to the `client.dns.lookup` option in
 		// the JVM client, this is especially useful with GSSAPI, where it
 		// allows providing an alias record instead of individual broker
-		// hostnames. Defaults to false.
+		// hostnames. The lookups go through the proxy selected for each
+		// bootstrap broker, see Proxy. Defaults to false.
 		ResolveCanonicalBootstrapServers bool
 
+		// BootstrapResolver, if set, returns the addresses of the bootstrap
+		// brokers, replacing the ones given to NewClient. It is called when
+		// the client is created and every time it rebootstraps, see
+		// Metadata.RecoveryStrategy. See SRVBootstrapResolver to discover
+		// them from a DNS SRV record (defaults to nil).
+		BootstrapResolver BootstrapResolver
+
+		// BrokerResolver, if set, is consulted every time a connection to a
+		// broker is opened and may rewrite the address to dial or select a
+		// different dialer for that broker, based on its id, rack and
+		// advertised address. See StaticBrokerResolver and SRVBrokerResolver
+		// (defaults to nil, the advertised address is dialed).
+		BrokerResolver BrokerResolver
+
 		TLS struct {
 			// Whether or not to use TLS when connecting to the broker
 			// (defaults to false).
@@ -71,6 +128,11 @@ type Config struct {
 			// The TLS configuration to use for secure connections if
 			// enabled (defaults to nil).
 			Config *tls.Config
+			// ConfigProvider, when set, supplies the TLS configuration of
+			// every new connection instead of Config, so that rotated
+			// certificates are picked up without recreating the client.
+			// See NewTLSFileReloader (defaults to nil).
+			ConfigProvider TLSConfigProvider
 		}
 
 		// SASL based authentication with broker. While there are multiple SASL authentication methods
@@ -80,7 +142,8 @@ type Config struct {
 			// (defaults to false).
 			Enable bool
 			// SASLMechanism is the name of the enabled SASL mechanism.
-			// Possible values: OAUTHBEARER, PLAIN (defaults to PLAIN).
+			// Possible values: OAUTHBEARER, PLAIN, SCRAM-SHA-256,
+			// SCRAM-SHA-512, GSSAPI and AWS_MSK_IAM (defaults to PLAIN).
 			Mechanism SASLMechanism
 			// Version is the SASL Protocol Version to use
 			// Kafka > 1.x should use V1, except on Azure EventHub which use V0
@@ -99,6 +162,12 @@ type Config struct {
 			User string
 			// Password for SASL/PLAIN authentication
 			Password string // #nosec G117 -- public SASL config schema; callers set this credential explicitly.
+			// CredentialsProvider, when set, supplies the User and Password
+			// for SASL/PLAIN and SASL/SCRAM instead of the fields above. It is
+			// consulted every time a connection authenticates, including
+			// KIP-368 re-authentications, so rotated credentials take effect
+			// without recreating the client.
+			CredentialsProvider SASLCredentialsProvider
 			// authz id used for SASL/SCRAM authentication
 			SCRAMAuthzID string
 			// SCRAMClientGeneratorFunc is a generator of a user provided implementation of a SCRAM
@@ -107,10 +176,16 @@ type Config struct {
 			// TokenProvider is a user-defined callback for generating
 			// access tokens for SASL/OAUTHBEARER auth. See the
 			// AccessTokenProvider interface docs for proper implementation
-			// guidelines.
+			// guidelines, or NewOAuth2ClientCredentialsProvider for a
+			// bundled implementation.
 			TokenProvider AccessTokenProvider
 
 			GSSAPI GSSAPIConfig
+
+			// AWSMSKIAM configures the AWS_MSK_IAM mechanism, which
+			// authenticates to Amazon MSK with payloads signed by AWS
+			// credentials.
+			AWSMSKIAM AWSMSKIAMConfig
 		}
 
 		// KeepAlive specifies the keep-alive period for an active network connection (defaults to 0).
@@ -130,6 +205,16 @@ type Config struct {
 			Enable bool
 			// The proxy dialer to use enabled (defaults to nil).
 			Dialer proxy.Dialer
+			// URL is the proxy to use when no Dialer is set, either a SOCKS5
+			// proxy "socks5://[user:password@]host:port" or an HTTP proxy
+			// supporting the CONNECT method "http://[user:password@]host:port"
+			// (defaults to "").
+			URL string
+			// Rules select a different proxy, or none, for the brokers matching
+			// their advertised address or rack. The first matching rule is used,
+			// brokers matching none use Dialer or URL, or connect directly if
+			// neither is set (defaults to nil).
+			Rules []ProxyRule
 		}
 	}
 
@@ -178,6 +263,15 @@ type Config struct {
 		// See https://github.com/IBM/sarama/issues/3224 for more details.
 		// SingleFlight defaults to true.
 		SingleFlight bool
+
+		// RecoveryStrategy controls what the client does once every known
+		// broker, including the bootstrap ones, has failed to answer a
+		// metadata request. MetadataRecoveryNone keeps retrying the same
+		// brokers while MetadataRecoveryRebootstrap discards them and
+		// resolves the bootstrap brokers again before the next retry (see
+		// KIP-899). Similar to `metadata.recovery.strategy` in the JVM
+		// version. Defaults to MetadataRecoveryNone.
+		RecoveryStrategy MetadataRecoveryStrategy
 	}
 
 	// Producer is the namespace for configuration related to producing messages,
@@ -422,6 +516,34 @@ type Config struct {
 			// record exceeds the limit, so keep this comfortably below the global
 			// `sarama.MaxResponseSize` safety net. Only used for Kafka >= 0.10.1.
 			MaxBytes int32
+			// RateLimit caps the rate at which this consumer reads from all
+			// topics combined, independently of any broker-side quotas. Fetch
+			// requests are deferred while the limit is exceeded, so short bursts
+			// of up to one fetch response are still possible. Defaults to no limit.
+			RateLimit FetchRateLimit
+			// TopicRateLimits caps the read rate of individual topics, keyed by
+			// topic name. Partitions of a throttled topic are left out of fetch
+			// requests until the topic is back under its limit. Applies in
+			// addition to RateLimit.
+			TopicRateLimits map[string]FetchRateLimit
+		}
+
+		// ReadReplica controls fetching from the replica that the partition
+		// leader designates for this consumer's RackID (KIP-392, requires
+		// Version >= V2_4_0_0 and a replica selector configured on the
+		// brokers).
+		ReadReplica struct {
+			// Policy decides which of the designated replicas are fetched
+			// from (defaults to ReadReplicaAny).
+			Policy ReadReplicaPolicy
+			// The number of consecutive failed fetches from a preferred read
+			// replica after which the consumer falls back to the leader
+			// (defaults to 1).
+			MaxErrors int
+			// How long a preferred read replica is used before the consumer
+			// returns to the leader to have it re-evaluated. Defaults to 0,
+			// which uses Metadata.RefreshFrequency.
+			RecheckInterval time.Duration
 		}
 		// The maximum amount of time the broker will wait for Consumer.Fetch.Min
 		// bytes to become available before it returns fewer than that anyways. The
@@ -510,6 +632,19 @@ type Config struct {
 		Interceptors []ConsumerInterceptor
 	}
 
+	// Telemetry is the namespace for pushing client metrics to the brokers
+	// (KIP-714).
+	Telemetry struct {
+		// If enabled, the client asks the brokers which of its metrics they
+		// want, and pushes the matching MetricRegistry values to them as
+		// OTLP at the interval they request. Metric names are converted
+		// from "incoming-byte-rate-for-broker-1" to
+		// "sarama.incoming.byte.rate" with a broker.id attribute. Requires
+		// Version >= V3_7_0_0 and brokers with a client telemetry plugin
+		// (defaults to false).
+		Enabled bool
+	}
+
 	// A user-provided string sent with every request to the brokers for logging,
 	// debugging, and auditing purposes. Defaults to "sarama", but you should
 	// probably set it to something specific to your application.
@@ -528,13 +663,21 @@ type Config struct {
 	// connection. This defaults to `true` to match the official Java client
 	// and most 3rdparty ones.
 	ApiVersionsRequest bool
-	// The version of Kafka that Sarama will assume it is running against.
-	// Defaults to 2.8 as Sarama has full protocol coverage for that
-	// version. Since Kafka provides
-	// backwards-compatibility, setting it to a version older than you have
-	// will not break anything, although it may prevent you from using the
-	// latest features. Setting it to a version greater than you are actually
-	// running may lead to random breakage.
+	// The highest version of Kafka that Sarama will assume it is running
+	// against. It is optional: the zero value, which is the default, means
+	// "negotiate up to MaxVersion", every request starts from the highest
+	// protocol version Sarama implements and is negotiated down to what each
+	// broker advertises in its ApiVersions response, which keeps working
+	// across rolling upgrades of mixed-version clusters. Validate does not
+	// write MaxVersion into it, so unset and an explicit cap stay distinct.
+	// Setting it
+	// caps the protocol versions used, and is required when
+	// ApiVersionsRequest is disabled since there is nothing to negotiate
+	// with. Since Kafka provides backwards-compatibility, setting it to a
+	// version older than you have will not break anything, although it may
+	// prevent you from using the latest features. Setting it to a version
+	// greater than you are actually running while ApiVersionsRequest is
+	// disabled may lead to random breakage.
 	Version KafkaVersion
 	// The registry to define metrics into.
 	// Defaults to a local registry.
@@ -556,6 +699,9 @@ func NewConfig() *Config {
 	c.Net.DialTimeout = 30 * time.Second
 	c.Net.ReadTimeout = 30 * time.Second
 	c.Net.WriteTimeout = 30 * time.Second
+	c.Net.MaxIdleTime = 9 * time.Minute
+	c.Net.ReconnectBackoff = 50 * time.Millisecond
+	c.Net.ReconnectBackoffMax = time.Second
 	c.Net.SASL.Handshake = true
 	c.Net.SASL.Version = SASLHandshakeV1
 
@@ -583,6 +729,7 @@ func NewConfig() *Config {
 	c.Consumer.Fetch.Default = 1024 * 1024
 	c.Consumer.Fetch.MaxBytes = 50 * 1024 * 1024
 	c.Consumer.Retry.Backoff = 2 * time.Second
+	c.Consumer.ReadReplica.MaxErrors = 1
 	c.Consumer.MaxWaitTime = 500 * time.Millisecond
 	c.Consumer.MaxProcessingTime = 100 * time.Millisecond
 	c.Consumer.Return.Errors = false
@@ -602,7 +749,6 @@ func NewConfig() *Config {
 	c.ClientID = defaultClientID
 	c.ChannelBufferSize = 256
 	c.ApiVersionsRequest = true
-	c.Version = DefaultVersion
 	c.MetricRegistry = metrics.NewRegistry()
 
 	return c
@@ -616,13 +762,29 @@ func (c *Config) groupStrategies() []BalanceStrategy {
 	return c.Consumer.Group.Rebalance.GroupStrategies
 }
 
+// version returns the Kafka version capping the protocol versions: Version,
+// or MaxVersion when it is unset. Validate leaves Version untouched so that a
+// Config shared between clients is never written to.
+func (c *Config) version() KafkaVersion {
+	if c.Version == (KafkaVersion{}) {
+		return MaxVersion
+	}
+	return c.Version
+}
+
 // Validate checks a Config instance. It will return a
 // ConfigurationError if the specified values don't make sense.
 //
 //nolint:gocyclo // This function's cyclomatic complexity has go beyond 100
 func (c *Config) Validate() error {
+	// an unset Version does not cap the protocol versions, requests are
+	// negotiated with each broker instead
+	if c.Version == (KafkaVersion{}) && !c.ApiVersionsRequest {
+		return ConfigurationError("Version must be set when ApiVersionsRequest is disabled")
+	}
+
 	// some configuration values should be warned on but not fail completely, do those first
-	if !c.Net.TLS.Enable && c.Net.TLS.Config != nil {
+	if !c.Net.TLS.Enable && (c.Net.TLS.Config != nil || c.Net.TLS.ConfigProvider != nil) {
 		Logger.Println("Net.TLS is disabled but a non-nil configuration was provided.")
 	}
 	if !c.Net.SASL.Enable {
@@ -671,6 +833,9 @@ func (c *Config) Validate() error {
 	}
 
 	// validate Net values
+	if err := c.validateProxy(); err != nil {
+		return err
+	}
 	switch {
 	case c.Net.MaxOpenRequests <= 0:
 		return ConfigurationError("Net.MaxOpenRequests must be > 0")
@@ -680,6 +845,16 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Net.ReadTimeout must be > 0")
 	case c.Net.WriteTimeout <= 0:
 		return ConfigurationError("Net.WriteTimeout must be > 0")
+	case !validRequestTimeouts(c.Net.RequestTimeouts):
+		return ConfigurationError("Net.RequestTimeouts must map Kafka API names to timeouts > 0")
+	case c.Net.MaxIdleTime < 0:
+		return ConfigurationError("Net.MaxIdleTime must be >= 0")
+	case c.Net.ReconnectBackoff < 0:
+		return ConfigurationError("Net.ReconnectBackoff must be >= 0")
+	case c.Net.ReconnectBackoffMax < c.Net.ReconnectBackoff:
+		return ConfigurationError("Net.ReconnectBackoffMax must be >= Net.ReconnectBackoff")
+	case !validDedicatedConnections(c.Net.DedicatedConnections):
+		return ConfigurationError("Net.DedicatedConnections must map produce, fetch or group request classes to a number of connections >= 0")
 	case c.Net.SASL.Enable:
 		if c.Net.SASL.Mechanism == "" {
 			c.Net.SASL.Mechanism = SASLTypePlaintext
@@ -689,22 +864,16 @@ func (c *Config) Validate() error {
 		}
 		switch c.Net.SASL.Mechanism {
 		case SASLTypePlaintext:
-			if c.Net.SASL.User == "" {
-				return ConfigurationError("Net.SASL.User must not be empty when SASL is enabled")
-			}
-			if c.Net.SASL.Password == "" {
-				return ConfigurationError("Net.SASL.Password must not be empty when SASL is enabled")
+			if err := c.validateSASLCredentials(); err != nil {
+				return err
 			}
 		case SASLTypeOAuth:
 			if c.Net.SASL.TokenProvider == nil {
 				return ConfigurationError("An AccessTokenProvider instance must be provided to Net.SASL.TokenProvider")
 			}
 		case SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512:
-			if c.Net.SASL.User == "" {
-				return ConfigurationError("Net.SASL.User must not be empty when SASL is enabled")
-			}
-			if c.Net.SASL.Password == "" {
-				return ConfigurationError("Net.SASL.Password must not be empty when SASL is enabled")
+			if err := c.validateSASLCredentials(); err != nil {
+				return err
 			}
 			if c.Net.SASL.SCRAMClientGeneratorFunc == nil {
 				return ConfigurationError("A SCRAMClientGeneratorFunc function must be provided to Net.SASL.SCRAMClientGeneratorFunc")
@@ -726,10 +895,7 @@ func (c *Config) Validate() error {
 						" and Net.SASL.GSSAPI.AuthType = KRB5_KEYTAB_AUTH")
 				}
 			case KRB5_CCACHE_AUTH:
-				if c.Net.SASL.GSSAPI.CCachePath == "" {
-					return ConfigurationError("Net.SASL.GSSAPI.CCachePath must not be empty when GSS-API mechanism is used" +
-						" and Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH")
-				}
+				// an empty CCachePath reads the cache named by KRB5CCNAME
 			default:
 				return ConfigurationError("Net.SASL.GSSAPI.AuthType is invalid. Possible values are KRB5_USER_AUTH, KRB5_KEYTAB_AUTH, and KRB5_CCACHE_AUTH")
 			}
@@ -743,9 +909,13 @@ func (c *Config) Validate() error {
 			if c.Net.SASL.GSSAPI.Realm == "" {
 				return ConfigurationError("Net.SASL.GSSAPI.Realm must not be empty when GSS-API mechanism is used")
 			}
+		case SASLTypeAWSMSKIAM:
+			if c.Net.SASL.AWSMSKIAM.Signer == nil && c.Net.SASL.AWSMSKIAM.CredentialsProvider == nil {
+				return ConfigurationError("An AWSCredentialsProvider instance must be provided to Net.SASL.AWSMSKIAM.CredentialsProvider")
+			}
 		default:
-			msg := fmt.Sprintf("The SASL mechanism configuration is invalid. Possible values are `%s`, `%s`, `%s`, `%s` and `%s`",
-				SASLTypeOAuth, SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512, SASLTypeGSSAPI)
+			msg := fmt.Sprintf("The SASL mechanism configuration is invalid. Possible values are `%s`, `%s`, `%s`, `%s`, `%s` and `%s`",
+				SASLTypeOAuth, SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512, SASLTypeGSSAPI, SASLTypeAWSMSKIAM)
 			return ConfigurationError(msg)
 		}
 	}
@@ -764,6 +934,8 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Metadata.Retry.Backoff must be >= 0")
 	case c.Metadata.RefreshFrequency < 0:
 		return ConfigurationError("Metadata.RefreshFrequency must be >= 0")
+	case c.Metadata.RecoveryStrategy != MetadataRecoveryNone && c.Metadata.RecoveryStrategy != MetadataRecoveryRebootstrap:
+		return ConfigurationError("Metadata.RecoveryStrategy must be MetadataRecoveryNone or MetadataRecoveryRebootstrap")
 	}
 
 	// validate the Producer values
@@ -792,7 +964,7 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Producer.Retry.Backoff must be >= 0")
 	}
 
-	if c.Producer.Compression == CompressionLZ4 && !c.Version.IsAtLeast(V0_10_0_0) {
+	if c.Producer.Compression == CompressionLZ4 && !c.version().IsAtLeast(V0_10_0_0) {
 		return ConfigurationError("lz4 compression requires Version >= V0_10_0_0")
 	}
 
@@ -804,12 +976,12 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	if c.Producer.Compression == CompressionZSTD && !c.Version.IsAtLeast(V2_1_0_0) {
+	if c.Producer.Compression == CompressionZSTD && !c.version().IsAtLeast(V2_1_0_0) {
 		return ConfigurationError("zstd compression requires Version >= V2_1_0_0")
 	}
 
 	if c.Producer.Idempotent {
-		if !c.Version.IsAtLeast(V0_11_0_0) {
+		if !c.version().IsAtLeast(V0_11_0_0) {
 			return ConfigurationError("Idempotent producer requires Version >= V0_11_0_0")
 		}
 		if c.Producer.Retry.Max == 0 {
@@ -821,6 +993,9 @@ func (c *Config) Validate() error {
 		if c.Net.MaxOpenRequests > 1 {
 			return ConfigurationError("Idempotent producer requires Net.MaxOpenRequests to be 1")
 		}
+		if c.Net.DedicatedConnections[RequestClassProduce] > 1 {
+			return ConfigurationError("Idempotent producer requires at most 1 dedicated produce connection")
+		}
 	}
 
 	if c.Producer.Transaction.ID != "" && !c.Producer.Idempotent {
@@ -837,6 +1012,18 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Consumer.Fetch.Max must be >= 0")
 	case c.Consumer.Fetch.MaxBytes <= 0:
 		return ConfigurationError("Consumer.Fetch.MaxBytes must be > 0")
+	case c.Consumer.ReadReplica.Policy < ReadReplicaAny || c.Consumer.ReadReplica.Policy > ReadReplicaLeaderOnly:
+		return ConfigurationError("Consumer.ReadReplica.Policy is invalid")
+	case c.Consumer.ReadReplica.Policy == ReadReplicaSameRack && c.RackID == "":
+		return ConfigurationError("Consumer.ReadReplica.Policy ReadReplicaSameRack requires RackID")
+	case c.Consumer.ReadReplica.MaxErrors <= 0:
+		return ConfigurationError("Consumer.ReadReplica.MaxErrors must be > 0")
+	case c.Consumer.ReadReplica.RecheckInterval < 0:
+		return ConfigurationError("Consumer.ReadReplica.RecheckInterval must be >= 0")
+	case c.Consumer.Fetch.RateLimit.BytesPerSecond < 0:
+		return ConfigurationError("Consumer.Fetch.RateLimit.BytesPerSecond must be >= 0")
+	case c.Consumer.Fetch.RateLimit.RecordsPerSecond < 0:
+		return ConfigurationError("Consumer.Fetch.RateLimit.RecordsPerSecond must be >= 0")
 	case c.Consumer.MaxWaitTime < 1*time.Millisecond:
 		return ConfigurationError("Consumer.MaxWaitTime must be >= 1ms")
 	case c.Consumer.MaxProcessingTime <= 0:
@@ -855,6 +1042,12 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Consumer.IsolationLevel must be ReadUncommitted or ReadCommitted")
 	}
 
+	for topic, limit := range c.Consumer.Fetch.TopicRateLimits {
+		if limit.BytesPerSecond < 0 || limit.RecordsPerSecond < 0 {
+			return ConfigurationError(fmt.Sprintf("Consumer.Fetch.TopicRateLimits for topic %q must be >= 0", topic))
+		}
+	}
+
 	if c.Consumer.Offsets.CommitInterval != 0 {
 		Logger.Println("Deprecation warning: Consumer.Offsets.CommitInterval exists for historical compatibility" +
 			" and should not be used. Please use Consumer.Offsets.AutoCommit, the current value will be ignored")
@@ -865,7 +1058,7 @@ func (c *Config) Validate() error {
 	}
 
 	// validate IsolationLevel
-	if c.Consumer.IsolationLevel == ReadCommitted && !c.Version.IsAtLeast(V0_11_0_0) {
+	if c.Consumer.IsolationLevel == ReadCommitted && !c.version().IsAtLeast(V0_11_0_0) {
 		return ConfigurationError("ReadCommitted requires Version >= V0_11_0_0")
 	}
 
@@ -898,13 +1091,13 @@ func (c *Config) Validate() error {
 		if err != nil {
 			return err
 		}
-		if protocol >= RebalanceProtocolCooperative && !c.Version.IsAtLeast(V2_4_0_0) {
+		if protocol >= RebalanceProtocolCooperative && !c.version().IsAtLeast(V2_4_0_0) {
 			return ConfigurationError("cooperative balance strategies require Version >= 2.4")
 		}
 	}
 
 	if c.Consumer.Group.InstanceId != "" {
-		if !c.Version.IsAtLeast(V2_3_0_0) {
+		if !c.version().IsAtLeast(V2_3_0_0) {
 			return ConfigurationError("Consumer.Group.InstanceId need Version >= 2.3")
 		}
 		if err := validateGroupInstanceId(c.Consumer.Group.InstanceId); err != nil {
@@ -916,27 +1109,47 @@ func (c *Config) Validate() error {
 	switch {
 	case c.ChannelBufferSize < 0:
 		return ConfigurationError("ChannelBufferSize must be >= 0")
+	case c.Telemetry.Enabled && !c.version().IsAtLeast(V3_7_0_0):
+		return ConfigurationError("Telemetry requires Version >= V3_7_0_0")
 	}
 
 	// only validate clientID locally for Kafka versions before KIP-190 was implemented
-	if !c.Version.IsAtLeast(V1_0_0_0) && !validClientID.MatchString(c.ClientID) {
+	if !c.version().IsAtLeast(V1_0_0_0) && !validClientID.MatchString(c.ClientID) {
 		return ConfigurationError(fmt.Sprintf("ClientID value %q is not valid for Kafka versions before 1.0.0", c.ClientID))
 	}
 
 	return nil
 }
 
-func (c *Config) getDialer() proxy.Dialer {
-	if c.Net.Proxy.Enable {
-		Logger.Println("using proxy")
-		return c.Net.Proxy.Dialer
-	} else {
-		return &net.Dialer{
-			Timeout:   c.Net.DialTimeout,
-			KeepAlive: c.Net.KeepAlive,
-			LocalAddr: c.Net.LocalAddr,
+// validateSASLCredentials checks the static SASL/PLAIN and SASL/SCRAM
+// credentials, which are not needed when a CredentialsProvider is set.
+func (c *Config) validateSASLCredentials() error {
+	switch {
+	case c.Net.SASL.CredentialsProvider != nil:
+		return nil
+	case c.Net.SASL.User == "":
+		return ConfigurationError("Net.SASL.User must not be empty when SASL is enabled")
+	case c.Net.SASL.Password == "":
+		return ConfigurationError("Net.SASL.Password must not be empty when SASL is enabled")
+	}
+	return nil
+}
+
+func validDedicatedConnections(connections map[RequestClass]int) bool {
+	for class, n := range connections {
+		if class <= RequestClassDefault || class >= numRequestClasses || n < 0 {
+			return false
 		}
 	}
+	return true
+}
+
+func (c *Config) directDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   c.Net.DialTimeout,
+		KeepAlive: c.Net.KeepAlive,
+		LocalAddr: c.Net.LocalAddr,
+	}
 }
 
 const MAX_GROUP_INSTANCE_ID_LENGTH = 249
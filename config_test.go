@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rcrowley/go-metrics"
 	assert "github.com/stretchr/testify/require"
@@ -35,6 +36,24 @@ func TestDefaultConfigValidates(t *testing.T) {
 	}
 }
 
+func TestUnsetVersionValidates(t *testing.T) {
+	config := NewConfig()
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, KafkaVersion{}, config.Version, "Validate should not write into Version")
+	assert.Equal(t, MaxVersion, config.version(), "an unset Version should not cap negotiation")
+
+	config = NewConfig()
+	config.ApiVersionsRequest = false
+	var target ConfigurationError
+	assert.ErrorAs(t, config.Validate(), &target)
+	assert.Equal(t, "Version must be set when ApiVersionsRequest is disabled", string(target))
+
+	config.Version = V2_1_0_0
+	assert.NoError(t, config.Validate())
+	assert.Equal(t, V2_1_0_0, config.Version)
+	assert.Equal(t, V2_1_0_0, config.version())
+}
+
 // TestInvalidClientIDValidated ensures that the ClientID field is checked
 // when Version is set to anything less than 1_0_0_0, but otherwise accepted
 func TestInvalidClientIDValidated(t *testing.T) {
@@ -88,6 +107,48 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"Net.ReadTimeout must be > 0",
 		},
+		{
+			"RequestTimeouts unknown API",
+			func(cfg *Config) {
+				cfg.Net.RequestTimeouts = map[string]time.Duration{"FetchRequest": time.Second}
+			},
+			"Net.RequestTimeouts must map Kafka API names to timeouts > 0",
+		},
+		{
+			"RequestTimeouts zero",
+			func(cfg *Config) {
+				cfg.Net.RequestTimeouts = map[string]time.Duration{"Fetch": 0}
+			},
+			"Net.RequestTimeouts must map Kafka API names to timeouts > 0",
+		},
+		{
+			"MaxIdleTime",
+			func(cfg *Config) {
+				cfg.Net.MaxIdleTime = -1
+			},
+			"Net.MaxIdleTime must be >= 0",
+		},
+		{
+			"ReconnectBackoff",
+			func(cfg *Config) {
+				cfg.Net.ReconnectBackoff = -1
+			},
+			"Net.ReconnectBackoff must be >= 0",
+		},
+		{
+			"ReconnectBackoffMax",
+			func(cfg *Config) {
+				cfg.Net.ReconnectBackoffMax = cfg.Net.ReconnectBackoff - 1
+			},
+			"Net.ReconnectBackoffMax must be >= Net.ReconnectBackoff",
+		},
+		{
+			"DedicatedConnections",
+			func(cfg *Config) {
+				cfg.Net.DedicatedConnections = map[RequestClass]int{RequestClassDefault: 1}
+			},
+			"Net.DedicatedConnections must map produce, fetch or group request classes to a number of connections >= 0",
+		},
 		{
 			"WriteTimeout",
 			func(cfg *Config) {
@@ -119,7 +180,7 @@ func TestNetConfigValidates(t *testing.T) {
 				cfg.Net.SASL.Mechanism = "AnIncorrectSASLMechanism"
 				cfg.Net.SASL.TokenProvider = &DummyTokenProvider{}
 			},
-			"The SASL mechanism configuration is invalid. Possible values are `OAUTHBEARER`, `PLAIN`, `SCRAM-SHA-256`, `SCRAM-SHA-512` and `GSSAPI`",
+			"The SASL mechanism configuration is invalid. Possible values are `OAUTHBEARER`, `PLAIN`, `SCRAM-SHA-256`, `SCRAM-SHA-512`, `GSSAPI` and `AWS_MSK_IAM`",
 		},
 		{
 			"SASL.Mechanism.OAUTHBEARER - Missing token provider",
@@ -130,6 +191,14 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"An AccessTokenProvider instance must be provided to Net.SASL.TokenProvider",
 		},
+		{
+			"SASL.Mechanism.AWS_MSK_IAM - Missing credentials provider",
+			func(cfg *Config) {
+				cfg.Net.SASL.Enable = true
+				cfg.Net.SASL.Mechanism = SASLTypeAWSMSKIAM
+			},
+			"An AWSCredentialsProvider instance must be provided to Net.SASL.AWSMSKIAM.CredentialsProvider",
+		},
 		{
 			"SASL.Mechanism SCRAM-SHA-256 - Missing SCRAM client",
 			func(cfg *Config) {
@@ -246,7 +315,7 @@ func TestNetConfigValidates(t *testing.T) {
 			"Net.SASL.GSSAPI.Realm must not be empty when GSS-API mechanism is used",
 		},
 		{
-			"SASL.Mechanism GSSAPI (Kerberos) - Using Credentials Cache, Missing CCachePath field",
+			"SASL.Mechanism GSSAPI (Kerberos) - Using Credentials Cache from KRB5CCNAME, Missing Username",
 			func(cfg *Config) {
 				cfg.Net.SASL.Enable = true
 				cfg.Net.SASL.GSSAPI.ServiceName = "kafka"
@@ -254,8 +323,7 @@ func TestNetConfigValidates(t *testing.T) {
 				cfg.Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH
 				cfg.Net.SASL.GSSAPI.KerberosConfigPath = "/etc/krb5.conf"
 			},
-			"Net.SASL.GSSAPI.CCachePath must not be empty when GSS-API mechanism is used" +
-				" and Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH",
+			"Net.SASL.GSSAPI.Username must not be empty when GSS-API mechanism is used",
 		},
 	}
 
@@ -454,6 +522,17 @@ func TestProducerConfigValidates(t *testing.T) {
 			},
 			"Idempotent producer requires Net.MaxOpenRequests to be 1",
 		},
+		{
+			"Idempotent with Net.DedicatedConnections",
+			func(cfg *Config) {
+				cfg.Version = V0_11_0_0
+				cfg.Producer.Idempotent = true
+				cfg.Producer.RequiredAcks = WaitForAll
+				cfg.Net.MaxOpenRequests = 1
+				cfg.Net.DedicatedConnections = map[RequestClass]int{RequestClassProduce: 2}
+			},
+			"Idempotent producer requires at most 1 dedicated produce connection",
+		},
 	}
 
 	for i, test := range tests {
@@ -489,6 +568,34 @@ func TestConsumerConfigValidates(t *testing.T) {
 			},
 			"Consumer.IsolationLevel must be ReadUncommitted or ReadCommitted",
 		},
+		{
+			"ReadReplica.Policy",
+			func(cfg *Config) {
+				cfg.Consumer.ReadReplica.Policy = ReadReplicaPolicy(42)
+			},
+			"Consumer.ReadReplica.Policy is invalid",
+		},
+		{
+			"ReadReplica.Policy without RackID",
+			func(cfg *Config) {
+				cfg.Consumer.ReadReplica.Policy = ReadReplicaSameRack
+			},
+			"Consumer.ReadReplica.Policy ReadReplicaSameRack requires RackID",
+		},
+		{
+			"ReadReplica.MaxErrors",
+			func(cfg *Config) {
+				cfg.Consumer.ReadReplica.MaxErrors = 0
+			},
+			"Consumer.ReadReplica.MaxErrors must be > 0",
+		},
+		{
+			"ReadReplica.RecheckInterval",
+			func(cfg *Config) {
+				cfg.Consumer.ReadReplica.RecheckInterval = -1
+			},
+			"Consumer.ReadReplica.RecheckInterval must be >= 0",
+		},
 	}
 
 	for i, test := range tests {
@@ -530,6 +637,20 @@ func TestZstdConfigValidation(t *testing.T) {
 	}
 }
 
+func TestTelemetryConfigValidation(t *testing.T) {
+	config := NewTestConfig()
+	config.Telemetry.Enabled = true
+	err := config.Validate()
+	var target ConfigurationError
+	if !errors.As(err, &target) || string(target) != "Telemetry requires Version >= V3_7_0_0" {
+		t.Error("Expected invalid telemetry/kafka version error, got ", err)
+	}
+	config.Version = V3_7_0_0
+	if err := config.Validate(); err != nil {
+		t.Error("Expected telemetry to work, got ", err)
+	}
+}
+
 func TestValidGroupInstanceId(t *testing.T) {
 	tests := []struct {
 		grouptInstanceId string
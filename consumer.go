@@ -105,6 +105,7 @@ type consumer struct {
 	brokerConsumers map[*Broker]*brokerConsumer
 	client          Client
 	metricRegistry  metrics.Registry
+	rateLimits      *consumerRateLimits
 	lock            sync.Mutex
 }
 
@@ -138,6 +139,7 @@ func newConsumer(client Client) (Consumer, error) {
 		children:        make(map[string]map[int32]*partitionConsumer),
 		brokerConsumers: make(map[*Broker]*brokerConsumer),
 		metricRegistry:  newCleanupRegistry(client.Config().MetricRegistry),
+		rateLimits:      newConsumerRateLimits(client.Config()),
 	}
 
 	return c, nil
@@ -385,6 +387,14 @@ type PartitionConsumer interface {
 	// You can use this to determine how far behind the processing is.
 	HighWaterMarkOffset() int64
 
+	// LastStableOffset returns the last stable offset of the partition as
+	// reported by the most recent fetch response, i.e. the offset below which
+	// all transactions have been either committed or aborted. When consuming
+	// with ReadCommitted this is the upper bound of what can be consumed.
+	// Requires Kafka 0.11+; before the first fetch, and on older versions, the
+	// high water mark offset is returned instead.
+	LastStableOffset() int64
+
 	// Pause suspends fetching from this partition. Future calls to the broker will not return
 	// any records from these partition until it have been resumed using Resume().
 	// Note that this method does not affect partition subscription.
@@ -427,6 +437,9 @@ func (s *brokerSubscription) release() {
 
 type partitionConsumer struct {
 	highWaterMarkOffset atomic.Int64 // must be at the top of the struct because https://golang.org/pkg/sync/atomic/#pkg-note-BUG
+	lastStableOffset    atomic.Int64
+	deliveredOffset     atomic.Int64 // next offset to be delivered on the messages channel
+	deliveredTimestamp  atomic.Int64 // timestamp of the last delivered message, in unix nanoseconds
 
 	consumer           *consumer
 	conf               *Config
@@ -439,6 +452,7 @@ type partitionConsumer struct {
 	leaderEpoch                int32
 	preferredReadReplica       int32
 	preferredReadReplicaExpiry time.Time
+	preferredReadReplicaErrors int
 
 	trigger, dying     chan none
 	dispatcherStop     chan none
@@ -539,6 +553,9 @@ func (child *partitionConsumer) dispatcher() {
 			child.consumer.unrefBrokerConsumer(child.broker)
 		}
 		child.consumer.removeChild(child)
+		if child.consumer.metricRegistry != nil {
+			child.consumer.metricRegistry.Unregister(getMetricNameForPartition("consumer-lso-lag", child.topic, child.partition))
+		}
 		close(child.feeder)
 	}()
 
@@ -631,6 +648,9 @@ func (child *partitionConsumer) preferredBroker() (*Broker, int32, error) {
 }
 
 func (child *partitionConsumer) preferredReadReplicaLease() time.Duration {
+	if child.conf.Consumer.ReadReplica.RecheckInterval > 0 {
+		return child.conf.Consumer.ReadReplica.RecheckInterval
+	}
 	if child.conf.Metadata.RefreshFrequency > 0 {
 		return child.conf.Metadata.RefreshFrequency
 	}
@@ -666,6 +686,7 @@ func (child *partitionConsumer) chooseStartingOffset(offset int64) error {
 	}
 
 	child.highWaterMarkOffset.Store(newestOffset)
+	child.lastStableOffset.Store(newestOffset)
 
 	oldestOffset, err := child.consumer.client.GetOffset(child.topic, child.partition, OffsetOldest)
 	if err != nil {
@@ -682,6 +703,7 @@ func (child *partitionConsumer) chooseStartingOffset(offset int64) error {
 	default:
 		return ErrOffsetOutOfRange
 	}
+	child.deliveredOffset.Store(child.offset)
 
 	return nil
 }
@@ -720,6 +742,10 @@ func (child *partitionConsumer) HighWaterMarkOffset() int64 {
 	return child.highWaterMarkOffset.Load()
 }
 
+func (child *partitionConsumer) LastStableOffset() int64 {
+	return child.lastStableOffset.Load()
+}
+
 func (child *partitionConsumer) responseFeeder() {
 	var msgs []*ConsumerMessage
 	expiryTicker := time.NewTicker(child.conf.Consumer.MaxProcessingTime)
@@ -744,6 +770,7 @@ feederLoop:
 				broker.acks.Done()
 				continue feederLoop
 			case child.messages <- msg:
+				child.markDelivered(msg)
 				firstAttempt = true
 			case <-expiryTicker.C:
 				if !firstAttempt {
@@ -754,6 +781,7 @@ feederLoop:
 						child.interceptors(msg)
 						select {
 						case child.messages <- msg:
+							child.markDelivered(msg)
 						case <-child.dying:
 							break remainingLoop
 						}
@@ -782,6 +810,29 @@ feederLoop:
 	close(child.errors)
 }
 
+// markDelivered records msg as the latest message handed to the user, for lag
+// reporting.
+func (child *partitionConsumer) markDelivered(msg *ConsumerMessage) {
+	child.deliveredOffset.Store(msg.Offset + 1)
+	if !msg.Timestamp.IsZero() {
+		child.deliveredTimestamp.Store(msg.Timestamp.UnixNano())
+	}
+}
+
+// lag returns how far the messages delivered to the user trail the end of
+// the partition, in offsets and in time.
+func (child *partitionConsumer) lag() (int64, time.Duration) {
+	offsets := max(child.highWaterMarkOffset.Load()-child.deliveredOffset.Load(), 0)
+	if offsets == 0 {
+		return 0, 0
+	}
+	ts := child.deliveredTimestamp.Load()
+	if ts == 0 {
+		return offsets, 0
+	}
+	return offsets, max(time.Since(time.Unix(0, ts)), 0)
+}
+
 func (child *partitionConsumer) parseMessages(msgSet *MessageSet) ([]*ConsumerMessage, error) {
 	var messages []*ConsumerMessage
 	for _, msgBlock := range msgSet.Messages {
@@ -846,9 +897,13 @@ func (child *partitionConsumer) parseRecords(batch *RecordBatch) ([]*ConsumerMes
 }
 
 func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*ConsumerMessage, error) {
-	var consumerBatchSizeMetric metrics.Histogram
+	var (
+		consumerBatchSizeMetric metrics.Histogram
+		metricRegistry          metrics.Registry
+	)
 	if child.consumer != nil && child.consumer.metricRegistry != nil {
-		consumerBatchSizeMetric = getOrRegisterHistogram("consumer-batch-size", child.consumer.metricRegistry)
+		metricRegistry = child.consumer.metricRegistry
+		consumerBatchSizeMetric = getOrRegisterHistogram("consumer-batch-size", metricRegistry)
 	}
 
 	// If request was throttled and empty we log and return without error
@@ -877,7 +932,18 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 		consumerBatchSizeMetric.Update(int64(nRecs))
 	}
 
-	if block.PreferredReadReplica != invalidPreferredReplicaID {
+	if response.Version >= 4 {
+		child.lastStableOffset.Store(block.LastStableOffset)
+		if metricRegistry != nil {
+			metrics.GetOrRegisterGauge(getMetricNameForPartition("consumer-lso-lag", child.topic, child.partition), metricRegistry).
+				Update(block.HighWaterMarkOffset - block.LastStableOffset)
+		}
+	}
+
+	if block.PreferredReadReplica != invalidPreferredReplicaID && child.acceptReadReplica(block.PreferredReadReplica) {
+		if block.PreferredReadReplica != child.preferredReadReplica {
+			child.preferredReadReplicaErrors = 0
+		}
 		child.preferredReadReplica = block.PreferredReadReplica
 		child.preferredReadReplicaExpiry = time.Now().Add(child.preferredReadReplicaLease())
 	}
@@ -979,6 +1045,10 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 				if controlRecord.Type == ControlRecordAbort {
 					delete(abortedProducerIDs, records.RecordBatch.ProducerID)
 				}
+				// only count control records not already seen in a previous fetch
+				if metricRegistry != nil && len(recordBatchMessages) > 0 {
+					child.incTopicCounter("consumer-control-records", 1, metricRegistry)
+				}
 				continue
 			}
 
@@ -986,6 +1056,9 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 			if child.conf.Consumer.IsolationLevel == ReadCommitted {
 				_, isAborted := abortedProducerIDs[records.RecordBatch.ProducerID]
 				if records.RecordBatch.IsTransactional && isAborted {
+					if metricRegistry != nil {
+						child.incTopicCounter("consumer-aborted-records-skipped", int64(len(recordBatchMessages)), metricRegistry)
+					}
 					continue
 				}
 			}
@@ -999,6 +1072,13 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 	return messages, nil
 }
 
+// incTopicCounter increments both the all-topics counter and the counter for
+// the child's topic.
+func (child *partitionConsumer) incTopicCounter(name string, n int64, r metrics.Registry) {
+	metrics.GetOrRegisterCounter(name, r).Inc(n)
+	metrics.GetOrRegisterCounter(getMetricNameForTopic(name, child.topic), r).Inc(n)
+}
+
 func (child *partitionConsumer) interceptors(msg *ConsumerMessage) {
 	for _, interceptor := range child.conf.Consumer.Interceptors {
 		msg.safelyApplyInterceptor(interceptor)
@@ -1030,6 +1110,7 @@ type brokerConsumer struct {
 	refs             int
 	stop             chan none
 	stopOnce         sync.Once
+	throttle         *fetchThrottleTracker
 }
 
 func (c *consumer) newBrokerConsumer(broker *Broker) *brokerConsumer {
@@ -1042,6 +1123,9 @@ func (c *consumer) newBrokerConsumer(broker *Broker) *brokerConsumer {
 		refs:             0,
 		stop:             make(chan none),
 	}
+	if c.rateLimits != nil {
+		bc.throttle = newFetchThrottleTracker(c.metricRegistry)
+	}
 
 	go withRecover(bc.subscriptionManager)
 	go withRecover(bc.subscriptionConsumer)
@@ -1192,6 +1276,9 @@ func (bc *brokerConsumer) handleResponses() {
 		child.responseResult = nil
 
 		if result == nil {
+			if bc.broker.ID() == child.preferredReadReplica {
+				child.preferredReadReplicaErrors = 0
+			}
 			if preferredBroker, _, err := child.preferredBroker(); err == nil {
 				if bc.broker.ID() != preferredBroker.ID() {
 					// not an error but needs redispatching to consume from preferred replica
@@ -1205,9 +1292,7 @@ func (bc *brokerConsumer) handleResponses() {
 			continue
 		}
 
-		// Discard any replica preference.
-		child.preferredReadReplica = invalidPreferredReplicaID
-		child.preferredReadReplicaExpiry = time.Time{}
+		child.readReplicaFailed(bc.broker.ID())
 
 		if errors.Is(result, errTimedOut) {
 			Logger.Printf("consumer/broker/%d abandoned subscription to %s/%d because consuming was taking too long\n",
@@ -1257,6 +1342,7 @@ func (bc *brokerConsumer) abort(err error) {
 		case <-child.dying:
 			child.stopDispatcher()
 		default:
+			child.readReplicaFailed(bc.broker.ID())
 			child.notifyError(err)
 		}
 	}
@@ -1276,7 +1362,7 @@ func (bc *brokerConsumer) abort(err error) {
 }
 
 // fetchNewMessages can be nil if no fetch is made, it can occur when
-// all partitions are paused
+// all partitions are paused or throttled by Consumer.Fetch rate limits
 func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 	request := &FetchRequest{
 		MinBytes:    bc.consumer.conf.Consumer.Fetch.Min,
@@ -1285,38 +1371,38 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 	// pick the highest Fetch version supported by the negotiated Kafka version
 	switch {
 	// Version 12 adds flexible version support and last fetched epoch.
-	case bc.consumer.conf.Version.IsAtLeast(V2_7_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V2_7_0_0):
 		request.Version = 12
 	// Version 11 adds RackID for KIP-392 fetch from closest replica.
-	case bc.consumer.conf.Version.IsAtLeast(V2_3_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V2_3_0_0):
 		request.Version = 11
 	// Version 9 adds CurrentLeaderEpoch (KIP-320); version 10 allows the ZStd
 	// compression algorithm (KIP-110).
-	case bc.consumer.conf.Version.IsAtLeast(V2_1_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V2_1_0_0):
 		request.Version = 10
 	// Version 8 is the same as version 7.
-	case bc.consumer.conf.Version.IsAtLeast(V2_0_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V2_0_0_0):
 		request.Version = 8
 	// Version 7 adds incremental fetch request support.
-	case bc.consumer.conf.Version.IsAtLeast(V1_1_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V1_1_0_0):
 		request.Version = 7
 	// Version 6 is the same as version 5.
-	case bc.consumer.conf.Version.IsAtLeast(V1_0_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V1_0_0_0):
 		request.Version = 6
 	// Version 4 adds IsolationLevel and requires Kafka log message format
 	// version 2; version 5 adds LogStartOffset.
-	case bc.consumer.conf.Version.IsAtLeast(V0_11_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V0_11_0_0):
 		request.Version = 5
 	// Version 3 adds MaxBytes and makes partition ordering significant:
 	// partitions are processed in the order they appear in the request.
-	case bc.consumer.conf.Version.IsAtLeast(V0_10_1_0):
+	case bc.consumer.conf.version().IsAtLeast(V0_10_1_0):
 		request.Version = 3
 	// Starting in version 2, the requester must be able to handle Kafka log
 	// message format version 1.
-	case bc.consumer.conf.Version.IsAtLeast(V0_10_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V0_10_0_0):
 		request.Version = 2
 	// Version 1 is the same as version 0.
-	case bc.consumer.conf.Version.IsAtLeast(V0_9_0_0):
+	case bc.consumer.conf.version().IsAtLeast(V0_9_0_0):
 		request.Version = 1
 	}
 
@@ -1337,6 +1423,17 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 		request.RackID = bc.consumer.conf.RackID
 	}
 
+	// defer the fetch entirely while the consumer is over its global rate limit
+	limits := bc.consumer.rateLimits
+	now := time.Now()
+	if limits != nil {
+		throttled := limits.globalWait(now) > 0
+		bc.throttle.update("", throttled, now)
+		if throttled {
+			return nil, nil
+		}
+	}
+
 	for child := range bc.subscriptions {
 		select {
 		case <-child.dying:
@@ -1346,9 +1443,17 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 		default:
 		}
 
-		if !child.IsPaused() {
-			request.AddBlock(child.topic, child.partition, child.offset, child.fetchSize, child.leaderEpoch)
+		if child.IsPaused() {
+			continue
 		}
+		if limits != nil {
+			throttled := limits.topicWait(child.topic, now) > 0
+			bc.throttle.update(child.topic, throttled, now)
+			if throttled {
+				continue
+			}
+		}
+		request.AddBlock(child.topic, child.partition, child.offset, child.fetchSize, child.leaderEpoch)
 	}
 
 	// avoid to fetch when there is no block
@@ -1356,7 +1461,11 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 		return nil, nil
 	}
 
-	return bc.broker.Fetch(request)
+	response, err := bc.broker.Fetch(request)
+	if err == nil && response != nil {
+		limits.record(response, time.Now())
+	}
+	return response, err
 }
 
 func (bc *brokerConsumer) stopConsuming() {
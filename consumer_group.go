@@ -144,7 +144,7 @@ func NewConsumerGroupFromClient(groupID string, client Client) (ConsumerGroup, e
 
 func newConsumerGroup(groupID string, client Client) (ConsumerGroup, error) {
 	config := client.Config()
-	if !config.Version.IsAtLeast(V0_10_2_0) {
+	if !config.version().IsAtLeast(V0_10_2_0) {
 		return nil, ConfigurationError("consumer groups require Version to be >= V0_10_2_0")
 	}
 
@@ -169,7 +169,7 @@ func newConsumerGroup(groupID string, client Client) (ConsumerGroup, error) {
 		protocol:       protocol,
 		metricRegistry: newCleanupRegistry(config.MetricRegistry),
 	}
-	if config.Consumer.Group.InstanceId != "" && config.Version.IsAtLeast(V2_3_0_0) {
+	if config.Consumer.Group.InstanceId != "" && config.version().IsAtLeast(V2_3_0_0) {
 		cg.groupInstanceId = &config.Consumer.Group.InstanceId
 	}
 	return cg, nil
@@ -540,21 +540,21 @@ func (c *consumerGroup) joinGroupRequest(coordinator *Broker, topics []string, h
 	// send two JoinGroupRequests, once with the empty member id, and then again
 	// with the assigned id from the first response. This is handled via the
 	// ErrMemberIdRequired case.
-	if c.config.Version.IsAtLeast(V3_1_0_0) {
+	if c.config.version().IsAtLeast(V3_1_0_0) {
 		req.Version = 8
-	} else if c.config.Version.IsAtLeast(V2_5_0_0) {
+	} else if c.config.version().IsAtLeast(V2_5_0_0) {
 		req.Version = 7
-	} else if c.config.Version.IsAtLeast(V2_4_0_0) {
+	} else if c.config.version().IsAtLeast(V2_4_0_0) {
 		req.Version = 6
-	} else if c.config.Version.IsAtLeast(V2_3_0_0) {
+	} else if c.config.version().IsAtLeast(V2_3_0_0) {
 		req.Version = 5
-	} else if c.config.Version.IsAtLeast(V2_2_0_0) {
+	} else if c.config.version().IsAtLeast(V2_2_0_0) {
 		req.Version = 4
-	} else if c.config.Version.IsAtLeast(V2_0_0_0) {
+	} else if c.config.version().IsAtLeast(V2_0_0_0) {
 		req.Version = 3
-	} else if c.config.Version.IsAtLeast(V0_11_0_0) {
+	} else if c.config.version().IsAtLeast(V0_11_0_0) {
 		req.Version = 2
-	} else if c.config.Version.IsAtLeast(V0_10_1_0) {
+	} else if c.config.version().IsAtLeast(V0_10_1_0) {
 		req.Version = 1
 	}
 	if req.Version >= 1 {
@@ -590,9 +590,9 @@ func (c *consumerGroup) joinGroupRequest(coordinator *Broker, topics []string, h
 // protocol so owned partitions are available during a rolling upgrade
 func (c *consumerGroup) subscriptionVersion() int16 {
 	switch {
-	case c.config.Version.IsAtLeast(V3_2_0_0):
+	case c.config.version().IsAtLeast(V3_2_0_0):
 		return 2 // KIP-792: GenerationID
-	case c.config.Version.IsAtLeast(V2_4_0_0):
+	case c.config.version().IsAtLeast(V2_4_0_0):
 		return 1 // KIP-429: OwnedPartitions
 	default:
 		return 0
@@ -656,15 +656,15 @@ func (c *consumerGroup) syncGroupRequest(
 		GenerationId: generationID,
 	}
 
-	if c.config.Version.IsAtLeast(V2_5_0_0) {
+	if c.config.version().IsAtLeast(V2_5_0_0) {
 		req.Version = 5
-	} else if c.config.Version.IsAtLeast(V2_4_0_0) {
+	} else if c.config.version().IsAtLeast(V2_4_0_0) {
 		req.Version = 4
-	} else if c.config.Version.IsAtLeast(V2_3_0_0) {
+	} else if c.config.version().IsAtLeast(V2_3_0_0) {
 		req.Version = 3
-	} else if c.config.Version.IsAtLeast(V2_0_0_0) {
+	} else if c.config.version().IsAtLeast(V2_0_0_0) {
 		req.Version = 2
-	} else if c.config.Version.IsAtLeast(V0_11_0_0) {
+	} else if c.config.version().IsAtLeast(V0_11_0_0) {
 		req.Version = 1
 	}
 	// Starting from version 3, we add a new field called groupInstanceId to indicate member identity across restarts.
@@ -708,18 +708,18 @@ func (c *consumerGroup) heartbeatRequest(coordinator *Broker, memberID string, g
 	}
 
 	// Version 1 and version 2 are the same as version 0.
-	if c.config.Version.IsAtLeast(V0_11_0_0) {
+	if c.config.version().IsAtLeast(V0_11_0_0) {
 		req.Version = 1
 	}
-	if c.config.Version.IsAtLeast(V2_0_0_0) {
+	if c.config.version().IsAtLeast(V2_0_0_0) {
 		req.Version = 2
 	}
 	// Starting from version 3, we add a new field called groupInstanceId to indicate member identity across restarts.
-	if c.config.Version.IsAtLeast(V2_3_0_0) {
+	if c.config.version().IsAtLeast(V2_3_0_0) {
 		req.Version = 3
 		req.GroupInstanceId = c.groupInstanceId
 		// Version 4 is the first flexible version
-		if c.config.Version.IsAtLeast(V2_4_0_0) {
+		if c.config.version().IsAtLeast(V2_4_0_0) {
 			req.Version = 4
 		}
 	}
@@ -781,13 +781,13 @@ func (c *consumerGroup) leave() error {
 		GroupId:  c.groupID,
 		MemberId: c.memberID,
 	}
-	if c.config.Version.IsAtLeast(V3_2_0_0) {
+	if c.config.version().IsAtLeast(V3_2_0_0) {
 		req.Version = 5
-	} else if c.config.Version.IsAtLeast(V2_4_0_0) {
+	} else if c.config.version().IsAtLeast(V2_4_0_0) {
 		req.Version = 4
-	} else if c.config.Version.IsAtLeast(V2_0_0_0) {
+	} else if c.config.version().IsAtLeast(V2_0_0_0) {
 		req.Version = 2
-	} else if c.config.Version.IsAtLeast(V0_11_0_0) {
+	} else if c.config.version().IsAtLeast(V0_11_0_0) {
 		req.Version = 1
 	}
 	if req.Version >= 3 {
@@ -950,6 +950,23 @@ type ConsumerGroupSession interface {
 
 	// Context returns the session context.
 	Context() context.Context
+
+	// Lag returns how far consumption of a claimed partition trails the end
+	// of the partition. The second return value is false if the partition is
+	// not currently being consumed by this session.
+	Lag(topic string, partition int32) (ClaimLag, bool)
+}
+
+// ClaimLag describes how far the messages delivered for a claim trail the
+// end of its partition.
+type ClaimLag struct {
+	// Offsets is the high water mark offset minus the next offset to be
+	// delivered on the claim's Messages() channel.
+	Offsets int64
+	// Time is the age of the last message delivered on the claim's Messages()
+	// channel, based on its record timestamp. It is zero when the claim has
+	// caught up or the record timestamp is unknown.
+	Time time.Duration
 }
 
 type consumerGroupSession struct {
@@ -966,6 +983,9 @@ type consumerGroupSession struct {
 	waitGroup       sync.WaitGroup
 	releaseOnce     sync.Once
 	hbDying, hbDead chan none
+
+	activeLock   sync.RWMutex
+	activeClaims map[string]map[int32]*consumerGroupClaim
 }
 
 func newConsumerGroupSession(ctx context.Context, parent *consumerGroup, claims map[string][]int32, memberID string, generationID int32, handler ConsumerGroupHandler) (*consumerGroupSession, error) {
@@ -990,6 +1010,7 @@ func newConsumerGroupSession(ctx context.Context, parent *consumerGroup, claims
 		cancel:       cancel,
 		hbDying:      make(chan none),
 		hbDead:       make(chan none),
+		activeClaims: make(map[string]map[int32]*consumerGroupClaim),
 	}
 
 	// start heartbeat loop
@@ -1081,6 +1102,50 @@ func (s *consumerGroupSession) Context() context.Context {
 	return s.ctx
 }
 
+func (s *consumerGroupSession) Lag(topic string, partition int32) (ClaimLag, bool) {
+	s.activeLock.RLock()
+	claim := s.activeClaims[topic][partition]
+	s.activeLock.RUnlock()
+	if claim == nil {
+		return ClaimLag{}, false
+	}
+	return claim.lag()
+}
+
+// trackClaim makes the lag of claim available through Lag and the
+// consumer-group-lag metrics until the returned func is called.
+func (s *consumerGroupSession) trackClaim(claim *consumerGroupClaim) func() {
+	topic, partition := claim.topic, claim.partition
+
+	s.activeLock.Lock()
+	if s.activeClaims[topic] == nil {
+		s.activeClaims[topic] = make(map[int32]*consumerGroupClaim)
+	}
+	s.activeClaims[topic][partition] = claim
+	s.activeLock.Unlock()
+
+	registry := s.parent.metricRegistry
+	offsetLagName := getMetricNameForPartition("consumer-group-lag-"+s.parent.groupID, topic, partition)
+	timeLagName := getMetricNameForPartition("consumer-group-time-lag-in-ms-"+s.parent.groupID, topic, partition)
+	_ = registry.Register(offsetLagName, metrics.NewFunctionalGauge(func() int64 {
+		lag, _ := claim.lag()
+		return lag.Offsets
+	}))
+	_ = registry.Register(timeLagName, metrics.NewFunctionalGauge(func() int64 {
+		lag, _ := claim.lag()
+		return lag.Time.Milliseconds()
+	}))
+
+	return func() {
+		registry.Unregister(offsetLagName)
+		registry.Unregister(timeLagName)
+
+		s.activeLock.Lock()
+		delete(s.activeClaims[topic], partition)
+		s.activeLock.Unlock()
+	}
+}
+
 // newClaimWithRetry calls newConsumerGroupClaim, retrying transient errors so
 // that brief leader/metadata desync around a rebalance doesn't leave a
 // partition permanently unclaimed for the lifetime of this session
@@ -1147,6 +1212,7 @@ func (s *consumerGroupSession) consume(topic string, partition int32) {
 		s.parent.handleError(err, topic, partition)
 		return
 	}
+	defer s.trackClaim(claim)()
 
 	// trigger close when session is done
 	go func() {
@@ -1383,6 +1449,15 @@ func (c *consumerGroupClaim) Topic() string        { return c.topic }
 func (c *consumerGroupClaim) Partition() int32     { return c.partition }
 func (c *consumerGroupClaim) InitialOffset() int64 { return c.offset }
 
+func (c *consumerGroupClaim) lag() (ClaimLag, bool) {
+	pc, ok := c.PartitionConsumer.(*partitionConsumer)
+	if !ok {
+		return ClaimLag{}, false
+	}
+	offsets, age := pc.lag()
+	return ClaimLag{Offsets: offsets, Time: age}, true
+}
+
 // Drains messages and errors, ensures the claim is fully closed.
 func (c *consumerGroupClaim) waitClosed() (errs ConsumerErrors) {
 	go func() {
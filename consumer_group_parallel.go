@@ -0,0 +1,151 @@
+package sarama
+
+import (
+	"sync"
+)
+
+// ConsumeClaimConcurrently processes the messages of a single claim using the
+// given number of worker goroutines. It is intended to be called from a
+// ConsumerGroupHandler's ConsumeClaim in place of a sequential consume loop,
+// so that a single slow record does not hold up the whole partition.
+//
+// Messages are routed to workers by key, using the same murmur2 hash as the
+// Java client's default partitioner, so records sharing a key are always
+// processed in order by the same worker. Messages without a key are spread
+// across workers by offset and carry no ordering guarantee.
+//
+// Offsets are only marked on the session once every preceding message of the
+// claim has been processed, i.e. the marked offset is always one past the
+// lowest contiguous completed offset. A message that is still in flight (or
+// that failed) therefore holds back the committed position of the partition,
+// and it will be redelivered after a rebalance or restart.
+//
+// ConsumeClaimConcurrently returns once the claim's Messages() channel is
+// closed or the session context is done, after all in-flight messages have
+// been handled. If process returns an error, no further messages are
+// dispatched and the first error is returned once the workers have exited.
+func ConsumeClaimConcurrently(sess ConsumerGroupSession, claim ConsumerGroupClaim, workers int, process func(*ConsumerMessage) error) error {
+	if workers < 1 {
+		return ConfigurationError("ConsumeClaimConcurrently requires at least one worker")
+	}
+	if process == nil {
+		return ConfigurationError("ConsumeClaimConcurrently requires a process function")
+	}
+
+	topic, partition := claim.Topic(), claim.Partition()
+	tracker := &claimOffsetTracker{done: make(map[int64]struct{})}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	failed := make(chan none)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(failed)
+		})
+	}
+
+	queues := make([]chan *ConsumerMessage, workers)
+	for i := range queues {
+		queues[i] = make(chan *ConsumerMessage, 1)
+		wg.Add(1)
+		go func(queue <-chan *ConsumerMessage) {
+			defer wg.Done()
+			for msg := range queue {
+				select {
+				case <-failed:
+					// drain without processing, the claim is being abandoned
+					continue
+				default:
+				}
+				if err := process(msg); err != nil {
+					fail(err)
+					continue
+				}
+				if next, ok := tracker.complete(msg.Offset); ok {
+					sess.MarkOffset(topic, partition, next, "")
+				}
+			}
+		}(queues[i])
+	}
+
+	ctx := sess.Context()
+dispatch:
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				break dispatch
+			}
+			tracker.add(msg.Offset)
+			select {
+			case queues[workerForMessage(msg, workers)] <- msg:
+			case <-failed:
+				break dispatch
+			case <-ctx.Done():
+				break dispatch
+			}
+		case <-failed:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// workerForMessage selects the worker index responsible for msg
+func workerForMessage(msg *ConsumerMessage, workers int) int {
+	if msg.Key == nil {
+		return int(msg.Offset % int64(workers))
+	}
+	return int((murmur2(msg.Key) & 0x7fffffff) % uint32(workers))
+}
+
+// claimOffsetTracker keeps track of the offsets dispatched for a claim and
+// reports the next offset to mark once a contiguous prefix has completed.
+type claimOffsetTracker struct {
+	lock    sync.Mutex
+	pending []int64 // dispatched and not yet marked, in offset order
+	done    map[int64]struct{}
+}
+
+func (t *claimOffsetTracker) add(offset int64) {
+	t.lock.Lock()
+	t.pending = append(t.pending, offset)
+	t.lock.Unlock()
+}
+
+// complete records offset as processed and returns the offset to mark if the
+// lowest pending offset has advanced as a result.
+func (t *claimOffsetTracker) complete(offset int64) (int64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.done[offset] = struct{}{}
+
+	advanced := false
+	var last int64
+	for len(t.pending) > 0 {
+		head := t.pending[0]
+		if _, ok := t.done[head]; !ok {
+			break
+		}
+		delete(t.done, head)
+		t.pending = t.pending[1:]
+		last, advanced = head, true
+	}
+	if !advanced {
+		return 0, false
+	}
+	return last + 1, true
+}
@@ -0,0 +1,155 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type parallelTestSession struct {
+	ctx context.Context
+
+	lock   sync.Mutex
+	marked int64
+}
+
+func (s *parallelTestSession) Claims() map[string][]int32 { return nil }
+func (s *parallelTestSession) MemberID() string           { return "member" }
+func (s *parallelTestSession) GenerationID() int32        { return 1 }
+func (s *parallelTestSession) Commit()                    {}
+func (s *parallelTestSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+func (s *parallelTestSession) MarkMessage(msg *ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func (s *parallelTestSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if offset > s.marked {
+		s.marked = offset
+	}
+}
+func (s *parallelTestSession) Context() context.Context { return s.ctx }
+func (s *parallelTestSession) Lag(topic string, partition int32) (ClaimLag, bool) {
+	return ClaimLag{}, false
+}
+
+func (s *parallelTestSession) markedOffset() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.marked
+}
+
+type parallelTestClaim struct {
+	messages chan *ConsumerMessage
+}
+
+func (c *parallelTestClaim) Topic() string                     { return "my-topic" }
+func (c *parallelTestClaim) Partition() int32                  { return 0 }
+func (c *parallelTestClaim) InitialOffset() int64              { return 0 }
+func (c *parallelTestClaim) HighWaterMarkOffset() int64        { return 0 }
+func (c *parallelTestClaim) Messages() <-chan *ConsumerMessage { return c.messages }
+
+func newParallelTestClaim(keys ...string) *parallelTestClaim {
+	claim := &parallelTestClaim{messages: make(chan *ConsumerMessage, len(keys))}
+	for i, key := range keys {
+		msg := &ConsumerMessage{Topic: "my-topic", Offset: int64(i)}
+		if key != "" {
+			msg.Key = []byte(key)
+		}
+		claim.messages <- msg
+	}
+	close(claim.messages)
+	return claim
+}
+
+func TestConsumeClaimConcurrentlyPreservesKeyOrder(t *testing.T) {
+	var keys []string
+	for i := range 100 {
+		keys = append(keys, "key-"+strconv.Itoa(i%7))
+	}
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := newParallelTestClaim(keys...)
+
+	var lock sync.Mutex
+	seen := make(map[string][]int64)
+	err := ConsumeClaimConcurrently(sess, claim, 4, func(msg *ConsumerMessage) error {
+		lock.Lock()
+		defer lock.Unlock()
+		seen[string(msg.Key)] = append(seen[string(msg.Key)], msg.Offset)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	for key, offsets := range seen {
+		for i := 1; i < len(offsets); i++ {
+			assert.Less(t, offsets[i-1], offsets[i], "out of order processing for key %s", key)
+		}
+	}
+	assert.Equal(t, int64(100), sess.markedOffset())
+}
+
+func TestConsumeClaimConcurrentlyMarksLowestContiguousOffset(t *testing.T) {
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := newParallelTestClaim("", "", "", "") // unkeyed, spread by offset
+
+	release := make(chan struct{})
+	processed := make(chan int64, 4)
+	done := make(chan error)
+	go func() {
+		done <- ConsumeClaimConcurrently(sess, claim, 4, func(msg *ConsumerMessage) error {
+			if msg.Offset == 0 {
+				<-release
+			}
+			processed <- msg.Offset
+			return nil
+		})
+	}()
+
+	for range 3 {
+		select {
+		case offset := <-processed:
+			assert.NotEqual(t, int64(0), offset)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for unblocked messages")
+		}
+	}
+	assert.Equal(t, int64(0), sess.markedOffset(), "offset marked past an in-flight message")
+
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, int64(4), sess.markedOffset())
+}
+
+func TestConsumeClaimConcurrentlyStopsOnError(t *testing.T) {
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := newParallelTestClaim("", "", "", "")
+
+	errProcess := errors.New("process failed")
+	err := ConsumeClaimConcurrently(sess, claim, 1, func(msg *ConsumerMessage) error {
+		if msg.Offset == 2 {
+			return errProcess
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errProcess)
+	assert.Equal(t, int64(2), sess.markedOffset())
+}
+
+func TestConsumeClaimConcurrentlyInvalidArguments(t *testing.T) {
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := newParallelTestClaim()
+	process := func(*ConsumerMessage) error { return nil }
+
+	var cfgErr ConfigurationError
+	assert.ErrorAs(t, ConsumeClaimConcurrently(sess, claim, 0, process), &cfgErr)
+	assert.ErrorAs(t, ConsumeClaimConcurrently(sess, claim, 1, nil), &cfgErr)
+}
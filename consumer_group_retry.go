@@ -0,0 +1,270 @@
+package sarama
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers added by RetryHandler to messages republished to a retry or
+// dead-letter topic.
+const (
+	RetryHeaderOriginalTopic     = "sarama-retry-original-topic"
+	RetryHeaderOriginalPartition = "sarama-retry-original-partition"
+	RetryHeaderOriginalOffset    = "sarama-retry-original-offset"
+	RetryHeaderAttempt           = "sarama-retry-attempt"
+	RetryHeaderError             = "sarama-retry-error"
+)
+
+// RetryTopic is a delayed retry stage of a RetryPolicy.
+type RetryTopic struct {
+	// Topic receives messages whose processing failed in the previous stage.
+	Topic string
+	// Delay is the minimum time between a message being republished to Topic
+	// and it being processed again, measured from the message timestamp.
+	Delay time.Duration
+}
+
+// RetryPolicy describes where a RetryHandler republishes failed messages.
+type RetryPolicy struct {
+	// Retries are the delayed retry stages, in the order they are attempted.
+	Retries []RetryTopic
+	// DeadLetterTopic receives messages that failed in every retry stage. If
+	// empty, the processing error of the last stage is returned from
+	// ConsumeClaim instead and the message is not marked.
+	DeadLetterTopic string
+}
+
+// RetryHandler is a ConsumerGroupHandler that processes messages one at a
+// time, and on error republishes the failed message to the next retry topic
+// of its RetryPolicy, or finally to its dead-letter topic.
+//
+// The original offset is only marked once the republished message has been
+// acknowledged by the broker, so a message is never lost if the republish
+// fails: the error is returned from ConsumeClaim and the message is
+// redelivered in the next session.
+//
+// The consumer group must subscribe to the retry topics as well as the source
+// topics, see Topics.
+type RetryHandler struct {
+	policy   RetryPolicy
+	process  func(ConsumerGroupSession, *ConsumerMessage) error
+	producer AsyncProducer
+	stages   map[string]int
+	wg       sync.WaitGroup
+}
+
+// NewRetryHandler creates a RetryHandler that calls process for each message
+// and republishes failed messages using an AsyncProducer created from client.
+// The client must be configured with Producer.Return.Successes and
+// Producer.Return.Errors enabled. It is still necessary to call Close() on
+// the handler when shutting down, and on the underlying client afterwards.
+func NewRetryHandler(client Client, policy RetryPolicy, process func(ConsumerGroupSession, *ConsumerMessage) error) (*RetryHandler, error) {
+	if process == nil {
+		return nil, ConfigurationError("RetryHandler requires a process function")
+	}
+	stages := make(map[string]int, len(policy.Retries))
+	for i, retry := range policy.Retries {
+		if retry.Topic == "" {
+			return nil, ConfigurationError("RetryPolicy.Retries must not contain an empty topic")
+		}
+		if retry.Delay < 0 {
+			return nil, ConfigurationError("RetryPolicy.Retries must not contain a negative delay")
+		}
+		if _, ok := stages[retry.Topic]; ok || retry.Topic == policy.DeadLetterTopic {
+			return nil, ConfigurationError(fmt.Sprintf("RetryPolicy topic %q is used more than once", retry.Topic))
+		}
+		stages[retry.Topic] = i
+	}
+	if conf := client.Config(); !conf.Producer.Return.Errors {
+		return nil, ConfigurationError("Producer.Return.Errors must be true to be used in a RetryHandler")
+	} else if !conf.Producer.Return.Successes {
+		return nil, ConfigurationError("Producer.Return.Successes must be true to be used in a RetryHandler")
+	}
+
+	producer, err := NewAsyncProducerFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &RetryHandler{
+		policy:   policy,
+		process:  process,
+		producer: producer,
+		stages:   stages,
+	}
+	h.wg.Add(2)
+	go withRecover(h.handleSuccesses)
+	go withRecover(h.handleErrors)
+	return h, nil
+}
+
+// Topics returns the given source topics together with the retry topics of
+// the policy, for passing to ConsumerGroup.Consume.
+func (h *RetryHandler) Topics(topics ...string) []string {
+	all := make([]string, 0, len(topics)+len(h.policy.Retries))
+	all = append(all, topics...)
+	for _, retry := range h.policy.Retries {
+		all = append(all, retry.Topic)
+	}
+	return all
+}
+
+// Setup implements ConsumerGroupHandler.
+func (h *RetryHandler) Setup(ConsumerGroupSession) error { return nil }
+
+// Cleanup implements ConsumerGroupHandler.
+func (h *RetryHandler) Cleanup(ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements ConsumerGroupHandler.
+func (h *RetryHandler) ConsumeClaim(sess ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	stage, isRetry := h.stages[claim.Topic()]
+	if !isRetry {
+		stage = -1
+	}
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if isRetry && !h.waitForDelay(sess, msg, h.policy.Retries[stage].Delay) {
+				return nil
+			}
+			if err := h.handle(sess, msg, stage); err != nil {
+				if sess.Context().Err() != nil {
+					// the session ended while republishing, msg is left
+					// unmarked for the next owner of the partition
+					return nil
+				}
+				return err
+			}
+			sess.MarkMessage(msg, "")
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Close shuts down the handler's producer, waiting for any buffered
+// republishes to be flushed.
+func (h *RetryHandler) Close() error {
+	h.producer.AsyncClose()
+	h.wg.Wait()
+	return nil
+}
+
+// waitForDelay blocks until msg is due for processing, returning false if
+// the session ended first.
+func (h *RetryHandler) waitForDelay(sess ConsumerGroupSession, msg *ConsumerMessage, delay time.Duration) bool {
+	if msg.Timestamp.IsZero() {
+		return true
+	}
+	wait := time.Until(msg.Timestamp.Add(delay))
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-sess.Context().Done():
+		return false
+	}
+}
+
+// handle processes msg, republishing it to the stage after stage on error.
+// A nil return means msg can be marked.
+func (h *RetryHandler) handle(sess ConsumerGroupSession, msg *ConsumerMessage, stage int) error {
+	processErr := h.process(sess, msg)
+	if processErr == nil {
+		return nil
+	}
+
+	next := stage + 1
+	var topic string
+	switch {
+	case next < len(h.policy.Retries):
+		topic = h.policy.Retries[next].Topic
+	case h.policy.DeadLetterTopic != "":
+		topic = h.policy.DeadLetterTopic
+	default:
+		return processErr
+	}
+
+	if err := h.republish(sess, msg, topic, next+1, processErr); err != nil {
+		return fmt.Errorf("kafka: failed to republish message from %s/%d at offset %d to %s: %w",
+			msg.Topic, msg.Partition, msg.Offset, topic, err)
+	}
+	return nil
+}
+
+// republish produces a copy of msg to topic and waits for it to be acked,
+// unless the session ends first.
+func (h *RetryHandler) republish(sess ConsumerGroupSession, msg *ConsumerMessage, topic string, attempt int, processErr error) error {
+	origTopic := msg.Topic
+	origPartition := strconv.FormatInt(int64(msg.Partition), 10)
+	origOffset := strconv.FormatInt(msg.Offset, 10)
+
+	headers := make([]RecordHeader, 0, len(msg.Headers)+5)
+	for _, header := range msg.Headers {
+		switch string(header.Key) {
+		case RetryHeaderOriginalTopic:
+			origTopic = string(header.Value)
+		case RetryHeaderOriginalPartition:
+			origPartition = string(header.Value)
+		case RetryHeaderOriginalOffset:
+			origOffset = string(header.Value)
+		case RetryHeaderAttempt, RetryHeaderError:
+		default:
+			headers = append(headers, *header)
+		}
+	}
+	headers = append(headers,
+		RecordHeader{Key: []byte(RetryHeaderOriginalTopic), Value: []byte(origTopic)},
+		RecordHeader{Key: []byte(RetryHeaderOriginalPartition), Value: []byte(origPartition)},
+		RecordHeader{Key: []byte(RetryHeaderOriginalOffset), Value: []byte(origOffset)},
+		RecordHeader{Key: []byte(RetryHeaderAttempt), Value: []byte(strconv.Itoa(attempt))},
+		RecordHeader{Key: []byte(RetryHeaderError), Value: []byte(processErr.Error())},
+	)
+
+	pmsg := &ProducerMessage{
+		Topic:    topic,
+		Value:    ByteEncoder(msg.Value),
+		Headers:  headers,
+		Metadata: make(chan error, 1),
+	}
+	if msg.Key != nil {
+		pmsg.Key = ByteEncoder(msg.Key)
+	}
+
+	select {
+	case h.producer.Input() <- pmsg:
+	case <-sess.Context().Done():
+		return sess.Context().Err()
+	}
+	select {
+	case err := <-pmsg.Metadata.(chan error):
+		return err
+	case <-sess.Context().Done():
+		// the result is buffered in Metadata and dropped
+		return sess.Context().Err()
+	}
+}
+
+func (h *RetryHandler) handleSuccesses() {
+	defer h.wg.Done()
+	for msg := range h.producer.Successes() {
+		msg.Metadata.(chan error) <- nil
+	}
+}
+
+func (h *RetryHandler) handleErrors() {
+	defer h.wg.Done()
+	for err := range h.producer.Errors() {
+		err.Msg.Metadata.(chan error) <- err.Err
+	}
+}
@@ -0,0 +1,198 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func newRetryHandlerTestClient(t *testing.T, topics ...string) (*MockBroker, Client) {
+	t.Helper()
+	broker := NewMockBroker(t, 1)
+	metadata := NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for _, topic := range topics {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+	}
+	broker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": metadata,
+		"ProduceRequest":  NewMockProduceResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_0_0_0
+	config.Producer.Return.Successes = true
+	config.Producer.Flush.Messages = 1
+	client, err := NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	return broker, client
+}
+
+func producedHeaders(t *testing.T, broker *MockBroker, topic string) map[string]string {
+	t.Helper()
+	for _, rr := range broker.History() {
+		req, ok := rr.Request.(*ProduceRequest)
+		if !ok {
+			continue
+		}
+		for _, records := range req.records[topic] {
+			headers := make(map[string]string)
+			for _, header := range records.RecordBatch.Records[0].Headers {
+				headers[string(header.Key)] = string(header.Value)
+			}
+			return headers
+		}
+	}
+	t.Fatalf("no message produced to %s", topic)
+	return nil
+}
+
+func TestRetryHandlerRepublishesToRetryTopic(t *testing.T) {
+	broker, client := newRetryHandlerTestClient(t, "my-topic-retry", "my-topic-dlq")
+	defer broker.Close()
+	defer safeClose(t, client)
+
+	policy := RetryPolicy{
+		Retries:         []RetryTopic{{Topic: "my-topic-retry", Delay: time.Minute}},
+		DeadLetterTopic: "my-topic-dlq",
+	}
+	handler, err := NewRetryHandler(client, policy, func(_ ConsumerGroupSession, msg *ConsumerMessage) error {
+		if msg.Offset == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	defer safeClose(t, handler)
+
+	assert.Equal(t, []string{"my-topic", "my-topic-retry"}, handler.Topics("my-topic"))
+
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := newParallelTestClaim("a", "b", "c")
+	assert.NoError(t, handler.ConsumeClaim(sess, claim))
+	assert.Equal(t, int64(3), sess.markedOffset())
+
+	headers := producedHeaders(t, broker, "my-topic-retry")
+	assert.Equal(t, "my-topic", headers[RetryHeaderOriginalTopic])
+	assert.Equal(t, "0", headers[RetryHeaderOriginalPartition])
+	assert.Equal(t, "1", headers[RetryHeaderOriginalOffset])
+	assert.Equal(t, "1", headers[RetryHeaderAttempt])
+	assert.Equal(t, "boom", headers[RetryHeaderError])
+}
+
+func TestRetryHandlerRepublishesToDeadLetterTopic(t *testing.T) {
+	broker, client := newRetryHandlerTestClient(t, "my-topic-retry", "my-topic-dlq")
+	defer broker.Close()
+	defer safeClose(t, client)
+
+	policy := RetryPolicy{
+		Retries:         []RetryTopic{{Topic: "my-topic-retry"}},
+		DeadLetterTopic: "my-topic-dlq",
+	}
+	handler, err := NewRetryHandler(client, policy, func(ConsumerGroupSession, *ConsumerMessage) error {
+		return errors.New("still failing")
+	})
+	assert.NoError(t, err)
+	defer safeClose(t, handler)
+
+	sess := &parallelTestSession{ctx: t.Context()}
+	claim := &parallelTestClaim{messages: make(chan *ConsumerMessage, 1)}
+	claim.messages <- &ConsumerMessage{
+		Topic:  "my-topic-retry",
+		Offset: 7,
+		Value:  []byte("payload"),
+		Headers: []*RecordHeader{
+			{Key: []byte(RetryHeaderOriginalTopic), Value: []byte("my-topic")},
+			{Key: []byte(RetryHeaderOriginalOffset), Value: []byte("42")},
+			{Key: []byte(RetryHeaderAttempt), Value: []byte("1")},
+			{Key: []byte("trace-id"), Value: []byte("abc")},
+		},
+	}
+	close(claim.messages)
+
+	retryClaim := &retryTestClaim{parallelTestClaim: claim, topic: "my-topic-retry"}
+	assert.NoError(t, handler.ConsumeClaim(sess, retryClaim))
+	assert.Equal(t, int64(8), sess.markedOffset())
+
+	headers := producedHeaders(t, broker, "my-topic-dlq")
+	assert.Equal(t, "my-topic", headers[RetryHeaderOriginalTopic])
+	assert.Equal(t, "42", headers[RetryHeaderOriginalOffset])
+	assert.Equal(t, "2", headers[RetryHeaderAttempt])
+	assert.Equal(t, "abc", headers["trace-id"])
+}
+
+func TestRetryHandlerReturnsErrorWithoutDeadLetterTopic(t *testing.T) {
+	broker, client := newRetryHandlerTestClient(t)
+	defer broker.Close()
+	defer safeClose(t, client)
+
+	errProcess := errors.New("boom")
+	handler, err := NewRetryHandler(client, RetryPolicy{}, func(ConsumerGroupSession, *ConsumerMessage) error {
+		return errProcess
+	})
+	assert.NoError(t, err)
+	defer safeClose(t, handler)
+
+	sess := &parallelTestSession{ctx: t.Context()}
+	assert.ErrorIs(t, handler.ConsumeClaim(sess, newParallelTestClaim("a")), errProcess)
+	assert.Equal(t, int64(0), sess.markedOffset())
+}
+
+func TestRetryHandlerStopsRepublishingWhenSessionEnds(t *testing.T) {
+	broker, client := newRetryHandlerTestClient(t, "my-topic-retry")
+	defer broker.Close()
+	defer safeClose(t, client)
+	// the republished message is never acknowledged, until it times out
+	metadata := NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("my-topic-retry", 0, broker.BrokerID())
+	broker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"MetadataRequest": func(req *request) encoderWithHeader { return metadata.For(req.body) },
+		"ProduceRequest":  func(*request) encoderWithHeader { return nil },
+	})
+	client.Config().Net.ReadTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	handler, err := NewRetryHandler(client, RetryPolicy{Retries: []RetryTopic{{Topic: "my-topic-retry"}}},
+		func(ConsumerGroupSession, *ConsumerMessage) error {
+			cancel()
+			return errors.New("boom")
+		})
+	assert.NoError(t, err)
+	defer safeClose(t, handler)
+
+	sess := &parallelTestSession{ctx: ctx}
+	done := make(chan error, 1)
+	go func() { done <- handler.ConsumeClaim(sess, newParallelTestClaim("a")) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ConsumeClaim should return once the session ends")
+	}
+	assert.Equal(t, int64(0), sess.markedOffset(), "the message is not marked without an acknowledgement")
+}
+
+func TestNewRetryHandlerRequiresSuccesses(t *testing.T) {
+	broker, client := newRetryHandlerTestClient(t)
+	defer broker.Close()
+	defer safeClose(t, client)
+	client.Config().Producer.Return.Successes = false
+
+	_, err := NewRetryHandler(client, RetryPolicy{}, func(ConsumerGroupSession, *ConsumerMessage) error { return nil })
+	var cfgErr ConfigurationError
+	assert.ErrorAs(t, err, &cfgErr)
+	assert.Equal(t, "Producer.Return.Successes must be true to be used in a RetryHandler", string(cfgErr))
+}
+
+type retryTestClaim struct {
+	*parallelTestClaim
+	topic string
+}
+
+func (c *retryTestClaim) Topic() string { return c.topic }
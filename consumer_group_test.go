@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rcrowley/go-metrics"
 	assert "github.com/stretchr/testify/require"
 )
 
@@ -796,3 +797,101 @@ func TestConsumerGroupReason(t *testing.T) {
 		assert.Equal(t, "the consumer is being closed", *leaveCapture.reasons[0])
 	})
 }
+
+type lagHandler struct {
+	lagCh  chan ClaimLag
+	gauge  func() int64
+	gauges chan int64
+}
+
+func (h *lagHandler) Setup(_ ConsumerGroupSession) error   { return nil }
+func (h *lagHandler) Cleanup(_ ConsumerGroupSession) error { return nil }
+func (h *lagHandler) ConsumeClaim(sess ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	select {
+	case <-claim.Messages():
+	case <-sess.Context().Done():
+		return nil
+	}
+	lag, ok := sess.Lag(claim.Topic(), claim.Partition())
+	if !ok {
+		return errors.New("no lag reported for active claim")
+	}
+	if _, ok := sess.Lag(claim.Topic(), claim.Partition()+1); ok {
+		return errors.New("lag reported for unclaimed partition")
+	}
+	h.gauges <- h.gauge()
+	h.lagCh <- lag
+	<-sess.Context().Done()
+	return nil
+}
+
+func TestConsumerGroupSessionLag(t *testing.T) {
+	config := NewTestConfig()
+	config.ClientID = t.Name()
+	config.Version = V2_0_0_0
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	broker0 := NewMockBroker(t, 0)
+	defer broker0.Close()
+
+	timestamp := time.Now().Add(-time.Minute)
+	fetchResponse := &FetchResponse{Version: 8}
+	fetchResponse.AddRecordWithTimestamp("my-topic", 0, nil, StringEncoder("foo"), 0, timestamp)
+	fetchResponse.SetLastStableOffset("my-topic", 0, 10)
+	fetchResponse.Blocks["my-topic"][0].HighWaterMarkOffset = 10
+
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my-topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my-topic", 0, OffsetOldest, 0).
+			SetOffset("my-topic", 0, OffsetNewest, 10),
+		"FindCoordinatorRequest": NewMockFindCoordinatorResponse(t).
+			SetCoordinator(CoordinatorGroup, "my-group", broker0),
+		"HeartbeatRequest": NewMockHeartbeatResponse(t),
+		"JoinGroupRequest": NewMockJoinGroupResponse(t).SetGroupProtocol(RangeBalanceStrategyName),
+		"SyncGroupRequest": NewMockSyncGroupResponse(t).SetMemberAssignment(
+			&ConsumerGroupMemberAssignment{
+				Version: 0,
+				Topics:  map[string][]int32{"my-topic": {0}},
+			}),
+		"OffsetFetchRequest": NewMockOffsetFetchResponse(t).SetOffset(
+			"my-group", "my-topic", 0, 0, "", ErrNoError,
+		).SetError(ErrNoError),
+		"FetchRequest":      NewMockWrapper(fetchResponse),
+		"LeaveGroupRequest": NewMockLeaveGroupResponse(t),
+	})
+
+	group, err := NewConsumerGroup([]string{broker0.Addr()}, "my-group", config)
+	assert.NoError(t, err)
+	defer func() { _ = group.Close() }()
+
+	h := &lagHandler{
+		lagCh:  make(chan ClaimLag, 1),
+		gauges: make(chan int64, 1),
+		gauge: func() int64 {
+			g, ok := config.MetricRegistry.Get("consumer-group-lag-my-group-for-topic-my-topic-partition-0").(metrics.Gauge)
+			if !ok {
+				return -1
+			}
+			return g.Value()
+		},
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = group.Consume(ctx, []string{"my-topic"}, h)
+	}()
+
+	select {
+	case lag := <-h.lagCh:
+		assert.Equal(t, int64(9), lag.Offsets)
+		assert.GreaterOrEqual(t, lag.Time, time.Minute)
+		assert.Equal(t, int64(9), <-h.gauges)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for claim lag")
+	}
+}
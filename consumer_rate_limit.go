@@ -0,0 +1,201 @@
+package sarama
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// FetchRateLimit caps the rate at which a consumer reads data, see
+// Config.Consumer.Fetch.RateLimit and Config.Consumer.Fetch.TopicRateLimits.
+// A zero value for either field means no limit.
+type FetchRateLimit struct {
+	// BytesPerSecond is the maximum number of record bytes read per second.
+	BytesPerSecond int64
+	// RecordsPerSecond is the maximum number of records read per second.
+	RecordsPerSecond int64
+}
+
+func (l FetchRateLimit) enabled() bool {
+	return l.BytesPerSecond > 0 || l.RecordsPerSecond > 0
+}
+
+// rateBucket is a token bucket that is allowed to go into debt: the size of a
+// fetch response is only known once it has been read, so the cost is charged
+// after the fact and further fetches wait until the debt has been repaid. The
+// bucket holds at most one second worth of tokens.
+type rateBucket struct {
+	rate   float64 // tokens per second, 0 means unlimited
+	tokens float64
+	last   time.Time
+}
+
+func newRateBucket(rate int64, now time.Time) *rateBucket {
+	return &rateBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+func (b *rateBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until the bucket is out of debt.
+func (b *rateBucket) wait(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *rateBucket) take(n int64, now time.Time) {
+	if b.rate <= 0 {
+		return
+	}
+	b.refill(now)
+	b.tokens -= float64(n)
+}
+
+// fetchRateLimiter enforces a FetchRateLimit.
+type fetchRateLimiter struct {
+	bytes, records *rateBucket
+}
+
+func newFetchRateLimiter(limit FetchRateLimit, now time.Time) *fetchRateLimiter {
+	return &fetchRateLimiter{
+		bytes:   newRateBucket(limit.BytesPerSecond, now),
+		records: newRateBucket(limit.RecordsPerSecond, now),
+	}
+}
+
+func (l *fetchRateLimiter) wait(now time.Time) time.Duration {
+	return max(l.bytes.wait(now), l.records.wait(now))
+}
+
+func (l *fetchRateLimiter) take(bytes, records int64, now time.Time) {
+	l.bytes.take(bytes, now)
+	l.records.take(records, now)
+}
+
+// consumerRateLimits holds the rate limiters shared by all the brokerConsumers
+// of a consumer.
+type consumerRateLimits struct {
+	lock   sync.Mutex
+	global *fetchRateLimiter
+	topics map[string]*fetchRateLimiter
+}
+
+// newConsumerRateLimits returns nil if no rate limits are configured.
+func newConsumerRateLimits(conf *Config) *consumerRateLimits {
+	now := time.Now()
+	limits := &consumerRateLimits{topics: make(map[string]*fetchRateLimiter)}
+	if conf.Consumer.Fetch.RateLimit.enabled() {
+		limits.global = newFetchRateLimiter(conf.Consumer.Fetch.RateLimit, now)
+	}
+	for topic, limit := range conf.Consumer.Fetch.TopicRateLimits {
+		if limit.enabled() {
+			limits.topics[topic] = newFetchRateLimiter(limit, now)
+		}
+	}
+	if limits.global == nil && len(limits.topics) == 0 {
+		return nil
+	}
+	return limits
+}
+
+// globalWait returns how long until fetching from any topic is allowed again.
+func (l *consumerRateLimits) globalWait(now time.Time) time.Duration {
+	if l == nil || l.global == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.global.wait(now)
+}
+
+// topicWait returns how long until fetching from topic is allowed again.
+func (l *consumerRateLimits) topicWait(topic string, now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	limiter, ok := l.topics[topic]
+	if !ok {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return limiter.wait(now)
+}
+
+// record charges the data returned in a fetch response against the limits.
+func (l *consumerRateLimits) record(response *FetchResponse, now time.Time) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for topic, partitions := range response.Blocks {
+		var bytes, records int64
+		for _, block := range partitions {
+			bytes += int64(block.recordsSize)
+			if n, err := block.numRecords(); err == nil {
+				records += int64(n)
+			}
+		}
+		if l.global != nil {
+			l.global.take(bytes, records, now)
+		}
+		if limiter, ok := l.topics[topic]; ok {
+			limiter.take(bytes, records, now)
+		}
+	}
+}
+
+// fetchThrottleTracker measures how long a brokerConsumer spends deferring
+// fetches because of consumer-side rate limits, and reports each throttled
+// period to the consumer-fetch-rate-limit-time-in-ms histograms.
+type fetchThrottleTracker struct {
+	registry metrics.Registry
+	global   time.Time
+	topics   map[string]time.Time
+}
+
+func newFetchThrottleTracker(registry metrics.Registry) *fetchThrottleTracker {
+	return &fetchThrottleTracker{registry: registry, topics: make(map[string]time.Time)}
+}
+
+// update records whether topic (or every topic, if empty) is currently
+// throttled, closing out a throttled period once it has ended.
+func (t *fetchThrottleTracker) update(topic string, throttled bool, now time.Time) {
+	since := t.global
+	if topic != "" {
+		since = t.topics[topic]
+	}
+
+	switch {
+	case throttled && since.IsZero():
+		since = now
+	case !throttled && !since.IsZero():
+		elapsed := int64(now.Sub(since) / time.Millisecond)
+		if topic == "" {
+			getOrRegisterHistogram("consumer-fetch-rate-limit-time-in-ms", t.registry).Update(elapsed)
+		} else {
+			getOrRegisterTopicHistogram("consumer-fetch-rate-limit-time-in-ms", topic, t.registry).Update(elapsed)
+		}
+		since = time.Time{}
+	default:
+		return
+	}
+
+	if topic == "" {
+		t.global = since
+	} else {
+		t.topics[topic] = since
+	}
+}
@@ -0,0 +1,125 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestRateBucketDebt(t *testing.T) {
+	now := time.Now()
+	bucket := newRateBucket(100, now)
+	assert.Zero(t, bucket.wait(now))
+
+	bucket.take(300, now)
+	assert.Equal(t, 2*time.Second, bucket.wait(now))
+	assert.Equal(t, time.Second, bucket.wait(now.Add(time.Second)))
+	assert.Zero(t, bucket.wait(now.Add(2*time.Second)))
+
+	// tokens never accumulate beyond one second worth
+	bucket.take(0, now.Add(time.Hour))
+	bucket.take(150, now.Add(time.Hour))
+	assert.Equal(t, 500*time.Millisecond, bucket.wait(now.Add(time.Hour)))
+}
+
+func TestRateBucketUnlimited(t *testing.T) {
+	now := time.Now()
+	bucket := newRateBucket(0, now)
+	bucket.take(1<<40, now)
+	assert.Zero(t, bucket.wait(now))
+}
+
+func TestConsumerRateLimitsRecord(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Consumer.Fetch.RateLimit.RecordsPerSecond = 100
+	conf.Consumer.Fetch.TopicRateLimits = map[string]FetchRateLimit{
+		"slow": {BytesPerSecond: 10},
+	}
+	limits := newConsumerRateLimits(conf)
+	assert.NotNil(t, limits)
+
+	now := time.Now()
+	response := &FetchResponse{}
+	response.AddMessage("slow", 0, nil, StringEncoder("payload"), 0)
+	response.Blocks["slow"][0].recordsSize = 20
+	limits.record(response, now)
+
+	assert.Zero(t, limits.globalWait(now))
+	assert.Equal(t, time.Second, limits.topicWait("slow", now))
+	assert.Zero(t, limits.topicWait("other", now))
+
+	assert.Nil(t, newConsumerRateLimits(NewTestConfig()))
+}
+
+func TestFetchThrottleTrackerMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	tracker := newFetchThrottleTracker(registry)
+
+	now := time.Now()
+	tracker.update("", true, now)
+	tracker.update("", true, now.Add(100*time.Millisecond))
+	tracker.update("", false, now.Add(300*time.Millisecond))
+	tracker.update("my.topic", true, now)
+	tracker.update("my.topic", false, now.Add(50*time.Millisecond))
+
+	global := registry.Get("consumer-fetch-rate-limit-time-in-ms").(metrics.Histogram)
+	assert.Equal(t, int64(1), global.Count())
+	assert.Equal(t, int64(300), global.Sum())
+
+	topic := registry.Get("consumer-fetch-rate-limit-time-in-ms-for-topic-my_topic").(metrics.Histogram)
+	assert.Equal(t, int64(1), topic.Count())
+	assert.Equal(t, int64(50), topic.Sum())
+}
+
+func TestConsumerFetchRateLimitDefersFetch(t *testing.T) {
+	broker0 := NewMockBroker(t, 0)
+	defer broker0.Close()
+
+	fetchResponse := NewMockFetchResponse(t, 10)
+	for i := range int64(10) {
+		fetchResponse.SetMessage("my_topic", 0, i, testMsg)
+	}
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my_topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 100),
+		"FetchRequest": fetchResponse,
+	})
+
+	config := NewTestConfig()
+	config.Consumer.Fetch.RateLimit.RecordsPerSecond = 2
+	master, err := NewConsumer([]string{broker0.Addr()}, config)
+	assert.NoError(t, err)
+	defer safeClose(t, master)
+
+	consumer, err := master.ConsumePartition("my_topic", 0, 0)
+	assert.NoError(t, err)
+	defer safeClose(t, consumer)
+
+	for i := range int64(10) {
+		select {
+		case msg := <-consumer.Messages():
+			assertMessageOffset(t, msg, i)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+
+	// the first response put the consumer four seconds into debt, so no
+	// further fetch should be issued for a while
+	time.Sleep(500 * time.Millisecond)
+	fetches := 0
+	for _, rr := range broker0.History() {
+		if _, ok := rr.Request.(*FetchRequest); ok {
+			fetches++
+		}
+	}
+	assert.Equal(t, 1, fetches)
+}
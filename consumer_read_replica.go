@@ -0,0 +1,71 @@
+package sarama
+
+import "time"
+
+// ReadReplicaPolicy decides which of the read replicas designated by a
+// partition leader the consumer fetches from, see Config.Consumer.ReadReplica.
+type ReadReplicaPolicy int
+
+const (
+	// ReadReplicaAny fetches from whichever replica the leader designates.
+	ReadReplicaAny ReadReplicaPolicy = iota
+	// ReadReplicaSameRack only fetches from a designated replica if it is in
+	// the consumer's RackID, and from the leader otherwise.
+	ReadReplicaSameRack
+	// ReadReplicaLeaderOnly always fetches from the leader. RackID is still
+	// sent so that the fetched bytes are attributed to a rack.
+	ReadReplicaLeaderOnly
+)
+
+func (p ReadReplicaPolicy) String() string {
+	switch p {
+	case ReadReplicaAny:
+		return "any"
+	case ReadReplicaSameRack:
+		return "same-rack"
+	case ReadReplicaLeaderOnly:
+		return "leader-only"
+	}
+	return "unknown"
+}
+
+// acceptReadReplica reports whether the replica designated by the leader is
+// allowed by the policy.
+func (child *partitionConsumer) acceptReadReplica(replicaID int32) bool {
+	switch child.conf.Consumer.ReadReplica.Policy {
+	case ReadReplicaLeaderOnly:
+		return false
+	case ReadReplicaSameRack:
+		broker, err := child.consumer.client.Broker(replicaID)
+		if err != nil || broker.Rack() != child.conf.RackID {
+			if child.preferredReadReplica != replicaID {
+				DebugLogger.Printf(
+					"consumer/%s/%d ignoring preferred read replica %d outside of rack %q\n",
+					child.topic, child.partition, replicaID, child.conf.RackID)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// readReplicaFailed records a failed fetch from the broker, discarding the
+// replica preference once it failed Consumer.ReadReplica.MaxErrors times in a
+// row, or straight away if the failure came from another broker.
+func (child *partitionConsumer) readReplicaFailed(brokerID int32) {
+	if child.preferredReadReplica == invalidPreferredReplicaID {
+		return
+	}
+	if brokerID == child.preferredReadReplica {
+		child.preferredReadReplicaErrors++
+		if child.preferredReadReplicaErrors < child.conf.Consumer.ReadReplica.MaxErrors {
+			return
+		}
+		Logger.Printf(
+			"consumer/%s/%d preferred read replica %d failed %d times - will fallback to leader\n",
+			child.topic, child.partition, brokerID, child.preferredReadReplicaErrors)
+	}
+	child.preferredReadReplica = invalidPreferredReplicaID
+	child.preferredReadReplicaExpiry = time.Time{}
+	child.preferredReadReplicaErrors = 0
+}
@@ -947,10 +947,102 @@ func TestConsumeMessagesFromReadReplica(t *testing.T) {
 		defer cleanup()
 		assertOffsets(t, c, 1, 2, 3, 4)
 	})
+
+	t.Run("stays on preferred follower below Consumer.ReadReplica.MaxErrors", func(t *testing.T) {
+		c, cleanup := newReadReplicaTest(t, readReplicaTestConfig{
+			configure: func(cfg *Config) {
+				cfg.Consumer.ReadReplica.MaxErrors = 2
+			},
+			leaderFetches: []readReplicaFetch{
+				{preferredReadReplica: preferredReplica(1)},
+				{records: []int64{30}},
+			},
+			followerFetches: []readReplicaFetch{
+				{records: []int64{1, 2}},
+				{err: ErrUnknown},
+				{records: []int64{3, 4}},
+			},
+		})
+		defer cleanup()
+		assertOffsets(t, c, 1, 2, 3, 4)
+	})
+
+	t.Run("expires after Consumer.ReadReplica.RecheckInterval and falls back to leader", func(t *testing.T) {
+		c, cleanup := newReadReplicaTest(t, readReplicaTestConfig{
+			configure: func(cfg *Config) {
+				cfg.Metadata.RefreshFrequency = 0
+				cfg.Consumer.ReadReplica.RecheckInterval = 50 * time.Millisecond
+			},
+			leaderFetches: []readReplicaFetch{
+				{records: []int64{1, 2}, preferredReadReplica: preferredReplica(1)},
+				{records: []int64{4}},
+			},
+			followerFetches: []readReplicaFetch{
+				{records: []int64{3}},
+				{},
+			},
+		})
+		defer cleanup()
+		assertOffsets(t, c, 1, 2, 3, 4)
+	})
+
+	t.Run("ReadReplicaSameRack ignores follower in another rack", func(t *testing.T) {
+		c, cleanup := newReadReplicaTest(t, readReplicaTestConfig{
+			configure: func(cfg *Config) {
+				cfg.Consumer.ReadReplica.Policy = ReadReplicaSameRack
+			},
+			racks: map[int32]string{0: "leader_rack", 1: "follower_rack"},
+			leaderFetches: []readReplicaFetch{
+				{records: []int64{1, 2}, preferredReadReplica: preferredReplica(1)},
+				{records: []int64{3, 4}},
+			},
+			followerFetches: []readReplicaFetch{
+				{records: []int64{30}},
+			},
+		})
+		defer cleanup()
+		assertOffsets(t, c, 1, 2, 3, 4)
+	})
+
+	t.Run("ReadReplicaSameRack uses follower in the consumer's rack", func(t *testing.T) {
+		c, cleanup := newReadReplicaTest(t, readReplicaTestConfig{
+			configure: func(cfg *Config) {
+				cfg.Consumer.ReadReplica.Policy = ReadReplicaSameRack
+			},
+			racks: map[int32]string{0: "leader_rack", 1: "consumer_rack"},
+			leaderFetches: []readReplicaFetch{
+				{records: []int64{1, 2}, preferredReadReplica: preferredReplica(1)},
+				{records: []int64{30}},
+			},
+			followerFetches: []readReplicaFetch{
+				{records: []int64{3, 4}},
+			},
+		})
+		defer cleanup()
+		assertOffsets(t, c, 1, 2, 3, 4)
+	})
+
+	t.Run("ReadReplicaLeaderOnly ignores preferred follower", func(t *testing.T) {
+		c, cleanup := newReadReplicaTest(t, readReplicaTestConfig{
+			configure: func(cfg *Config) {
+				cfg.Consumer.ReadReplica.Policy = ReadReplicaLeaderOnly
+			},
+			leaderFetches: []readReplicaFetch{
+				{records: []int64{1, 2}, preferredReadReplica: preferredReplica(1)},
+				{records: []int64{3, 4}},
+			},
+			followerFetches: []readReplicaFetch{
+				{records: []int64{30}},
+			},
+		})
+		defer cleanup()
+		assertOffsets(t, c, 1, 2, 3, 4)
+	})
 }
 
 type readReplicaTestConfig struct {
 	configure       func(*Config)
+	racks           map[int32]string
 	leaderFetches   []readReplicaFetch
 	followerFetches []readReplicaFetch
 }
@@ -1004,6 +1096,9 @@ func newReadReplicaTest(t *testing.T, testConfig readReplicaTestConfig) (Partiti
 		follower = NewMockBroker(t, 1)
 		meta.SetBroker(follower.Addr(), follower.BrokerID())
 	}
+	for id, rack := range testConfig.racks {
+		meta.SetBrokerRack(id, rack)
+	}
 
 	offsets := NewMockOffsetResponse(t).
 		SetOffset("my_topic", 0, OffsetNewest, 1234).
@@ -2053,6 +2148,79 @@ func TestExcludeUncommitted(t *testing.T) {
 	broker0.Close()
 }
 
+// Aborted and control records filtered with ReadCommitted are reported through
+// metrics, along with the last stable offset of the partition
+func TestExcludeUncommittedMetrics(t *testing.T) {
+	// Given
+	broker0 := NewMockBroker(t, 0)
+	defer broker0.Close()
+
+	fetchResponse := &FetchResponse{
+		Version: 5,
+		Blocks: map[string]map[int32]*FetchResponseBlock{"my_topic": {0: {
+			AbortedTransactions: []*AbortedTransaction{{ProducerID: 7, FirstOffset: 1235}},
+		}}},
+	}
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1234, 7, true)   // committed msg
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1235, 7, true)   // uncommitted msg
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1236, 7, true)   // uncommitted msg
+	fetchResponse.AddControlRecord("my_topic", 0, 1237, 7, ControlRecordAbort) // abort control record
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1238, 7, true)   // committed msg
+	fetchResponse.SetLastStableOffset("my_topic", 0, 1239)
+	fetchResponse.Blocks["my_topic"][0].HighWaterMarkOffset = 1250
+
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my_topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 1237),
+		"FetchRequest": NewMockWrapper(fetchResponse),
+	})
+
+	cfg := NewTestConfig()
+	cfg.Consumer.Return.Errors = true
+	cfg.Version = V0_11_0_0
+	cfg.Consumer.IsolationLevel = ReadCommitted
+
+	// When
+	master, err := NewConsumer([]string{broker0.Addr()}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, master)
+
+	consumer, err := master.ConsumePartition("my_topic", 0, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int64{1234, 1238} {
+		select {
+		case message := <-consumer.Messages():
+			assertMessageOffset(t, message, want)
+		case err := <-consumer.Errors():
+			t.Fatal(err)
+		}
+	}
+
+	// Then
+	if lso := consumer.LastStableOffset(); lso != 1239 {
+		t.Errorf("Expected last stable offset 1239, found %d", lso)
+	}
+	metricValidators := newMetricValidators()
+	metricValidators.register(counterValidator("consumer-aborted-records-skipped", 2))
+	metricValidators.register(counterValidator("consumer-aborted-records-skipped-for-topic-my_topic", 2))
+	metricValidators.register(counterValidator("consumer-control-records", 1))
+	metricValidators.register(counterValidator("consumer-control-records-for-topic-my_topic", 1))
+	metricValidators.run(t, cfg.MetricRegistry)
+	if lag := metrics.GetOrRegisterGauge("consumer-lso-lag-for-topic-my_topic-partition-0", cfg.MetricRegistry).Value(); lag != 11 {
+		t.Errorf("Expected last stable offset lag 11, found %d", lag)
+	}
+
+	safeClose(t, consumer)
+}
+
 func assertMessageKey(t *testing.T, msg *ConsumerMessage, expectedKey Encoder) {
 	t.Helper()
 
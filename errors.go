@@ -63,6 +63,9 @@ var ErrControllerNotAvailable = errors.New("kafka: controller is not available")
 // the metadata.
 var ErrNoTopicsToUpdateMetadata = errors.New("kafka: no specific topics to update metadata")
 
+// ErrNoBootstrapServers is returned when Net.BootstrapResolver does not return any broker address.
+var ErrNoBootstrapServers = errors.New("kafka: bootstrap resolver returned no broker addresses")
+
 // ErrUnknownScramMechanism is returned when user tries to AlterUserScramCredentials with unknown SCRAM mechanism
 var ErrUnknownScramMechanism = errors.New("kafka: unknown SCRAM mechanism provided")
 
@@ -176,99 +179,101 @@ type KError int16
 
 // Numeric error codes returned by the Kafka server.
 const (
-	ErrUnknown                            KError = -1 // Errors.UNKNOWN_SERVER_ERROR
-	ErrNoError                            KError = 0  // Errors.NONE
-	ErrOffsetOutOfRange                   KError = 1  // Errors.OFFSET_OUT_OF_RANGE
-	ErrInvalidMessage                     KError = 2  // Errors.CORRUPT_MESSAGE
-	ErrUnknownTopicOrPartition            KError = 3  // Errors.UNKNOWN_TOPIC_OR_PARTITION
-	ErrInvalidMessageSize                 KError = 4  // Errors.INVALID_FETCH_SIZE
-	ErrLeaderNotAvailable                 KError = 5  // Errors.LEADER_NOT_AVAILABLE
-	ErrNotLeaderForPartition              KError = 6  // Errors.NOT_LEADER_OR_FOLLOWER
-	ErrRequestTimedOut                    KError = 7  // Errors.REQUEST_TIMED_OUT
-	ErrBrokerNotAvailable                 KError = 8  // Errors.BROKER_NOT_AVAILABLE
-	ErrReplicaNotAvailable                KError = 9  // Errors.REPLICA_NOT_AVAILABLE
-	ErrMessageSizeTooLarge                KError = 10 // Errors.MESSAGE_TOO_LARGE
-	ErrStaleControllerEpochCode           KError = 11 // Errors.STALE_CONTROLLER_EPOCH
-	ErrOffsetMetadataTooLarge             KError = 12 // Errors.OFFSET_METADATA_TOO_LARGE
-	ErrNetworkException                   KError = 13 // Errors.NETWORK_EXCEPTION
-	ErrOffsetsLoadInProgress              KError = 14 // Errors.COORDINATOR_LOAD_IN_PROGRESS
-	ErrConsumerCoordinatorNotAvailable    KError = 15 // Errors.COORDINATOR_NOT_AVAILABLE
-	ErrNotCoordinatorForConsumer          KError = 16 // Errors.NOT_COORDINATOR
-	ErrInvalidTopic                       KError = 17 // Errors.INVALID_TOPIC_EXCEPTION
-	ErrMessageSetSizeTooLarge             KError = 18 // Errors.RECORD_LIST_TOO_LARGE
-	ErrNotEnoughReplicas                  KError = 19 // Errors.NOT_ENOUGH_REPLICAS
-	ErrNotEnoughReplicasAfterAppend       KError = 20 // Errors.NOT_ENOUGH_REPLICAS_AFTER_APPEND
-	ErrInvalidRequiredAcks                KError = 21 // Errors.INVALID_REQUIRED_ACKS
-	ErrIllegalGeneration                  KError = 22 // Errors.ILLEGAL_GENERATION
-	ErrInconsistentGroupProtocol          KError = 23 // Errors.INCONSISTENT_GROUP_PROTOCOL
-	ErrInvalidGroupId                     KError = 24 // Errors.INVALID_GROUP_ID
-	ErrUnknownMemberId                    KError = 25 // Errors.UNKNOWN_MEMBER_ID
-	ErrInvalidSessionTimeout              KError = 26 // Errors.INVALID_SESSION_TIMEOUT
-	ErrRebalanceInProgress                KError = 27 // Errors.REBALANCE_IN_PROGRESS
-	ErrInvalidCommitOffsetSize            KError = 28 // Errors.INVALID_COMMIT_OFFSET_SIZE
-	ErrTopicAuthorizationFailed           KError = 29 // Errors.TOPIC_AUTHORIZATION_FAILED
-	ErrGroupAuthorizationFailed           KError = 30 // Errors.GROUP_AUTHORIZATION_FAILED
-	ErrClusterAuthorizationFailed         KError = 31 // Errors.CLUSTER_AUTHORIZATION_FAILED
-	ErrInvalidTimestamp                   KError = 32 // Errors.INVALID_TIMESTAMP
-	ErrUnsupportedSASLMechanism           KError = 33 // Errors.UNSUPPORTED_SASL_MECHANISM
-	ErrIllegalSASLState                   KError = 34 // Errors.ILLEGAL_SASL_STATE
-	ErrUnsupportedVersion                 KError = 35 // Errors.UNSUPPORTED_VERSION
-	ErrTopicAlreadyExists                 KError = 36 // Errors.TOPIC_ALREADY_EXISTS
-	ErrInvalidPartitions                  KError = 37 // Errors.INVALID_PARTITIONS
-	ErrInvalidReplicationFactor           KError = 38 // Errors.INVALID_REPLICATION_FACTOR
-	ErrInvalidReplicaAssignment           KError = 39 // Errors.INVALID_REPLICA_ASSIGNMENT
-	ErrInvalidConfig                      KError = 40 // Errors.INVALID_CONFIG
-	ErrNotController                      KError = 41 // Errors.NOT_CONTROLLER
-	ErrInvalidRequest                     KError = 42 // Errors.INVALID_REQUEST
-	ErrUnsupportedForMessageFormat        KError = 43 // Errors.UNSUPPORTED_FOR_MESSAGE_FORMAT
-	ErrPolicyViolation                    KError = 44 // Errors.POLICY_VIOLATION
-	ErrOutOfOrderSequenceNumber           KError = 45 // Errors.OUT_OF_ORDER_SEQUENCE_NUMBER
-	ErrDuplicateSequenceNumber            KError = 46 // Errors.DUPLICATE_SEQUENCE_NUMBER
-	ErrInvalidProducerEpoch               KError = 47 // Errors.INVALID_PRODUCER_EPOCH
-	ErrInvalidTxnState                    KError = 48 // Errors.INVALID_TXN_STATE
-	ErrInvalidProducerIDMapping           KError = 49 // Errors.INVALID_PRODUCER_ID_MAPPING
-	ErrInvalidTransactionTimeout          KError = 50 // Errors.INVALID_TRANSACTION_TIMEOUT
-	ErrConcurrentTransactions             KError = 51 // Errors.CONCURRENT_TRANSACTIONS
-	ErrTransactionCoordinatorFenced       KError = 52 // Errors.TRANSACTION_COORDINATOR_FENCED
-	ErrTransactionalIDAuthorizationFailed KError = 53 // Errors.TRANSACTIONAL_ID_AUTHORIZATION_FAILED
-	ErrSecurityDisabled                   KError = 54 // Errors.SECURITY_DISABLED
-	ErrOperationNotAttempted              KError = 55 // Errors.OPERATION_NOT_ATTEMPTED
-	ErrKafkaStorageError                  KError = 56 // Errors.KAFKA_STORAGE_ERROR
-	ErrLogDirNotFound                     KError = 57 // Errors.LOG_DIR_NOT_FOUND
-	ErrSASLAuthenticationFailed           KError = 58 // Errors.SASL_AUTHENTICATION_FAILED
-	ErrUnknownProducerID                  KError = 59 // Errors.UNKNOWN_PRODUCER_ID
-	ErrReassignmentInProgress             KError = 60 // Errors.REASSIGNMENT_IN_PROGRESS
-	ErrDelegationTokenAuthDisabled        KError = 61 // Errors.DELEGATION_TOKEN_AUTH_DISABLED
-	ErrDelegationTokenNotFound            KError = 62 // Errors.DELEGATION_TOKEN_NOT_FOUND
-	ErrDelegationTokenOwnerMismatch       KError = 63 // Errors.DELEGATION_TOKEN_OWNER_MISMATCH
-	ErrDelegationTokenRequestNotAllowed   KError = 64 // Errors.DELEGATION_TOKEN_REQUEST_NOT_ALLOWED
-	ErrDelegationTokenAuthorizationFailed KError = 65 // Errors.DELEGATION_TOKEN_AUTHORIZATION_FAILED
-	ErrDelegationTokenExpired             KError = 66 // Errors.DELEGATION_TOKEN_EXPIRED
-	ErrInvalidPrincipalType               KError = 67 // Errors.INVALID_PRINCIPAL_TYPE
-	ErrNonEmptyGroup                      KError = 68 // Errors.NON_EMPTY_GROUP
-	ErrGroupIDNotFound                    KError = 69 // Errors.GROUP_ID_NOT_FOUND
-	ErrFetchSessionIDNotFound             KError = 70 // Errors.FETCH_SESSION_ID_NOT_FOUND
-	ErrInvalidFetchSessionEpoch           KError = 71 // Errors.INVALID_FETCH_SESSION_EPOCH
-	ErrListenerNotFound                   KError = 72 // Errors.LISTENER_NOT_FOUND
-	ErrTopicDeletionDisabled              KError = 73 // Errors.TOPIC_DELETION_DISABLED
-	ErrFencedLeaderEpoch                  KError = 74 // Errors.FENCED_LEADER_EPOCH
-	ErrUnknownLeaderEpoch                 KError = 75 // Errors.UNKNOWN_LEADER_EPOCH
-	ErrUnsupportedCompressionType         KError = 76 // Errors.UNSUPPORTED_COMPRESSION_TYPE
-	ErrStaleBrokerEpoch                   KError = 77 // Errors.STALE_BROKER_EPOCH
-	ErrOffsetNotAvailable                 KError = 78 // Errors.OFFSET_NOT_AVAILABLE
-	ErrMemberIdRequired                   KError = 79 // Errors.MEMBER_ID_REQUIRED
-	ErrPreferredLeaderNotAvailable        KError = 80 // Errors.PREFERRED_LEADER_NOT_AVAILABLE
-	ErrGroupMaxSizeReached                KError = 81 // Errors.GROUP_MAX_SIZE_REACHED
-	ErrFencedInstancedId                  KError = 82 // Errors.FENCED_INSTANCE_ID
-	ErrEligibleLeadersNotAvailable        KError = 83 // Errors.ELIGIBLE_LEADERS_NOT_AVAILABLE
-	ErrElectionNotNeeded                  KError = 84 // Errors.ELECTION_NOT_NEEDED
-	ErrNoReassignmentInProgress           KError = 85 // Errors.NO_REASSIGNMENT_IN_PROGRESS
-	ErrGroupSubscribedToTopic             KError = 86 // Errors.GROUP_SUBSCRIBED_TO_TOPIC
-	ErrInvalidRecord                      KError = 87 // Errors.INVALID_RECORD
-	ErrUnstableOffsetCommit               KError = 88 // Errors.UNSTABLE_OFFSET_COMMIT
-	ErrThrottlingQuotaExceeded            KError = 89 // Errors.THROTTLING_QUOTA_EXCEEDED
-	ErrProducerFenced                     KError = 90 // Errors.PRODUCER_FENCED
-	ErrInvalidUpdateVersion               KError = 95 // Errors.INVALID_UPDATE_VERSION
+	ErrUnknown                            KError = -1  // Errors.UNKNOWN_SERVER_ERROR
+	ErrNoError                            KError = 0   // Errors.NONE
+	ErrOffsetOutOfRange                   KError = 1   // Errors.OFFSET_OUT_OF_RANGE
+	ErrInvalidMessage                     KError = 2   // Errors.CORRUPT_MESSAGE
+	ErrUnknownTopicOrPartition            KError = 3   // Errors.UNKNOWN_TOPIC_OR_PARTITION
+	ErrInvalidMessageSize                 KError = 4   // Errors.INVALID_FETCH_SIZE
+	ErrLeaderNotAvailable                 KError = 5   // Errors.LEADER_NOT_AVAILABLE
+	ErrNotLeaderForPartition              KError = 6   // Errors.NOT_LEADER_OR_FOLLOWER
+	ErrRequestTimedOut                    KError = 7   // Errors.REQUEST_TIMED_OUT
+	ErrBrokerNotAvailable                 KError = 8   // Errors.BROKER_NOT_AVAILABLE
+	ErrReplicaNotAvailable                KError = 9   // Errors.REPLICA_NOT_AVAILABLE
+	ErrMessageSizeTooLarge                KError = 10  // Errors.MESSAGE_TOO_LARGE
+	ErrStaleControllerEpochCode           KError = 11  // Errors.STALE_CONTROLLER_EPOCH
+	ErrOffsetMetadataTooLarge             KError = 12  // Errors.OFFSET_METADATA_TOO_LARGE
+	ErrNetworkException                   KError = 13  // Errors.NETWORK_EXCEPTION
+	ErrOffsetsLoadInProgress              KError = 14  // Errors.COORDINATOR_LOAD_IN_PROGRESS
+	ErrConsumerCoordinatorNotAvailable    KError = 15  // Errors.COORDINATOR_NOT_AVAILABLE
+	ErrNotCoordinatorForConsumer          KError = 16  // Errors.NOT_COORDINATOR
+	ErrInvalidTopic                       KError = 17  // Errors.INVALID_TOPIC_EXCEPTION
+	ErrMessageSetSizeTooLarge             KError = 18  // Errors.RECORD_LIST_TOO_LARGE
+	ErrNotEnoughReplicas                  KError = 19  // Errors.NOT_ENOUGH_REPLICAS
+	ErrNotEnoughReplicasAfterAppend       KError = 20  // Errors.NOT_ENOUGH_REPLICAS_AFTER_APPEND
+	ErrInvalidRequiredAcks                KError = 21  // Errors.INVALID_REQUIRED_ACKS
+	ErrIllegalGeneration                  KError = 22  // Errors.ILLEGAL_GENERATION
+	ErrInconsistentGroupProtocol          KError = 23  // Errors.INCONSISTENT_GROUP_PROTOCOL
+	ErrInvalidGroupId                     KError = 24  // Errors.INVALID_GROUP_ID
+	ErrUnknownMemberId                    KError = 25  // Errors.UNKNOWN_MEMBER_ID
+	ErrInvalidSessionTimeout              KError = 26  // Errors.INVALID_SESSION_TIMEOUT
+	ErrRebalanceInProgress                KError = 27  // Errors.REBALANCE_IN_PROGRESS
+	ErrInvalidCommitOffsetSize            KError = 28  // Errors.INVALID_COMMIT_OFFSET_SIZE
+	ErrTopicAuthorizationFailed           KError = 29  // Errors.TOPIC_AUTHORIZATION_FAILED
+	ErrGroupAuthorizationFailed           KError = 30  // Errors.GROUP_AUTHORIZATION_FAILED
+	ErrClusterAuthorizationFailed         KError = 31  // Errors.CLUSTER_AUTHORIZATION_FAILED
+	ErrInvalidTimestamp                   KError = 32  // Errors.INVALID_TIMESTAMP
+	ErrUnsupportedSASLMechanism           KError = 33  // Errors.UNSUPPORTED_SASL_MECHANISM
+	ErrIllegalSASLState                   KError = 34  // Errors.ILLEGAL_SASL_STATE
+	ErrUnsupportedVersion                 KError = 35  // Errors.UNSUPPORTED_VERSION
+	ErrTopicAlreadyExists                 KError = 36  // Errors.TOPIC_ALREADY_EXISTS
+	ErrInvalidPartitions                  KError = 37  // Errors.INVALID_PARTITIONS
+	ErrInvalidReplicationFactor           KError = 38  // Errors.INVALID_REPLICATION_FACTOR
+	ErrInvalidReplicaAssignment           KError = 39  // Errors.INVALID_REPLICA_ASSIGNMENT
+	ErrInvalidConfig                      KError = 40  // Errors.INVALID_CONFIG
+	ErrNotController                      KError = 41  // Errors.NOT_CONTROLLER
+	ErrInvalidRequest                     KError = 42  // Errors.INVALID_REQUEST
+	ErrUnsupportedForMessageFormat        KError = 43  // Errors.UNSUPPORTED_FOR_MESSAGE_FORMAT
+	ErrPolicyViolation                    KError = 44  // Errors.POLICY_VIOLATION
+	ErrOutOfOrderSequenceNumber           KError = 45  // Errors.OUT_OF_ORDER_SEQUENCE_NUMBER
+	ErrDuplicateSequenceNumber            KError = 46  // Errors.DUPLICATE_SEQUENCE_NUMBER
+	ErrInvalidProducerEpoch               KError = 47  // Errors.INVALID_PRODUCER_EPOCH
+	ErrInvalidTxnState                    KError = 48  // Errors.INVALID_TXN_STATE
+	ErrInvalidProducerIDMapping           KError = 49  // Errors.INVALID_PRODUCER_ID_MAPPING
+	ErrInvalidTransactionTimeout          KError = 50  // Errors.INVALID_TRANSACTION_TIMEOUT
+	ErrConcurrentTransactions             KError = 51  // Errors.CONCURRENT_TRANSACTIONS
+	ErrTransactionCoordinatorFenced       KError = 52  // Errors.TRANSACTION_COORDINATOR_FENCED
+	ErrTransactionalIDAuthorizationFailed KError = 53  // Errors.TRANSACTIONAL_ID_AUTHORIZATION_FAILED
+	ErrSecurityDisabled                   KError = 54  // Errors.SECURITY_DISABLED
+	ErrOperationNotAttempted              KError = 55  // Errors.OPERATION_NOT_ATTEMPTED
+	ErrKafkaStorageError                  KError = 56  // Errors.KAFKA_STORAGE_ERROR
+	ErrLogDirNotFound                     KError = 57  // Errors.LOG_DIR_NOT_FOUND
+	ErrSASLAuthenticationFailed           KError = 58  // Errors.SASL_AUTHENTICATION_FAILED
+	ErrUnknownProducerID                  KError = 59  // Errors.UNKNOWN_PRODUCER_ID
+	ErrReassignmentInProgress             KError = 60  // Errors.REASSIGNMENT_IN_PROGRESS
+	ErrDelegationTokenAuthDisabled        KError = 61  // Errors.DELEGATION_TOKEN_AUTH_DISABLED
+	ErrDelegationTokenNotFound            KError = 62  // Errors.DELEGATION_TOKEN_NOT_FOUND
+	ErrDelegationTokenOwnerMismatch       KError = 63  // Errors.DELEGATION_TOKEN_OWNER_MISMATCH
+	ErrDelegationTokenRequestNotAllowed   KError = 64  // Errors.DELEGATION_TOKEN_REQUEST_NOT_ALLOWED
+	ErrDelegationTokenAuthorizationFailed KError = 65  // Errors.DELEGATION_TOKEN_AUTHORIZATION_FAILED
+	ErrDelegationTokenExpired             KError = 66  // Errors.DELEGATION_TOKEN_EXPIRED
+	ErrInvalidPrincipalType               KError = 67  // Errors.INVALID_PRINCIPAL_TYPE
+	ErrNonEmptyGroup                      KError = 68  // Errors.NON_EMPTY_GROUP
+	ErrGroupIDNotFound                    KError = 69  // Errors.GROUP_ID_NOT_FOUND
+	ErrFetchSessionIDNotFound             KError = 70  // Errors.FETCH_SESSION_ID_NOT_FOUND
+	ErrInvalidFetchSessionEpoch           KError = 71  // Errors.INVALID_FETCH_SESSION_EPOCH
+	ErrListenerNotFound                   KError = 72  // Errors.LISTENER_NOT_FOUND
+	ErrTopicDeletionDisabled              KError = 73  // Errors.TOPIC_DELETION_DISABLED
+	ErrFencedLeaderEpoch                  KError = 74  // Errors.FENCED_LEADER_EPOCH
+	ErrUnknownLeaderEpoch                 KError = 75  // Errors.UNKNOWN_LEADER_EPOCH
+	ErrUnsupportedCompressionType         KError = 76  // Errors.UNSUPPORTED_COMPRESSION_TYPE
+	ErrStaleBrokerEpoch                   KError = 77  // Errors.STALE_BROKER_EPOCH
+	ErrOffsetNotAvailable                 KError = 78  // Errors.OFFSET_NOT_AVAILABLE
+	ErrMemberIdRequired                   KError = 79  // Errors.MEMBER_ID_REQUIRED
+	ErrPreferredLeaderNotAvailable        KError = 80  // Errors.PREFERRED_LEADER_NOT_AVAILABLE
+	ErrGroupMaxSizeReached                KError = 81  // Errors.GROUP_MAX_SIZE_REACHED
+	ErrFencedInstancedId                  KError = 82  // Errors.FENCED_INSTANCE_ID
+	ErrEligibleLeadersNotAvailable        KError = 83  // Errors.ELIGIBLE_LEADERS_NOT_AVAILABLE
+	ErrElectionNotNeeded                  KError = 84  // Errors.ELECTION_NOT_NEEDED
+	ErrNoReassignmentInProgress           KError = 85  // Errors.NO_REASSIGNMENT_IN_PROGRESS
+	ErrGroupSubscribedToTopic             KError = 86  // Errors.GROUP_SUBSCRIBED_TO_TOPIC
+	ErrInvalidRecord                      KError = 87  // Errors.INVALID_RECORD
+	ErrUnstableOffsetCommit               KError = 88  // Errors.UNSTABLE_OFFSET_COMMIT
+	ErrThrottlingQuotaExceeded            KError = 89  // Errors.THROTTLING_QUOTA_EXCEEDED
+	ErrProducerFenced                     KError = 90  // Errors.PRODUCER_FENCED
+	ErrInvalidUpdateVersion               KError = 95  // Errors.INVALID_UPDATE_VERSION
+	ErrUnknownSubscriptionId              KError = 117 // Errors.UNKNOWN_SUBSCRIPTION_ID
+	ErrTelemetryTooLarge                  KError = 118 // Errors.TELEMETRY_TOO_LARGE
 )
 
 func (err KError) Error() string {
@@ -459,6 +464,10 @@ func (err KError) Error() string {
 		return "kafka server: The throttling quota has been exceeded"
 	case ErrInvalidUpdateVersion:
 		return "kafka server: The given update version was invalid"
+	case ErrUnknownSubscriptionId:
+		return "kafka server: Client sent a push telemetry request with an invalid or outdated subscription ID"
+	case ErrTelemetryTooLarge:
+		return "kafka server: Client sent a push telemetry request larger than the maximum size the broker will accept"
 	}
 
 	return fmt.Sprintf("Unknown error, how did this happen? Error code = %d", err)
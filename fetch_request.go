@@ -103,6 +103,15 @@ func (r *FetchRequest) setVersion(v int16) {
 	r.Version = v
 }
 
+// minEncodableVersion is 4 when reading committed records only, as older
+// versions would silently return aborted transactional records.
+func (r *FetchRequest) minEncodableVersion() int16 {
+	if r.Isolation == ReadCommitted {
+		return 4
+	}
+	return 0
+}
+
 type IsolationLevel int8
 
 const (
@@ -98,6 +98,10 @@ type FetchResponseBlock struct {
 	// partial batch fully, when one is present. Zero if no partial trailing
 	// batch was detected or the size is unknown (e.g. legacy MessageSet).
 	partialBatchSize int32
+
+	// recordsSize is the on-wire size in bytes of the records in this block.
+	// This field is computed locally and is not part of the server's binary response.
+	recordsSize int32
 }
 
 func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error) {
@@ -163,6 +167,7 @@ func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error)
 		if err != nil {
 			return err
 		}
+		b.recordsSize = int32(len(recordsBytes))
 		if sizeMetric != nil {
 			sizeMetric.Update(int64(len(recordsBytes)))
 		}
@@ -174,6 +179,7 @@ func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error)
 		if err != nil {
 			return err
 		}
+		b.recordsSize = recordsSize
 		if sizeMetric != nil {
 			sizeMetric.Update(int64(recordsSize))
 		}
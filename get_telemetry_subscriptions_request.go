@@ -0,0 +1,69 @@
+package sarama
+
+// GetTelemetrySubscriptions Request (Version: 0) => client_instance_id _tagged_fields
+//   client_instance_id => UUID
+
+// GetTelemetrySubscriptionsRequest asks a broker which client metrics it wants
+// pushed, see KIP-714.
+type GetTelemetrySubscriptionsRequest struct {
+	Version int16
+
+	// ClientInstanceID is the id assigned by the broker to this client, or the
+	// zero Uuid to have the broker assign one.
+	ClientInstanceID Uuid
+}
+
+func NewGetTelemetrySubscriptionsRequest(clientInstanceID Uuid) *GetTelemetrySubscriptionsRequest {
+	return &GetTelemetrySubscriptionsRequest{ClientInstanceID: clientInstanceID}
+}
+
+func (r *GetTelemetrySubscriptionsRequest) setVersion(v int16) {
+	r.Version = v
+}
+
+func (r *GetTelemetrySubscriptionsRequest) encode(pe packetEncoder) error {
+	if err := pe.putUuid(r.ClientInstanceID); err != nil {
+		return err
+	}
+
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *GetTelemetrySubscriptionsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ClientInstanceID, err = pd.getUuid(); err != nil {
+		return err
+	}
+
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *GetTelemetrySubscriptionsRequest) key() int16 {
+	return apiKeyGetTelemetrySubscriptions
+}
+
+func (r *GetTelemetrySubscriptionsRequest) version() int16 {
+	return r.Version
+}
+
+func (r *GetTelemetrySubscriptionsRequest) headerVersion() int16 {
+	return 2
+}
+
+func (r *GetTelemetrySubscriptionsRequest) isValidVersion() bool {
+	return r.Version == 0
+}
+
+func (r *GetTelemetrySubscriptionsRequest) isFlexible() bool {
+	return r.isFlexibleVersion(r.Version)
+}
+
+func (r *GetTelemetrySubscriptionsRequest) isFlexibleVersion(version int16) bool {
+	return version >= 0
+}
+
+func (r *GetTelemetrySubscriptionsRequest) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
@@ -0,0 +1,16 @@
+//go:build !functional
+
+package sarama
+
+import "testing"
+
+var getTelemetrySubscriptionsRequestV0 = []byte{
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+	0, // empty tagged fields
+}
+
+func TestGetTelemetrySubscriptionsRequest(t *testing.T) {
+	request := NewGetTelemetrySubscriptionsRequest(Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+
+	testRequest(t, "v0", request, getTelemetrySubscriptionsRequestV0)
+}
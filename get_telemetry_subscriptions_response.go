@@ -0,0 +1,149 @@
+package sarama
+
+import "time"
+
+// GetTelemetrySubscriptions Response (Version: 0) => throttle_time_ms error_code client_instance_id subscription_id [accepted_compression_types] push_interval_ms telemetry_max_bytes delta_temporality [requested_metrics] _tagged_fields
+//   throttle_time_ms => INT32
+//   error_code => INT16
+//   client_instance_id => UUID
+//   subscription_id => INT32
+//   accepted_compression_types => INT8
+//   push_interval_ms => INT32
+//   telemetry_max_bytes => INT32
+//   delta_temporality => BOOLEAN
+//   requested_metrics => COMPACT_STRING
+
+type GetTelemetrySubscriptionsResponse struct {
+	Version int16
+
+	ThrottleTimeMs int32
+	Err            KError
+
+	// ClientInstanceID is the id assigned to the client by the broker.
+	ClientInstanceID Uuid
+	// SubscriptionID identifies the subscription, it must be sent back with
+	// every PushTelemetryRequest.
+	SubscriptionID int32
+	// AcceptedCompressionTypes lists the compression codecs the broker
+	// accepts for the pushed metrics, in order of preference.
+	AcceptedCompressionTypes []CompressionCodec
+	// PushIntervalMs is how often metrics should be pushed.
+	PushIntervalMs int32
+	// TelemetryMaxBytes is the maximum size of the pushed metrics.
+	TelemetryMaxBytes int32
+	// DeltaTemporality is true if sums must be pushed as deltas since the
+	// previous push rather than cumulatively.
+	DeltaTemporality bool
+	// RequestedMetrics contains the prefixes of the metrics to push. An empty
+	// list means no metrics, a single empty string means all metrics.
+	RequestedMetrics []string
+}
+
+func (r *GetTelemetrySubscriptionsResponse) setVersion(v int16) {
+	r.Version = v
+}
+
+func (r *GetTelemetrySubscriptionsResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putKError(r.Err)
+	if err := pe.putUuid(r.ClientInstanceID); err != nil {
+		return err
+	}
+	pe.putInt32(r.SubscriptionID)
+
+	if err := pe.putArrayLength(len(r.AcceptedCompressionTypes)); err != nil {
+		return err
+	}
+	for _, codec := range r.AcceptedCompressionTypes {
+		pe.putInt8(int8(codec))
+	}
+
+	pe.putInt32(r.PushIntervalMs)
+	pe.putInt32(r.TelemetryMaxBytes)
+	pe.putBool(r.DeltaTemporality)
+	if err := pe.putStringArray(r.RequestedMetrics); err != nil {
+		return err
+	}
+
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *GetTelemetrySubscriptionsResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.Err, err = pd.getKError(); err != nil {
+		return err
+	}
+	if r.ClientInstanceID, err = pd.getUuid(); err != nil {
+		return err
+	}
+	if r.SubscriptionID, err = pd.getInt32(); err != nil {
+		return err
+	}
+
+	n, err := pd.getArrayLength()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		r.AcceptedCompressionTypes = make([]CompressionCodec, n)
+		for i := range n {
+			codec, err := pd.getInt8()
+			if err != nil {
+				return err
+			}
+			r.AcceptedCompressionTypes[i] = CompressionCodec(codec)
+		}
+	}
+
+	if r.PushIntervalMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.TelemetryMaxBytes, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.DeltaTemporality, err = pd.getBool(); err != nil {
+		return err
+	}
+	if r.RequestedMetrics, err = pd.getStringArray(); err != nil {
+		return err
+	}
+
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *GetTelemetrySubscriptionsResponse) key() int16 {
+	return apiKeyGetTelemetrySubscriptions
+}
+
+func (r *GetTelemetrySubscriptionsResponse) version() int16 {
+	return r.Version
+}
+
+func (r *GetTelemetrySubscriptionsResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *GetTelemetrySubscriptionsResponse) isValidVersion() bool {
+	return r.Version == 0
+}
+
+func (r *GetTelemetrySubscriptionsResponse) isFlexible() bool {
+	return r.isFlexibleVersion(r.Version)
+}
+
+func (r *GetTelemetrySubscriptionsResponse) isFlexibleVersion(version int16) bool {
+	return version >= 0
+}
+
+func (r *GetTelemetrySubscriptionsResponse) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
+
+func (r *GetTelemetrySubscriptionsResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
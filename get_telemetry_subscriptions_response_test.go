@@ -0,0 +1,36 @@
+//go:build !functional
+
+package sarama
+
+import "testing"
+
+var getTelemetrySubscriptionsResponseV0 = []byte{
+	0, 0, 0, 100, // throttle time
+	0, 0, // error code
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+	0, 0, 0, 7, // subscription id
+	3,    // accepted compression types (two element array)
+	4, 1, // zstd, gzip
+	0, 0, 0x75, 0x30, // push interval ms
+	0, 0, 0x04, 0x00, // telemetry max bytes
+	1,                                                                             // delta temporality
+	2,                                                                             // requested metrics (one element array)
+	16, 's', 'a', 'r', 'a', 'm', 'a', '.', 'r', 'e', 'q', 'u', 'e', 's', 't', '.', // requested metric prefix
+	0, // empty tagged fields
+}
+
+func TestGetTelemetrySubscriptionsResponse(t *testing.T) {
+	response := &GetTelemetrySubscriptionsResponse{
+		ThrottleTimeMs:           100,
+		Err:                      ErrNoError,
+		ClientInstanceID:         Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		SubscriptionID:           7,
+		AcceptedCompressionTypes: []CompressionCodec{CompressionZSTD, CompressionGZIP},
+		PushIntervalMs:           30000,
+		TelemetryMaxBytes:        1024,
+		DeltaTemporality:         true,
+		RequestedMetrics:         []string{"sarama.request."},
+	}
+
+	testResponse(t, "v0", response, getTelemetrySubscriptionsResponseV0)
+}
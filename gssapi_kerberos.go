@@ -28,11 +28,21 @@ const (
 	GSS_API_INITIAL     = 1
 	GSS_API_VERIFY      = 2
 	GSS_API_FINISH      = 3
+
+	// kerberosReloginWindowFactor is the fraction of the TGT lifetime after
+	// which a cached Kerberos client logs in again, like the
+	// sasl.kerberos.ticket.renew.window.factor setting of the Java client.
+	kerberosReloginWindowFactor = 0.8
 )
 
 type GSSAPIConfig struct {
-	AuthType           int
-	KeyTabPath         string
+	AuthType   int
+	KeyTabPath string
+	// CCachePath is the credential cache used with KRB5_CCACHE_AUTH. If
+	// empty, the cache named by the KRB5CCNAME environment variable is used,
+	// or /tmp/krb5cc_<uid> if it is not set. Only FILE caches are supported.
+	// The cache is read again when the client logs in again, so that TGTs
+	// renewed by kinit or k5start are picked up.
 	CCachePath         string
 	KerberosConfigPath string
 	ServiceName        string
@@ -49,6 +59,12 @@ type GSSAPIKerberosAuth struct {
 	encKey                types.EncryptionKey
 	NewKerberosClientFunc func(config *GSSAPIConfig) (KerberosClient, error)
 	step                  int
+
+	// client is kept between the authentications of a connection, such as
+	// KIP-368 re-authentications, until reloginAt
+	client    KerberosClient
+	reloginAt time.Time
+	now       func() time.Time
 }
 
 type KerberosClient interface {
@@ -59,6 +75,16 @@ type KerberosClient interface {
 	Destroy()
 }
 
+// KerberosTGTTimes is optionally implemented by a KerberosClient that knows
+// when its TGT was issued and when it expires. GSSAPIKerberosAuth then keeps
+// the client logged in between the authentications of a connection,
+// including KIP-368 re-authentications, and replaces it with a new one once
+// 80% of the TGT lifetime has elapsed. Clients not implementing it are
+// created and logged in for every authentication.
+type KerberosTGTTimes interface {
+	TGTTimes() (authTime, endTime time.Time)
+}
+
 type BuildSpnFunc func(serviceName, host string) string
 
 // writePackage appends length in big endian before the payload, and sends it to kafka
@@ -218,7 +244,10 @@ func (krbAuth *GSSAPIKerberosAuth) Login(
 		Logger.Printf("Kerberos client login error: %s", err)
 		return nil, err
 	}
+	return krbAuth.serviceTicket(client, spn)
+}
 
+func (krbAuth *GSSAPIKerberosAuth) serviceTicket(client KerberosClient, spn string) (*messages.Ticket, error) {
 	ticket, encKey, err := client.GetServiceTicket(spn)
 	if err != nil {
 		Logger.Printf("Kerberos service ticket error for %s: %s", spn, err)
@@ -231,19 +260,70 @@ func (krbAuth *GSSAPIKerberosAuth) Login(
 	return &ticket, nil
 }
 
-// Authorize performs the kerberos auth handshake for authorization
-func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
+// login returns a logged in client and a service ticket for the broker. The
+// cached client is reused until its TGT is due for a refresh, after which, or
+// if it fails to get a service ticket, a new client logs in. Callers must
+// destroy the returned client once done if it is not krbAuth.client.
+func (krbAuth *GSSAPIKerberosAuth) login(broker *Broker) (KerberosClient, *messages.Ticket, error) {
+	spn := krbAuth.spn(broker)
+	if krbAuth.client != nil {
+		if krbAuth.clock().Before(krbAuth.reloginAt) {
+			if ticket, err := krbAuth.serviceTicket(krbAuth.client, spn); err == nil {
+				return krbAuth.client, ticket, nil
+			}
+			Logger.Printf("Kerberos client for %s failed to get a service ticket, logging in again", spn)
+		} else {
+			DebugLogger.Printf("Kerberos TGT for %s is due for a refresh, logging in again", spn)
+		}
+		krbAuth.destroyClient()
+	}
+
 	client, err := krbAuth.NewKerberosClientFunc(krbAuth.Config)
 	if err != nil {
 		Logger.Printf("Kerberos client initialization error: %s", err)
-		return err
+		return nil, nil, err
+	}
+	ticket, err := krbAuth.Login(client, spn)
+	if err != nil {
+		client.Destroy()
+		return nil, nil, err
+	}
+
+	if times, ok := client.(KerberosTGTTimes); ok {
+		authTime, endTime := times.TGTTimes()
+		if lifetime := endTime.Sub(authTime); lifetime > 0 && krbAuth.clock().Before(endTime) {
+			krbAuth.client = client
+			krbAuth.reloginAt = authTime.Add(time.Duration(float64(lifetime) * kerberosReloginWindowFactor))
+		}
+	}
+	return client, ticket, nil
+}
+
+// destroyClient destroys the cached client, if any.
+func (krbAuth *GSSAPIKerberosAuth) destroyClient() {
+	if krbAuth.client != nil {
+		krbAuth.client.Destroy()
+		krbAuth.client = nil
+		krbAuth.reloginAt = time.Time{}
 	}
-	defer client.Destroy()
+}
+
+func (krbAuth *GSSAPIKerberosAuth) clock() time.Time {
+	if krbAuth.now != nil {
+		return krbAuth.now()
+	}
+	return time.Now()
+}
 
-	ticket, err := krbAuth.Login(client, krbAuth.spn(broker))
+// Authorize performs the kerberos auth handshake for authorization
+func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
+	client, ticket, err := krbAuth.login(broker)
 	if err != nil {
 		return err
 	}
+	if client != krbAuth.client {
+		defer client.Destroy()
+	}
 
 	principal := strings.Join(ticket.SName.NameString, "/") + "@" + ticket.Realm
 	var receivedBytes []byte
@@ -284,16 +364,12 @@ func (krbAuth *GSSAPIKerberosAuth) AuthorizeV2(
 	broker *Broker,
 	authSendReceiver func(authBytes []byte) (*SaslAuthenticateResponse, error),
 ) error {
-	client, err := krbAuth.NewKerberosClientFunc(krbAuth.Config)
+	client, ticket, err := krbAuth.login(broker)
 	if err != nil {
-		Logger.Printf("Kerberos client initialization error: %s", err)
 		return err
 	}
-	defer client.Destroy()
-
-	ticket, err := krbAuth.Login(client, krbAuth.spn(broker))
-	if err != nil {
-		return err
+	if client != krbAuth.client {
+		defer client.Destroy()
 	}
 
 	principal := strings.Join(ticket.SName.NameString, "/") + "@" + ticket.Realm
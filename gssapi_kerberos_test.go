@@ -0,0 +1,148 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestGSSAPIKerberosAuthReloginBeforeTGTExpiry(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Net.SASL.GSSAPI.ServiceName = "kafka"
+	broker := &Broker{addr: "localhost:9092", conf: conf}
+
+	now := time.Now()
+	var clients []*MockKerberosClient
+	krbAuth := &GSSAPIKerberosAuth{
+		Config: &conf.Net.SASL.GSSAPI,
+		NewKerberosClientFunc: func(*GSSAPIConfig) (KerberosClient, error) {
+			client := &MockKerberosClient{tgtAuthTime: now, tgtEndTime: now.Add(10 * time.Hour)}
+			clients = append(clients, client)
+			return client, nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	client, ticket, err := krbAuth.login(broker)
+	assert.NoError(t, err)
+	assert.NotNil(t, ticket)
+	assert.Same(t, clients[0], client)
+	assert.Same(t, client, krbAuth.client, "the client is cached")
+
+	// reused until 80% of the TGT lifetime has elapsed
+	now = now.Add(7 * time.Hour)
+	client, _, err = krbAuth.login(broker)
+	assert.NoError(t, err)
+	assert.Same(t, clients[0], client)
+	assert.Equal(t, 1, clients[0].logins)
+	assert.Equal(t, GSS_API_INITIAL, krbAuth.step)
+
+	now = now.Add(2 * time.Hour)
+	client, _, err = krbAuth.login(broker)
+	assert.NoError(t, err)
+	assert.Len(t, clients, 2)
+	assert.Same(t, clients[1], client)
+	assert.True(t, clients[0].destroyed)
+
+	// a cached client failing to get a service ticket is replaced
+	clients[1].errorStage, clients[1].mockError = "service_ticket", errors.New("ticket revoked")
+	client, _, err = krbAuth.login(broker)
+	assert.NoError(t, err)
+	assert.Len(t, clients, 3)
+	assert.Same(t, clients[2], client)
+	assert.True(t, clients[1].destroyed)
+
+	krbAuth.destroyClient()
+	assert.True(t, clients[2].destroyed)
+	assert.Nil(t, krbAuth.client)
+}
+
+func TestGSSAPIKerberosAuthWithoutTGTTimes(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Net.SASL.GSSAPI.ServiceName = "kafka"
+	broker := &Broker{addr: "localhost:9092", conf: conf}
+
+	krbAuth := &GSSAPIKerberosAuth{
+		Config: &conf.Net.SASL.GSSAPI,
+		NewKerberosClientFunc: func(*GSSAPIConfig) (KerberosClient, error) {
+			return &MockKerberosClient{}, nil
+		},
+	}
+	first, _, err := krbAuth.login(broker)
+	assert.NoError(t, err)
+	second, _, err := krbAuth.login(broker)
+	assert.NoError(t, err)
+	assert.NotSame(t, first, second, "clients without TGT times are not cached")
+	assert.Nil(t, krbAuth.client)
+}
+
+func TestGSSAPIKerberosAuthKIP368ReAuthentication(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+
+	gssapiHandler := KafkaGSSAPIHandler{client: &MockKerberosClient{}}
+	var apReqs atomic.Int32
+	mockBroker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"SaslHandshakeRequest": func(req *request) encoderWithHeader {
+			return NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypeGSSAPI}).For(req.body)
+		},
+		"SaslAuthenticateRequest": func(req *request) encoderWithHeader {
+			authenticate := req.body.(*SaslAuthenticateRequest)
+			res := &SaslAuthenticateResponse{Version: authenticate.Version}
+			if len(authenticate.SaslAuthBytes) > 0 && authenticate.SaslAuthBytes[0] == GSS_API_GENERIC_TAG {
+				// the AP_REQ is answered with a wrap token
+				apReqs.Add(1)
+				res.SaslAuthBytes = gssapiHandler.MockKafkaGSSAPI(nil)[4:]
+			} else {
+				res.SessionLifetimeMs = 100
+			}
+			return res
+		},
+		"ApiVersionsRequest": func(req *request) encoderWithHeader {
+			return NewMockApiVersionsResponse(t).For(req.body)
+		},
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypeGSSAPI
+	conf.Net.SASL.GSSAPI.ServiceName = "kafka"
+	conf.Net.SASL.GSSAPI.KerberosConfigPath = "testdata/krb5.conf"
+	conf.Net.SASL.GSSAPI.Realm = "EXAMPLE.COM"
+	conf.Net.SASL.GSSAPI.Username = "kafka"
+	conf.Net.SASL.GSSAPI.Password = "kafka"
+	conf.Net.SASL.GSSAPI.AuthType = KRB5_USER_AUTH
+
+	var clients []*MockKerberosClient
+	broker := NewBroker(mockBroker.Addr())
+	broker.kerberosAuthenticator.NewKerberosClientFunc = func(*GSSAPIConfig) (KerberosClient, error) {
+		client := &MockKerberosClient{tgtAuthTime: time.Now(), tgtEndTime: time.Now().Add(time.Hour)}
+		clients = append(clients, client)
+		return client, nil
+	}
+	assert.NoError(t, broker.Open(conf))
+	connected, err := broker.Connected()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+
+	// the session expires after 100ms, the next request re-authenticates
+	// with the cached client
+	deadline := time.Now().Add(time.Second)
+	for apReqs.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, err = broker.ApiVersions(&ApiVersionsRequest{})
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, apReqs.Load(), int32(2), "the connection should have re-authenticated")
+
+	assert.NoError(t, broker.Close())
+	assert.Len(t, clients, 1, "re-authentications reuse the logged in client")
+	assert.Equal(t, 1, clients[0].logins)
+	assert.True(t, clients[0].destroyed, "the client is destroyed with the connection")
+}
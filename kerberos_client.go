@@ -1,15 +1,24 @@
 package sarama
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	krb5client "github.com/jcmturner/gokrb5/v8/client"
 	krb5config "github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
 
 type KerberosGoKrb5Client struct {
 	krb5client.Client
+
+	tgtAuthTime time.Time
+	tgtEndTime  time.Time
 }
 
 func (c *KerberosGoKrb5Client) Domain() string {
@@ -20,6 +29,27 @@ func (c *KerberosGoKrb5Client) CName() types.PrincipalName {
 	return c.Credentials.CName()
 }
 
+// Login obtains a TGT with the password or keytab of the client. A client
+// created from a credential cache uses the TGT of the cache instead.
+func (c *KerberosGoKrb5Client) Login() error {
+	if !c.Credentials.HasPassword() && !c.Credentials.HasKeytab() {
+		return c.Client.Login()
+	}
+	if err := c.Client.Login(); err != nil {
+		return err
+	}
+	// gokrb5 does not expose the times of the TGT it obtained, assume the
+	// KDC granted the requested lifetime
+	c.tgtAuthTime = time.Now()
+	c.tgtEndTime = c.tgtAuthTime.Add(c.Config.LibDefaults.TicketLifetime)
+	return nil
+}
+
+// TGTTimes implements KerberosTGTTimes.
+func (c *KerberosGoKrb5Client) TGTTimes() (time.Time, time.Time) {
+	return c.tgtAuthTime, c.tgtEndTime
+}
+
 // NewKerberosClient creates kerberos client used to obtain TGT and TGS tokens.
 // It uses pure go Kerberos 5 solution (RFC-4121 and RFC-4120).
 // uses gokrb5 library underlying which is a pure go kerberos client with some GSS-API capabilities.
@@ -33,6 +63,7 @@ func NewKerberosClient(config *GSSAPIConfig) (KerberosClient, error) {
 
 func createClient(config *GSSAPIConfig, cfg *krb5config.Config) (KerberosClient, error) {
 	var client *krb5client.Client
+	var tgtAuthTime, tgtEndTime time.Time
 	switch config.AuthType {
 	case KRB5_KEYTAB_AUTH:
 		kt, err := keytab.Load(config.KeyTabPath)
@@ -41,7 +72,11 @@ func createClient(config *GSSAPIConfig, cfg *krb5config.Config) (KerberosClient,
 		}
 		client = krb5client.NewWithKeytab(config.Username, config.Realm, kt, cfg, krb5client.DisablePAFXFAST(config.DisablePAFXFAST))
 	case KRB5_CCACHE_AUTH:
-		cc, err := credentials.LoadCCache(config.CCachePath)
+		path, err := kerberosCCachePath(config.CCachePath)
+		if err != nil {
+			return nil, err
+		}
+		cc, err := credentials.LoadCCache(path)
 		if err != nil {
 			return nil, err
 		}
@@ -49,9 +84,37 @@ func createClient(config *GSSAPIConfig, cfg *krb5config.Config) (KerberosClient,
 		if err != nil {
 			return nil, err
 		}
+		if tgt, ok := cc.GetEntry(types.PrincipalName{
+			NameType:   nametype.KRB_NT_SRV_INST,
+			NameString: []string{"krbtgt", cc.GetClientRealm()},
+		}); ok {
+			tgtAuthTime, tgtEndTime = tgt.AuthTime, tgt.EndTime
+		}
 	default:
 		client = krb5client.NewWithPassword(config.Username,
 			config.Realm, config.Password, cfg, krb5client.DisablePAFXFAST(config.DisablePAFXFAST))
 	}
-	return &KerberosGoKrb5Client{*client}, nil
+	return &KerberosGoKrb5Client{Client: *client, tgtAuthTime: tgtAuthTime, tgtEndTime: tgtEndTime}, nil
+}
+
+// kerberosCCachePath returns the credential cache to load: the configured
+// path, else the cache named by the KRB5CCNAME environment variable, else the
+// default cache of MIT Kerberos, /tmp/krb5cc_<uid>. Only FILE caches can be
+// read.
+func kerberosCCachePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("KRB5CCNAME")
+	}
+	if path == "" {
+		return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()), nil
+	}
+	if kind, name, ok := strings.Cut(path, ":"); ok {
+		switch kind {
+		case "FILE":
+			return name, nil
+		case "DIR", "KEYRING", "KCM", "MEMORY", "API", "MSLSA":
+			return "", fmt.Errorf("unsupported Kerberos credential cache %s, only FILE caches can be read", path)
+		}
+	}
+	return path, nil
 }
@@ -4,9 +4,12 @@ package sarama
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"testing"
 
 	krbcfg "github.com/jcmturner/gokrb5/v8/config"
+	assert "github.com/stretchr/testify/require"
 )
 
 /*
@@ -172,3 +175,23 @@ func TestCreateWithDisablePAFXFAST(t *testing.T) {
 		t.Errorf("Expected error:%s, got:%s.", err, expectedErr)
 	}
 }
+
+func TestKerberosCCachePath(t *testing.T) {
+	t.Setenv("KRB5CCNAME", "")
+	path, err := kerberosCCachePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()), path)
+
+	t.Setenv("KRB5CCNAME", "FILE:/var/run/kafka/krb5cc")
+	path, err = kerberosCCachePath("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/run/kafka/krb5cc", path)
+
+	path, err = kerberosCCachePath("/etc/kafka/krb5cc")
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/kafka/krb5cc", path, "the configured path takes precedence")
+
+	t.Setenv("KRB5CCNAME", "KEYRING:persistent:1000")
+	_, err = kerberosCCachePath("")
+	assert.ErrorContains(t, err, "only FILE caches can be read")
+}
@@ -35,6 +35,10 @@ func getMetricNameForTopic(name string, topic string) string {
 	return name + "-for-topic-" + strings.ReplaceAll(topic, ".", "_")
 }
 
+func getMetricNameForPartition(name string, topic string, partition int32) string {
+	return getMetricNameForTopic(name, topic) + "-partition-" + strconv.FormatInt(int64(partition), 10)
+}
+
 func getOrRegisterTopicMeter(name string, topic string, r metrics.Registry) metrics.Meter {
 	return metrics.GetOrRegisterMeter(getMetricNameForTopic(name, topic), r)
 }
@@ -2,6 +2,7 @@ package sarama
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -67,6 +68,9 @@ type MockBroker struct {
 	history       []RequestResponse
 	lock          sync.Mutex
 	gssApiHandler GSSApiHandlerFunc
+
+	sasl           *MockSASLConfig
+	saslPrincipals []string
 }
 
 // RequestResponse represents a Request/Response pair processed by MockBroker.
@@ -250,8 +254,18 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 		}
 	}()
 
+	// a client rejecting the broker certificate or presenting an invalid
+	// one is not a broker error, the connection is closed like Kafka does
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			Logger.Printf("*** mockbroker/%d/%d: TLS handshake failed: %v", b.brokerID, idx, err)
+			return
+		}
+	}
+
 	var bytesWritten int
 	var bytesRead int
+	var session mockSASLSession
 	for {
 		buffer, err := b.readToBytes(conn)
 		if err != nil {
@@ -279,11 +293,17 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 			}
 
 			b.lock.Lock()
-			res := b.handler(req)
+			res, handled, closeConn := b.handleSASL(&session, req)
+			if !handled {
+				res = b.handler(req)
+			}
 			b.history = append(b.history, RequestResponse{req.body, res})
 			b.lock.Unlock()
 
 			if res == nil {
+				if closeConn {
+					break
+				}
 				Logger.Printf("*** mockbroker/%d/%d: ignored %v", b.brokerID, idx, spew.Sdump(req))
 				continue
 			}
@@ -318,6 +338,9 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 				break
 			}
 			bytesWritten = len(resHeader) + len(encodedRes)
+			if closeConn {
+				break
+			}
 		} else {
 			// GSSAPI is not part of kafka protocol, but is supported for authentication proposes.
 			// Don't support history for this kind of request as is only used for test GSSAPI authentication mechanism
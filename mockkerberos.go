@@ -3,6 +3,7 @@ package sarama
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"time"
 
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
@@ -61,12 +62,20 @@ type MockKerberosClient struct {
 	credentials *credentials.Credentials
 	mockError   error
 	errorStage  string
+
+	// tgtAuthTime and tgtEndTime are returned by TGTTimes, so that the
+	// client is cached between authentications when set
+	tgtAuthTime time.Time
+	tgtEndTime  time.Time
+	logins      int
+	destroyed   bool
 }
 
 func (c *MockKerberosClient) Login() error {
 	if c.errorStage == "login" && c.mockError != nil {
 		return c.mockError
 	}
+	c.logins++
 	c.asRepBytes = "6b8202e9308202e5a003020105a10302010ba22b30293027a103020113a220041e301c301aa003020112a1131b114" +
 		"558414d504c452e434f4d636c69656e74a30d1b0b4558414d504c452e434f4da4133011a003020101a10a30081b06636c69656e7" +
 		"4a5820156618201523082014ea003020105a10d1b0b4558414d504c452e434f4da220301ea003020102a11730151b066b7262746" +
@@ -120,6 +129,10 @@ func (c *MockKerberosClient) CName() types.PrincipalName {
 	return p
 }
 
+func (c *MockKerberosClient) TGTTimes() (time.Time, time.Time) {
+	return c.tgtAuthTime, c.tgtEndTime
+}
+
 func (c *MockKerberosClient) Destroy() {
-	// Do nothing.
+	c.destroyed = true
 }
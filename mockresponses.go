@@ -1,9 +1,14 @@
 package sarama
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 // TestReporter has methods matching go's testing.T to avoid importing
@@ -138,6 +143,7 @@ type MockMetadataResponse struct {
 	errors       map[string]KError
 	leaders      map[string]map[int32]int32
 	brokers      map[string]int32
+	racks        map[int32]string
 	t            TestReporter
 }
 
@@ -146,6 +152,7 @@ func NewMockMetadataResponse(t TestReporter) *MockMetadataResponse {
 		errors:  make(map[string]KError),
 		leaders: make(map[string]map[int32]int32),
 		brokers: make(map[string]int32),
+		racks:   make(map[int32]string),
 		t:       t,
 	}
 }
@@ -170,6 +177,13 @@ func (mmr *MockMetadataResponse) SetBroker(addr string, brokerID int32) *MockMet
 	return mmr
 }
 
+// SetBrokerRack sets the rack advertised for a broker, only returned from
+// metadata version 1.
+func (mmr *MockMetadataResponse) SetBrokerRack(brokerID int32, rack string) *MockMetadataResponse {
+	mmr.racks[brokerID] = rack
+	return mmr
+}
+
 func (mmr *MockMetadataResponse) SetController(brokerID int32) *MockMetadataResponse {
 	mmr.controllerID = brokerID
 	return mmr
@@ -183,6 +197,9 @@ func (mmr *MockMetadataResponse) For(reqBody versionedDecoder) encoderWithHeader
 	}
 	for addr, brokerID := range mmr.brokers {
 		metadataResponse.AddBroker(addr, brokerID)
+		if rack, ok := mmr.racks[brokerID]; ok {
+			metadataResponse.Brokers[len(metadataResponse.Brokers)-1].rack = &rack
+		}
 	}
 
 	// Generate set of replicas
@@ -1129,6 +1146,112 @@ func (mr *MockListAclsResponse) For(reqBody versionedDecoder) encoderWithHeader
 	return res
 }
 
+// MockSaslOAuthBearerResponse is a SaslAuthenticateRequest handler acting as
+// a broker validating SASL/OAUTHBEARER initial client responses with the
+// default unsecured validator of Kafka, see NewUnsecuredJWSTokenProvider.
+// Invalid responses are answered with an error challenge, and the
+// authentication fails once the client acknowledges it, as described by
+// KIP-255.
+type MockSaslOAuthBearerResponse struct {
+	t                  TestReporter
+	lock               sync.Mutex
+	requiredScopes     []string
+	expectedExtensions map[string]string
+	sessionLifetimeMs  int64
+	challenged         bool
+	principals         []string
+}
+
+func NewMockSaslOAuthBearerResponse(t TestReporter) *MockSaslOAuthBearerResponse {
+	return &MockSaslOAuthBearerResponse{t: t}
+}
+
+// SetRequiredScopes rejects tokens that do not carry all of the scopes.
+func (m *MockSaslOAuthBearerResponse) SetRequiredScopes(scopes ...string) *MockSaslOAuthBearerResponse {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requiredScopes = scopes
+	return m
+}
+
+// SetExpectedExtensions rejects initial responses that do not carry exactly
+// the extensions.
+func (m *MockSaslOAuthBearerResponse) SetExpectedExtensions(extensions map[string]string) *MockSaslOAuthBearerResponse {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.expectedExtensions = extensions
+	return m
+}
+
+// SetSessionLifetimeMs sets the session lifetime returned on success, which
+// makes the client re-authenticate, see KIP-368.
+func (m *MockSaslOAuthBearerResponse) SetSessionLifetimeMs(sessionLifetimeMs int64) *MockSaslOAuthBearerResponse {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sessionLifetimeMs = sessionLifetimeMs
+	return m
+}
+
+// Principals returns the principals that authenticated successfully, in
+// order.
+func (m *MockSaslOAuthBearerResponse) Principals() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]string(nil), m.principals...)
+}
+
+func (m *MockSaslOAuthBearerResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*SaslAuthenticateRequest)
+	res := &SaslAuthenticateResponse{Version: req.version()}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.challenged {
+		// the client must acknowledge the error challenge with a single
+		// %x01 before the failure is reported
+		m.challenged = false
+		if !bytes.Equal(req.SaslAuthBytes, []byte{0x01}) {
+			m.t.Errorf("expected the OAUTHBEARER error challenge to be acknowledged with %%x01, got %q", req.SaslAuthBytes)
+		}
+		res.Err = ErrSASLAuthenticationFailed
+		message := "Authentication failed during authentication due to invalid credentials with SASL mechanism OAUTHBEARER"
+		res.ErrorMessage = &message
+		return res
+	}
+
+	principal, err := m.validate(req.SaslAuthBytes)
+	if err != nil {
+		m.challenged = true
+		challenge, _ := json.Marshal(map[string]string{"status": "invalid_token", "error": err.Error()})
+		res.SaslAuthBytes = challenge
+		return res
+	}
+	m.principals = append(m.principals, principal)
+	res.SessionLifetimeMs = m.sessionLifetimeMs
+	return res
+}
+
+func (m *MockSaslOAuthBearerResponse) validate(message []byte) (string, error) {
+	token, extensions, err := parseOAuthBearerClientFirstMessage(message)
+	if err != nil {
+		return "", err
+	}
+	jws, err := ParseUnsecuredJWS(token, "", "", time.Now())
+	if err != nil {
+		return "", err
+	}
+	for _, required := range m.requiredScopes {
+		if !slices.Contains(jws.Scope, required) {
+			return "", fmt.Errorf("token is missing the %s scope", required)
+		}
+	}
+	if m.expectedExtensions != nil && !maps.Equal(m.expectedExtensions, extensions) {
+		return "", fmt.Errorf("unexpected extensions %v", extensions)
+	}
+	return jws.Principal, nil
+}
+
 type MockSaslAuthenticateResponse struct {
 	t                 TestReporter
 	kerror            KError
@@ -342,6 +342,12 @@ func (pc *PartitionConsumer) HighWaterMarkOffset() int64 {
 	return pc.highWaterMarkOffset.Load()
 }
 
+// LastStableOffset implements the LastStableOffset method from the sarama.PartitionConsumer interface.
+// The mock does not model transactions, so this is always the high water mark offset.
+func (pc *PartitionConsumer) LastStableOffset() int64 {
+	return pc.highWaterMarkOffset.Load()
+}
+
 // Pause implements the Pause method from the sarama.PartitionConsumer interface.
 func (pc *PartitionConsumer) Pause() {
 	pc.l.Lock()
@@ -0,0 +1,356 @@
+package sarama
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mockSCRAMDefaultIterations is the iteration count of the SCRAM credentials
+// of a MockBroker when none is configured, the minimum accepted by Kafka.
+const mockSCRAMDefaultIterations = 4096
+
+// MockSASLConfig makes a MockBroker require SASL/PLAIN or SASL/SCRAM
+// authentication, see MockBroker.SetSASL.
+type MockSASLConfig struct {
+	// Mechanisms are the enabled mechanisms among SASLTypePlaintext,
+	// SASLTypeSCRAMSHA256 and SASLTypeSCRAMSHA512 (defaults to all of them).
+	Mechanisms []SASLMechanism
+	// Users maps the user names to their passwords.
+	Users map[string]string
+	// SCRAMIterations is the iteration count of the SCRAM credentials
+	// (defaults to 4096).
+	SCRAMIterations int
+	// SessionLifetime, when set, is sent in SaslAuthenticate responses so
+	// that clients re-authenticate (KIP-368), and connections are closed on
+	// their first request after their session expired, like brokers with
+	// connections.max.reauth.ms.
+	SessionLifetime time.Duration
+}
+
+// mockSASLSession is the authentication state of a connection to a MockBroker.
+type mockSASLSession struct {
+	mechanism     SASLMechanism
+	scram         *mockSCRAMServer
+	authenticated bool
+	expiresAt     time.Time
+}
+
+// SetSASL makes the broker require SASL authentication as configured: only
+// ApiVersions, SaslHandshake and SaslAuthenticate requests are accepted until
+// a connection authenticates, other requests close it like Kafka does. The
+// SaslHandshake and SaslAuthenticate requests are answered by the broker
+// itself, without going through the handlers. Only the v1 handshake, where
+// the SASL tokens are wrapped in SaslAuthenticate requests, is supported.
+// A nil config disables authentication.
+func (b *MockBroker) SetSASL(config *MockSASLConfig) {
+	if config != nil {
+		c := *config
+		if len(c.Mechanisms) == 0 {
+			c.Mechanisms = []SASLMechanism{SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512}
+		}
+		if c.SCRAMIterations == 0 {
+			c.SCRAMIterations = mockSCRAMDefaultIterations
+		}
+		config = &c
+	}
+	b.lock.Lock()
+	b.sasl = config
+	b.lock.Unlock()
+}
+
+// SASLPrincipals returns the users that successfully authenticated, including
+// re-authentications, in order.
+func (b *MockBroker) SASLPrincipals() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return slices.Clone(b.saslPrincipals)
+}
+
+// handleSASL answers the SASL requests of a connection and rejects the other
+// requests until it is authenticated. It returns whether the request was
+// handled, and whether the connection must be closed once the response, if
+// any, is sent. It must be called with the lock held.
+func (b *MockBroker) handleSASL(session *mockSASLSession, req *request) (res encoderWithHeader, handled, closeConn bool) {
+	config := b.sasl
+	if config == nil {
+		return nil, false, false
+	}
+
+	switch body := req.body.(type) {
+	case *ApiVersionsRequest:
+		return nil, false, false
+	case *SaslHandshakeRequest:
+		return session.handshake(config, body), true, false
+	case *SaslAuthenticateRequest:
+		res, principal := session.authenticate(config, body)
+		if principal != "" {
+			b.saslPrincipals = append(b.saslPrincipals, principal)
+		}
+		return res, true, !errors.Is(res.Err, ErrNoError)
+	}
+
+	switch {
+	case !session.authenticated:
+		Logger.Printf("*** mockbroker/%d: unexpected %T before SASL authentication, closing the connection", b.brokerID, req.body)
+		return nil, true, true
+	case !session.expiresAt.IsZero() && time.Now().After(session.expiresAt):
+		Logger.Printf("*** mockbroker/%d: SASL session expired, closing the connection", b.brokerID)
+		return nil, true, true
+	}
+	return nil, false, false
+}
+
+func (s *mockSASLSession) handshake(config *MockSASLConfig, req *SaslHandshakeRequest) *SaslHandshakeResponse {
+	res := &SaslHandshakeResponse{Version: req.Version}
+	for _, mechanism := range config.Mechanisms {
+		res.EnabledMechanisms = append(res.EnabledMechanisms, string(mechanism))
+	}
+
+	s.mechanism, s.scram = "", nil
+	switch {
+	case req.Version < 1:
+		res.Err = ErrUnsupportedVersion
+	case !slices.Contains(config.Mechanisms, SASLMechanism(req.Mechanism)):
+		res.Err = ErrUnsupportedSASLMechanism
+	default:
+		s.mechanism = SASLMechanism(req.Mechanism)
+	}
+	return res
+}
+
+// authenticate processes a SASL token and returns the response, with the
+// authenticated user once the exchange completed successfully.
+func (s *mockSASLSession) authenticate(config *MockSASLConfig, req *SaslAuthenticateRequest) (*SaslAuthenticateResponse, string) {
+	res := &SaslAuthenticateResponse{Version: req.Version}
+	fail := func(err KError, message string) (*SaslAuthenticateResponse, string) {
+		s.mechanism, s.scram = "", nil
+		res.Err = err
+		res.ErrorMessage = &message
+		return res, ""
+	}
+
+	var user string
+	switch s.mechanism {
+	case "":
+		return fail(ErrIllegalSASLState, "Unexpected SaslAuthenticate request before a SaslHandshake")
+	case SASLTypePlaintext:
+		var ok bool
+		if user, ok = mockPlainAuthenticate(config.Users, req.SaslAuthBytes); !ok {
+			return fail(ErrSASLAuthenticationFailed, "Authentication failed: Invalid username or password")
+		}
+	default:
+		if s.scram == nil {
+			s.scram = newMockSCRAMServer(s.mechanism, config)
+		}
+		challenge, err := s.scram.step(req.SaslAuthBytes)
+		if err != nil {
+			return fail(ErrSASLAuthenticationFailed, fmt.Sprintf(
+				"Authentication failed during authentication due to invalid credentials with SASL mechanism %s", s.mechanism))
+		}
+		res.SaslAuthBytes = []byte(challenge)
+		if !s.scram.done {
+			return res, ""
+		}
+		user = s.scram.user
+	}
+
+	s.mechanism, s.scram = "", nil
+	s.authenticated = true
+	s.expiresAt = time.Time{}
+	if config.SessionLifetime > 0 {
+		res.SessionLifetimeMs = config.SessionLifetime.Milliseconds()
+		s.expiresAt = time.Now().Add(config.SessionLifetime)
+	}
+	return res, user
+}
+
+// mockPlainAuthenticate checks a SASL/PLAIN message, see RFC 4616.
+func mockPlainAuthenticate(users map[string]string, message []byte) (string, bool) {
+	parts := bytes.Split(message, []byte{0})
+	if len(parts) != 3 {
+		return "", false
+	}
+	user, password := string(parts[1]), string(parts[2])
+	expected, ok := users[user]
+	if !ok || !hmac.Equal([]byte(expected), []byte(password)) {
+		return "", false
+	}
+	// like Kafka, only an authorization identity naming the user is allowed
+	if authzID := string(parts[0]); authzID != "" && authzID != user {
+		return "", false
+	}
+	return user, true
+}
+
+// mockSCRAMServer is the server side of a SCRAM exchange, see RFC 5802.
+type mockSCRAMServer struct {
+	formatter  scramFormatter
+	users      map[string]string
+	iterations int
+
+	user            string
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+	nonce           string
+	saltedPassword  []byte
+	done            bool
+}
+
+func newMockSCRAMServer(mechanism SASLMechanism, config *MockSASLConfig) *mockSCRAMServer {
+	s := &mockSCRAMServer{users: config.Users, iterations: config.SCRAMIterations}
+	s.formatter.mechanism = SCRAM_MECHANISM_SHA_256
+	if mechanism == SASLTypeSCRAMSHA512 {
+		s.formatter.mechanism = SCRAM_MECHANISM_SHA_512
+	}
+	return s
+}
+
+// step processes the client-first or client-final message and returns the
+// server-first or server-final message.
+func (s *mockSCRAMServer) step(message []byte) (string, error) {
+	if s.serverFirst == "" {
+		return s.clientFirst(string(message))
+	}
+	if s.done {
+		return "", errors.New("SCRAM exchange already completed")
+	}
+	return s.clientFinal(string(message))
+}
+
+func (s *mockSCRAMServer) clientFirst(message string) (string, error) {
+	// gs2-header: no channel binding and an optional authzid
+	cbind, rest, ok := strings.Cut(message, ",")
+	if !ok || cbind != "n" {
+		return "", errors.New("channel binding is not supported")
+	}
+	authzID, bare, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", errors.New("malformed client-first message")
+	}
+	s.gs2Header = cbind + "," + authzID + ","
+	s.clientFirstBare = bare
+
+	attributes := scramAttributes(bare)
+	user, err := scramUnescape(attributes["n"])
+	if err != nil {
+		return "", err
+	}
+	if authzID != "" && authzID != "a="+scramEscape(user) {
+		return "", errors.New("authorization identity must match the user")
+	}
+	clientNonce := attributes["r"]
+	if user == "" || clientNonce == "" {
+		return "", errors.New("client-first message must have a user and a nonce")
+	}
+	password, ok := s.users[user]
+	if !ok {
+		return "", fmt.Errorf("unknown user %s", user)
+	}
+
+	salt := make([]byte, 16)
+	serverNonce := make([]byte, 18)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(serverNonce); err != nil {
+		return "", err
+	}
+	if s.saltedPassword, err = s.formatter.saltedPassword([]byte(password), salt, s.iterations); err != nil {
+		return "", err
+	}
+
+	s.user = user
+	s.nonce = clientNonce + base64.RawStdEncoding.EncodeToString(serverNonce)
+	s.serverFirst = "r=" + s.nonce + ",s=" + base64.StdEncoding.EncodeToString(salt) + ",i=" + strconv.Itoa(s.iterations)
+	return s.serverFirst, nil
+}
+
+func (s *mockSCRAMServer) clientFinal(message string) (string, error) {
+	withoutProof, proofAttribute, ok := strings.Cut(message, ",p=")
+	if !ok {
+		return "", errors.New("client-final message must have a proof")
+	}
+	attributes := scramAttributes(withoutProof)
+	if attributes["c"] != base64.StdEncoding.EncodeToString([]byte(s.gs2Header)) {
+		return "", errors.New("invalid channel binding")
+	}
+	if attributes["r"] != s.nonce {
+		return "", errors.New("invalid nonce")
+	}
+	proof, err := base64.StdEncoding.DecodeString(proofAttribute)
+	if err != nil {
+		return "", fmt.Errorf("invalid proof: %w", err)
+	}
+
+	authMessage := []byte(s.clientFirstBare + "," + s.serverFirst + "," + withoutProof)
+	clientKey, err := s.formatter.clientKey(s.saltedPassword)
+	if err != nil {
+		return "", err
+	}
+	storedKey, err := s.formatter.storedKey(clientKey)
+	if err != nil {
+		return "", err
+	}
+	clientSignature, err := s.formatter.hmac(storedKey, authMessage)
+	if err != nil {
+		return "", err
+	}
+	if len(proof) != len(clientSignature) {
+		return "", errors.New("invalid proof")
+	}
+	// the proof is the client key masked by the client signature
+	s.formatter.xor(proof, clientSignature)
+	computedStoredKey, err := s.formatter.storedKey(proof)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(computedStoredKey, storedKey) {
+		return "", errors.New("invalid proof")
+	}
+
+	serverKey, err := s.formatter.serverKey(s.saltedPassword)
+	if err != nil {
+		return "", err
+	}
+	serverSignature, err := s.formatter.hmac(serverKey, authMessage)
+	if err != nil {
+		return "", err
+	}
+	s.done = true
+	return "v=" + base64.StdEncoding.EncodeToString(serverSignature), nil
+}
+
+// scramAttributes parses the comma separated key=value attributes of a SCRAM
+// message.
+func scramAttributes(message string) map[string]string {
+	attributes := make(map[string]string)
+	for _, attribute := range strings.Split(message, ",") {
+		if key, value, ok := strings.Cut(attribute, "="); ok {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
+// scramEscape and scramUnescape encode the ',' and '=' characters of SCRAM
+// user names, see RFC 5802 section 5.1.
+func scramEscape(name string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(name)
+}
+
+func scramUnescape(name string) (string, error) {
+	unescaped := strings.NewReplacer("=2C", ",", "=3D", "=").Replace(name)
+	if strings.Count(unescaped, "=") != strings.Count(name, "=3D") {
+		return "", errors.New("invalid escape sequence in SCRAM user name")
+	}
+	return unescaped, nil
+}
@@ -0,0 +1,214 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// testSCRAMClient is the client side of a SCRAM exchange built on
+// scramFormatter, to test the SCRAM server of MockBroker.
+type testSCRAMClient struct {
+	formatter scramFormatter
+
+	user, password, authzID string
+	clientFirstBare         string
+	serverSignature         []byte
+	step                    int
+}
+
+func (c *testSCRAMClient) Begin(user, password, authzID string) error {
+	c.user, c.password, c.authzID = user, password, authzID
+	c.step = 0
+	return nil
+}
+
+func (c *testSCRAMClient) Step(challenge string) (string, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		nonce := make([]byte, 18)
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		c.clientFirstBare = "n=" + scramEscape(c.user) + ",r=" + base64.RawStdEncoding.EncodeToString(nonce)
+		return c.gs2Header() + c.clientFirstBare, nil
+	case 2:
+		attributes := scramAttributes(challenge)
+		salt, err := base64.StdEncoding.DecodeString(attributes["s"])
+		if err != nil {
+			return "", err
+		}
+		iterations, err := strconv.Atoi(attributes["i"])
+		if err != nil {
+			return "", err
+		}
+		saltedPassword, err := c.formatter.saltedPassword([]byte(c.password), salt, iterations)
+		if err != nil {
+			return "", err
+		}
+		withoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte(c.gs2Header())) + ",r=" + attributes["r"]
+		authMessage := []byte(c.clientFirstBare + "," + challenge + "," + withoutProof)
+
+		clientKey, err := c.formatter.clientKey(saltedPassword)
+		if err != nil {
+			return "", err
+		}
+		storedKey, err := c.formatter.storedKey(clientKey)
+		if err != nil {
+			return "", err
+		}
+		clientSignature, err := c.formatter.hmac(storedKey, authMessage)
+		if err != nil {
+			return "", err
+		}
+		c.formatter.xor(clientKey, clientSignature)
+		serverKey, err := c.formatter.serverKey(saltedPassword)
+		if err != nil {
+			return "", err
+		}
+		if c.serverSignature, err = c.formatter.hmac(serverKey, authMessage); err != nil {
+			return "", err
+		}
+		return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(clientKey), nil
+	default:
+		signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(challenge, "v="))
+		if err != nil {
+			return "", err
+		}
+		if !hmac.Equal(signature, c.serverSignature) {
+			return "", errors.New("invalid server signature")
+		}
+		return "", nil
+	}
+}
+
+func (c *testSCRAMClient) Done() bool {
+	return c.step >= 3
+}
+
+func (c *testSCRAMClient) gs2Header() string {
+	if c.authzID == "" {
+		return "n,,"
+	}
+	return "n,a=" + scramEscape(c.authzID) + ","
+}
+
+var _ SCRAMClient = &testSCRAMClient{}
+
+func newMockSASLBroker(t *testing.T, config *MockSASLConfig) *MockBroker {
+	t.Helper()
+	mockBroker := NewMockBroker(t, 0)
+	t.Cleanup(mockBroker.Close)
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"MetadataRequest":    NewMockMetadataResponse(t).SetBroker(mockBroker.Addr(), mockBroker.BrokerID()),
+	})
+	mockBroker.SetSASL(config)
+	return mockBroker
+}
+
+func newMockSASLConfig(mechanism SASLMechanism, user, password string) *Config {
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Version = SASLHandshakeV1
+	conf.Net.SASL.Mechanism = mechanism
+	conf.Net.SASL.User = user
+	conf.Net.SASL.Password = password
+	switch mechanism {
+	case SASLTypeSCRAMSHA256:
+		conf.Net.SASL.SCRAMClientGeneratorFunc = func() SCRAMClient {
+			return &testSCRAMClient{formatter: scramFormatter{mechanism: SCRAM_MECHANISM_SHA_256}}
+		}
+	case SASLTypeSCRAMSHA512:
+		conf.Net.SASL.SCRAMClientGeneratorFunc = func() SCRAMClient {
+			return &testSCRAMClient{formatter: scramFormatter{mechanism: SCRAM_MECHANISM_SHA_512}}
+		}
+	}
+	return conf
+}
+
+func TestMockBrokerSASL(t *testing.T) {
+	for _, mechanism := range []SASLMechanism{SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512} {
+		t.Run(string(mechanism), func(t *testing.T) {
+			users := map[string]string{"alice": "alice-secret", "bob,=": "bob-secret"}
+			mockBroker := newMockSASLBroker(t, &MockSASLConfig{Users: users})
+
+			for _, user := range []string{"alice", "bob,="} {
+				broker := NewBroker(mockBroker.Addr())
+				assert.NoError(t, broker.Open(newMockSASLConfig(mechanism, user, users[user])))
+				_, err := broker.GetMetadata(&MetadataRequest{})
+				assert.NoError(t, err)
+				assert.NoError(t, broker.Close())
+			}
+
+			broker := NewBroker(mockBroker.Addr())
+			assert.NoError(t, broker.Open(newMockSASLConfig(mechanism, "alice", "wrong")))
+			_, err := broker.Connected()
+			assert.ErrorIs(t, err, ErrSASLAuthenticationFailed)
+			_ = broker.Close()
+
+			assert.Equal(t, []string{"alice", "bob,="}, mockBroker.SASLPrincipals())
+		})
+	}
+}
+
+func TestMockBrokerSASLUnsupportedMechanism(t *testing.T) {
+	mockBroker := newMockSASLBroker(t, &MockSASLConfig{
+		Mechanisms: []SASLMechanism{SASLTypeSCRAMSHA512},
+		Users:      map[string]string{"alice": "alice-secret"},
+	})
+
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(newMockSASLConfig(SASLTypePlaintext, "alice", "alice-secret")))
+	_, err := broker.Connected()
+	assert.ErrorIs(t, err, ErrUnsupportedSASLMechanism)
+	_ = broker.Close()
+}
+
+func TestMockBrokerSASLUnauthenticatedRequest(t *testing.T) {
+	mockBroker := newMockSASLBroker(t, &MockSASLConfig{
+		Users: map[string]string{"alice": "alice-secret"},
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(conf))
+	defer broker.Close()
+	_, err := broker.GetMetadata(&MetadataRequest{})
+	assert.Error(t, err, "the connection is closed without SASL authentication")
+	assert.Empty(t, mockBroker.SASLPrincipals())
+}
+
+func TestMockBrokerSASLReAuthentication(t *testing.T) {
+	mockBroker := newMockSASLBroker(t, &MockSASLConfig{
+		Users:           map[string]string{"alice": "alice-secret"},
+		SCRAMIterations: 1024,
+		SessionLifetime: 100 * time.Millisecond,
+	})
+
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(newMockSASLConfig(SASLTypeSCRAMSHA512, "alice", "alice-secret")))
+	defer broker.Close()
+
+	// the client re-authenticates before its session expires, otherwise the
+	// mock broker would close the connection
+	deadline := time.Now().Add(time.Second)
+	for len(mockBroker.SASLPrincipals()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		_, err := broker.GetMetadata(&MetadataRequest{})
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, len(mockBroker.SASLPrincipals()), 3, "the connection should have re-authenticated")
+}
@@ -0,0 +1,161 @@
+package sarama
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// mockTLSValidity is how long the certificates of a MockTLSCA are valid, they
+// are also backdated by an hour to tolerate clock skew.
+const mockTLSValidity = 24 * time.Hour
+
+// MockTLSCA is an ephemeral certificate authority issuing certificates for
+// TLS tests with a MockBroker, so that no certificate has to be generated
+// with external tooling or committed.
+type MockTLSCA struct {
+	// Certificate is the self-signed certificate of the CA.
+	Certificate *x509.Certificate
+
+	key    *ecdsa.PrivateKey
+	serial atomic.Int64
+}
+
+// NewMockTLSCA generates a new CA with a random key.
+func NewMockTLSCA(t TestReporter) *MockTLSCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &MockTLSCA{key: key}
+	template := ca.template("sarama mock CA")
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.Certificate, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// CertPool returns a pool trusting the CA, to be used as the RootCAs of the
+// clients or the ClientCAs of the brokers.
+func (ca *MockTLSCA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// PEM returns the PEM encoded certificate of the CA, to be written to a CA
+// file.
+func (ca *MockTLSCA) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate.Raw})
+}
+
+// IssueServerCertificate issues a broker certificate for the given host names
+// and IP addresses (defaults to localhost, 127.0.0.1 and ::1).
+func (ca *MockTLSCA) IssueServerCertificate(t TestReporter, hosts ...string) tls.Certificate {
+	t.Helper()
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+	template := ca.template(hosts[0])
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	return ca.IssueCertificate(t, template)
+}
+
+// IssueClientCertificate issues a client certificate whose subject has the
+// given common name, which brokers map to the principal User:<commonName>.
+func (ca *MockTLSCA) IssueClientCertificate(t TestReporter, commonName string) tls.Certificate {
+	t.Helper()
+	template := ca.template(commonName)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	return ca.IssueCertificate(t, template)
+}
+
+// IssueCertificate issues a certificate from a template, for tests needing
+// specific attributes such as an expired certificate. The serial number is
+// set if the template has none.
+func (ca *MockTLSCA) IssueCertificate(t TestReporter, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.SerialNumber == nil {
+		template.SerialNumber = big.NewInt(ca.serial.Add(1))
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// ClientTLSConfig returns a client configuration trusting the CA, with a
+// client certificate for commonName unless it is empty. Set it as
+// Config.Net.TLS.Config.
+func (ca *MockTLSCA) ClientTLSConfig(t TestReporter, commonName string) *tls.Config {
+	t.Helper()
+	config := &tls.Config{
+		RootCAs:    ca.CertPool(),
+		MinVersion: tls.VersionTLS12,
+	}
+	if commonName != "" {
+		config.Certificates = []tls.Certificate{ca.IssueClientCertificate(t, commonName)}
+	}
+	return config
+}
+
+func (ca *MockTLSCA) template(commonName string) *x509.Certificate {
+	now := time.Now()
+	return &x509.Certificate{
+		Subject:      pkix.Name{CommonName: commonName},
+		SerialNumber: big.NewInt(ca.serial.Add(1)),
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(mockTLSValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+}
+
+// NewMockBrokerTLS behaves like NewMockBroker but accepts TLS connections
+// with a server certificate issued by ca. With tls.RequireAndVerifyClientCert
+// or tls.VerifyClientCertIfGiven, client certificates must be issued by ca
+// as well (mTLS).
+func NewMockBrokerTLS(t TestReporter, brokerID int32, ca *MockTLSCA, clientAuth tls.ClientAuthType) *MockBroker {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "localhost:0", &tls.Config{
+		Certificates: []tls.Certificate{ca.IssueServerCertificate(t)},
+		ClientAuth:   clientAuth,
+		ClientCAs:    ca.CertPool(),
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMockBrokerListener(t, brokerID, listener)
+}
@@ -0,0 +1,90 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestMockBrokerTLS(t *testing.T) {
+	ca := NewMockTLSCA(t)
+	otherCA := NewMockTLSCA(t)
+
+	for _, test := range []struct {
+		name       string
+		clientAuth tls.ClientAuthType
+		client     *tls.Config
+		succeed    bool
+	}{
+		{"TLS", tls.NoClientCert, ca.ClientTLSConfig(t, ""), true},
+		{"mTLS", tls.RequireAndVerifyClientCert, ca.ClientTLSConfig(t, "alice"), true},
+		{"mTLS without client certificate", tls.RequireAndVerifyClientCert, ca.ClientTLSConfig(t, ""), false},
+		{"mTLS with untrusted client certificate", tls.RequireAndVerifyClientCert, &tls.Config{
+			RootCAs:      ca.CertPool(),
+			Certificates: []tls.Certificate{otherCA.IssueClientCertificate(t, "mallory")},
+			MinVersion:   tls.VersionTLS12,
+		}, false},
+		{"untrusted broker", tls.NoClientCert, otherCA.ClientTLSConfig(t, ""), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mockBroker := NewMockBrokerTLS(t, 0, ca, test.clientAuth)
+			defer mockBroker.Close()
+			mockBroker.SetHandlerByMap(map[string]MockResponse{
+				"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+			})
+
+			conf := NewTestConfig()
+			conf.Version = V1_0_0_0
+			conf.Net.TLS.Enable = true
+			conf.Net.TLS.Config = test.client
+
+			broker := NewBroker(mockBroker.Addr())
+			assert.NoError(t, broker.Open(conf))
+			defer broker.Close()
+			_, err := broker.ApiVersions(&ApiVersionsRequest{})
+			if !test.succeed {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			state, ok := broker.TLSConnectionState()
+			assert.True(t, ok)
+			assert.True(t, state.HandshakeComplete)
+			assert.Equal(t, "localhost", state.PeerCertificates[0].Subject.CommonName)
+			assert.True(t, state.VerifiedChains[0][len(state.VerifiedChains[0])-1].Equal(ca.Certificate))
+		})
+	}
+}
+
+func TestMockTLSCAIssueCertificate(t *testing.T) {
+	ca := NewMockTLSCA(t)
+
+	server := ca.IssueServerCertificate(t, "broker-1.example.com", "10.0.0.1")
+	assert.Equal(t, []string{"broker-1.example.com"}, server.Leaf.DNSNames)
+	assert.Len(t, server.Leaf.IPAddresses, 1)
+	_, err := server.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "broker-1.example.com",
+		Roots:   ca.CertPool(),
+	})
+	assert.NoError(t, err)
+
+	expired := ca.IssueCertificate(t, &x509.Certificate{
+		NotBefore:   time.Now().Add(-2 * time.Hour),
+		NotAfter:    time.Now().Add(-time.Hour),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NotEqual(t, server.Leaf.SerialNumber, expired.Leaf.SerialNumber)
+	_, err = expired.Leaf.Verify(x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	var invalid x509.CertificateInvalidError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, x509.Expired, invalid.Reason)
+}
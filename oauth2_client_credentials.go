@@ -0,0 +1,302 @@
+package sarama
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oauth2RefreshWindowFactor and oauth2RefreshWindowJitter pick when a
+	// token is refreshed, as a fraction of its lifetime, like the
+	// sasl.login.refresh.window.* settings of the Java client.
+	oauth2RefreshWindowFactor = 0.8
+	oauth2RefreshWindowJitter = 0.05
+	// oauth2DefaultTimeout bounds a token request when no timeout is set.
+	oauth2DefaultTimeout = 10 * time.Second
+	// oauth2AssertionLifetime is how long a signed client assertion is valid.
+	oauth2AssertionLifetime = 5 * time.Minute
+
+	oauth2JWTBearerAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// OAuth2ClientCredentialsConfig configures an AccessTokenProvider fetching
+// tokens with the OAuth2 client credentials grant, see
+// NewOAuth2ClientCredentialsProvider.
+type OAuth2ClientCredentialsConfig struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID identifies the client to the authorization server.
+	ClientID string
+	// ClientSecret authenticates the client with HTTP basic authentication.
+	// It is not needed when ClientAssertionKey is set.
+	ClientSecret string // #nosec G117 -- public SASL config schema; callers set this credential explicitly.
+	// ClientAssertionKey, when set, authenticates the client with a JWT
+	// assertion signed by this key instead of the client secret (RFC 7523).
+	// RSA keys sign with RS256 and P-256 ECDSA keys with ES256.
+	ClientAssertionKey crypto.Signer
+	// ClientAssertionKeyID is the optional kid header of the assertion.
+	ClientAssertionKeyID string
+	// Scopes are the optional scopes requested for the token.
+	Scopes []string
+	// EndpointParams are additional parameters sent to the token endpoint,
+	// such as an audience.
+	EndpointParams url.Values
+	// Extensions are the SASL/OAUTHBEARER extensions sent along with every
+	// token, see AccessToken.
+	Extensions map[string]string
+	// HTTPClient sends the token requests (defaults to http.DefaultClient).
+	HTTPClient *http.Client
+	// Timeout bounds each token request (defaults to 10s).
+	Timeout time.Duration
+}
+
+// oauth2ClientCredentialsProvider caches the token it fetches and refreshes
+// it once most of its lifetime has elapsed, so that connections opened
+// concurrently share a single token request.
+type oauth2ClientCredentialsProvider struct {
+	config OAuth2ClientCredentialsConfig
+	now    func() time.Time
+
+	lock      sync.Mutex
+	token     *AccessToken
+	expiresAt time.Time
+	refreshAt time.Time
+}
+
+// NewOAuth2ClientCredentialsProvider returns an AccessTokenProvider for
+// SASL/OAUTHBEARER that obtains tokens from an OAuth2 authorization server
+// with the client credentials grant (RFC 6749 section 4.4). Tokens are cached
+// and refreshed after 80 to 85% of their lifetime; if a refresh fails, the
+// cached token is used until it expires.
+func NewOAuth2ClientCredentialsProvider(config OAuth2ClientCredentialsConfig) (AccessTokenProvider, error) {
+	switch {
+	case config.TokenURL == "":
+		return nil, ConfigurationError("OAuth2ClientCredentialsConfig.TokenURL must not be empty")
+	case config.ClientID == "":
+		return nil, ConfigurationError("OAuth2ClientCredentialsConfig.ClientID must not be empty")
+	case config.ClientSecret == "" && config.ClientAssertionKey == nil:
+		return nil, ConfigurationError("OAuth2ClientCredentialsConfig requires a ClientSecret or a ClientAssertionKey")
+	case config.Timeout < 0:
+		return nil, ConfigurationError("OAuth2ClientCredentialsConfig.Timeout must be >= 0")
+	}
+	if _, ok := config.Extensions[SASLExtKeyAuth]; ok {
+		return nil, ConfigurationError(fmt.Sprintf("OAuth2ClientCredentialsConfig.Extensions must not contain the `%s` extension", SASLExtKeyAuth))
+	}
+	if config.ClientAssertionKey != nil {
+		if _, err := jwtAlgorithm(config.ClientAssertionKey); err != nil {
+			return nil, err
+		}
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Timeout == 0 {
+		config.Timeout = oauth2DefaultTimeout
+	}
+	return &oauth2ClientCredentialsProvider{config: config, now: time.Now}, nil
+}
+
+// Token returns the cached token, fetching a new one when it is due for a
+// refresh.
+func (p *oauth2ClientCredentialsProvider) Token() (*AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	if p.token != nil && (p.refreshAt.IsZero() || now.Before(p.refreshAt)) {
+		return p.token, nil
+	}
+
+	token, lifetime, err := p.fetch()
+	if err != nil {
+		if p.token != nil && now.Before(p.expiresAt) {
+			Logger.Printf("Failed to refresh the OAuth2 access token, using the current one until it expires at %s: %v\n", p.expiresAt.Format(time.RFC3339), err)
+			return p.token, nil
+		}
+		return nil, err
+	}
+
+	// a token without an expiry is never refreshed
+	p.token = &AccessToken{Token: token, Extensions: p.config.Extensions}
+	p.expiresAt, p.refreshAt = time.Time{}, time.Time{}
+	if lifetime > 0 {
+		window := oauth2RefreshWindowFactor + mathrand.Float64()*oauth2RefreshWindowJitter
+		p.expiresAt = now.Add(lifetime)
+		p.refreshAt = now.Add(time.Duration(float64(lifetime) * window))
+	}
+	return p.token, nil
+}
+
+// fetch requests a new token and returns it with its lifetime, or 0 if the
+// server did not say when it expires.
+func (p *oauth2ClientCredentialsProvider) fetch() (string, time.Duration, error) {
+	form := url.Values{}
+	for k, v := range p.config.EndpointParams {
+		form[k] = v
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(p.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.config.Scopes, " "))
+	}
+	if p.config.ClientAssertionKey != nil {
+		assertion, err := p.clientAssertion()
+		if err != nil {
+			return "", 0, err
+		}
+		form.Set("client_id", p.config.ClientID)
+		form.Set("client_assertion_type", oauth2JWTBearerAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientAssertionKey == nil {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request an OAuth2 access token: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read the OAuth2 token response: %w", err)
+	}
+
+	var response struct {
+		AccessToken      string          `json:"access_token"`
+		ExpiresIn        json.RawMessage `json:"expires_in"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil && res.StatusCode == http.StatusOK {
+		return "", 0, fmt.Errorf("failed to parse the OAuth2 token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK || response.Error != "" {
+		if response.Error == "" {
+			return "", 0, fmt.Errorf("OAuth2 token endpoint returned %s", res.Status)
+		}
+		if response.ErrorDescription != "" {
+			return "", 0, fmt.Errorf("OAuth2 token endpoint returned %s: %s", response.Error, response.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("OAuth2 token endpoint returned %s", response.Error)
+	}
+	if response.AccessToken == "" {
+		return "", 0, errors.New("OAuth2 token response has no access_token")
+	}
+
+	// some servers send expires_in as a string
+	var expiresIn json.Number
+	if len(response.ExpiresIn) > 0 {
+		if err := json.Unmarshal(response.ExpiresIn, &expiresIn); err != nil {
+			var s string
+			if json.Unmarshal(response.ExpiresIn, &s) == nil {
+				expiresIn = json.Number(s)
+			}
+		}
+	}
+	seconds, _ := expiresIn.Int64()
+	return response.AccessToken, time.Duration(seconds) * time.Second, nil
+}
+
+// clientAssertion signs a JWT identifying the client to the token endpoint,
+// see RFC 7523 section 3.
+func (p *oauth2ClientCredentialsProvider) clientAssertion() (string, error) {
+	algorithm, err := jwtAlgorithm(p.config.ClientAssertionKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": algorithm, "typ": "JWT"}
+	if p.config.ClientAssertionKeyID != "" {
+		header["kid"] = p.config.ClientAssertionKeyID
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := p.now()
+	claims := map[string]any{
+		"iss": p.config.ClientID,
+		"sub": p.config.ClientID,
+		"aud": p.config.TokenURL,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"exp": now.Add(oauth2AssertionLifetime).Unix(),
+	}
+
+	encode := func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b), err
+	}
+	encodedHeader, err := encode(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encode(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := p.config.ClientAssertionKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the OAuth2 client assertion: %w", err)
+	}
+	if algorithm == "ES256" {
+		// JWS wants the raw r || s pair rather than ASN.1
+		if signature, err = ecdsaRawSignature(signature); err != nil {
+			return "", err
+		}
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func jwtAlgorithm(key crypto.Signer) (string, error) {
+	switch public := key.Public().(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		if public.Curve.Params().BitSize == 256 {
+			return "ES256", nil
+		}
+	}
+	return "", ConfigurationError("OAuth2ClientCredentialsConfig.ClientAssertionKey must be an RSA or a P-256 ECDSA key")
+}
+
+func ecdsaRawSignature(der []byte) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("failed to decode the ECDSA signature: %w", err)
+	}
+	raw := make([]byte, 64)
+	sig.R.FillBytes(raw[:32])
+	sig.S.FillBytes(raw[32:])
+	return raw, nil
+}
@@ -0,0 +1,246 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// oauth2TestServer is a token endpoint handing out numbered tokens.
+type oauth2TestServer struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	requests  []url.Values
+	users     []string
+	expiresIn any
+	fail      bool
+}
+
+func newOAuth2TestServer(t *testing.T) *oauth2TestServer {
+	s := &oauth2TestServer{expiresIn: 3600}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, r.ParseForm())
+		user, _, _ := r.BasicAuth()
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.requests = append(s.requests, r.PostForm)
+		s.users = append(s.users, user)
+
+		w.Header().Set("Content-Type", "application/json")
+		if s.fail {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"unknown client"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "token-" + string(rune('0'+len(s.requests))),
+			"token_type":   "Bearer",
+			"expires_in":   s.expiresIn,
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *oauth2TestServer) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.requests)
+}
+
+func newTestOAuth2Provider(t *testing.T, config OAuth2ClientCredentialsConfig, now *time.Time) *oauth2ClientCredentialsProvider {
+	provider, err := NewOAuth2ClientCredentialsProvider(config)
+	assert.NoError(t, err)
+	p := provider.(*oauth2ClientCredentialsProvider)
+	p.now = func() time.Time { return *now }
+	return p
+}
+
+func TestOAuth2ClientCredentialsProvider(t *testing.T) {
+	server := newOAuth2TestServer(t)
+	now := time.Now()
+	provider := newTestOAuth2Provider(t, OAuth2ClientCredentialsConfig{
+		TokenURL:       server.URL,
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		Scopes:         []string{"kafka", "read"},
+		EndpointParams: url.Values{"audience": {"cluster"}},
+		Extensions:     map[string]string{"logicalCluster": "lkc-1"},
+	}, &now)
+
+	token, err := provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, &AccessToken{Token: "token-1", Extensions: map[string]string{"logicalCluster": "lkc-1"}}, token)
+
+	server.lock.Lock()
+	assert.Equal(t, "client", server.users[0])
+	assert.Equal(t, "client_credentials", server.requests[0].Get("grant_type"))
+	assert.Equal(t, "kafka read", server.requests[0].Get("scope"))
+	assert.Equal(t, "cluster", server.requests[0].Get("audience"))
+	server.lock.Unlock()
+
+	// cached until 80-85% of the hour has elapsed
+	now = now.Add(47 * time.Minute)
+	token, err = provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token.Token)
+	assert.Equal(t, 1, server.count())
+
+	now = now.Add(4 * time.Minute)
+	token, err = provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token.Token)
+	assert.Equal(t, 2, server.count())
+}
+
+func TestOAuth2ClientCredentialsProviderRefreshFailure(t *testing.T) {
+	server := newOAuth2TestServer(t)
+	server.expiresIn = "60" // some servers send strings
+	now := time.Now()
+	provider := newTestOAuth2Provider(t, OAuth2ClientCredentialsConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}, &now)
+
+	_, err := provider.Token()
+	assert.NoError(t, err)
+
+	server.lock.Lock()
+	server.fail = true
+	server.lock.Unlock()
+
+	// the current token is still valid while the refresh fails
+	now = now.Add(55 * time.Second)
+	token, err := provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", token.Token)
+
+	now = now.Add(10 * time.Second)
+	_, err = provider.Token()
+	assert.ErrorContains(t, err, "invalid_client: unknown client")
+}
+
+func TestOAuth2ClientCredentialsProviderConcurrentConnections(t *testing.T) {
+	server := newOAuth2TestServer(t)
+	provider, err := NewOAuth2ClientCredentialsProvider(OAuth2ClientCredentialsConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := provider.Token()
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token.Token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, server.count())
+}
+
+func TestOAuth2ClientCredentialsProviderClientAssertion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+		server := newOAuth2TestServer(t)
+		provider, err := NewOAuth2ClientCredentialsProvider(OAuth2ClientCredentialsConfig{
+			TokenURL:             server.URL,
+			ClientID:             "client",
+			ClientAssertionKey:   key,
+			ClientAssertionKeyID: "key-1",
+		})
+		assert.NoError(t, err)
+		_, err = provider.Token()
+		assert.NoError(t, err)
+
+		server.lock.Lock()
+		form := server.requests[0]
+		assert.Empty(t, server.users[0], "no basic auth with a client assertion")
+		server.lock.Unlock()
+		assert.Equal(t, "client", form.Get("client_id"))
+		assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", form.Get("client_assertion_type"))
+
+		parts := strings.Split(form.Get("client_assertion"), ".")
+		assert.Len(t, parts, 3)
+		var header map[string]string
+		var claims map[string]any
+		decodeJWTPart(t, parts[0], &header)
+		decodeJWTPart(t, parts[1], &claims)
+		assert.Equal(t, "key-1", header["kid"])
+		assert.Equal(t, "client", claims["iss"])
+		assert.Equal(t, "client", claims["sub"])
+		assert.Equal(t, server.URL, claims["aud"])
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			assert.Equal(t, "RS256", header["alg"])
+			assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+		case *ecdsa.PrivateKey:
+			assert.Equal(t, "ES256", header["alg"])
+			assert.Len(t, signature, 64)
+			r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+			assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+		}
+	}
+}
+
+func decodeJWTPart(t *testing.T, part string, v any) {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, v))
+}
+
+func TestOAuth2ClientCredentialsConfigValidation(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		config OAuth2ClientCredentialsConfig
+		err    string
+	}{
+		{OAuth2ClientCredentialsConfig{ClientID: "c", ClientSecret: "s"}, "OAuth2ClientCredentialsConfig.TokenURL must not be empty"},
+		{OAuth2ClientCredentialsConfig{TokenURL: "http://x", ClientSecret: "s"}, "OAuth2ClientCredentialsConfig.ClientID must not be empty"},
+		{OAuth2ClientCredentialsConfig{TokenURL: "http://x", ClientID: "c"}, "OAuth2ClientCredentialsConfig requires a ClientSecret or a ClientAssertionKey"},
+		{OAuth2ClientCredentialsConfig{TokenURL: "http://x", ClientID: "c", ClientSecret: "s", Timeout: -1}, "OAuth2ClientCredentialsConfig.Timeout must be >= 0"},
+		{OAuth2ClientCredentialsConfig{TokenURL: "http://x", ClientID: "c", ClientSecret: "s", Extensions: map[string]string{"auth": "x"}}, "OAuth2ClientCredentialsConfig.Extensions must not contain the `auth` extension"},
+		{OAuth2ClientCredentialsConfig{TokenURL: "http://x", ClientID: "c", ClientAssertionKey: p384}, "OAuth2ClientCredentialsConfig.ClientAssertionKey must be an RSA or a P-256 ECDSA key"},
+	} {
+		_, err := NewOAuth2ClientCredentialsProvider(test.config)
+		var target ConfigurationError
+		assert.ErrorAs(t, err, &target)
+		assert.Equal(t, test.err, string(target))
+	}
+}
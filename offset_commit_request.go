@@ -93,22 +93,22 @@ func NewOffsetCommitRequest(conf *Config, group string) *OffsetCommitRequest {
 		ConsumerGroupGeneration: GroupGenerationUndefined,
 	}
 
-	if conf.Version.IsAtLeast(V2_4_0_0) {
+	if conf.version().IsAtLeast(V2_4_0_0) {
 		// Version 8 is the first flexible version.
 		request.Version = 8
-	} else if conf.Version.IsAtLeast(V2_3_0_0) {
+	} else if conf.version().IsAtLeast(V2_3_0_0) {
 		// Version 7 adds GroupInstanceId.
 		request.Version = 7
-	} else if conf.Version.IsAtLeast(V2_1_0_0) {
+	} else if conf.version().IsAtLeast(V2_1_0_0) {
 		// Version 6 adds committed leader epoch (version 5 removes retention time).
 		request.Version = 6
-	} else if conf.Version.IsAtLeast(V2_0_0_0) {
+	} else if conf.version().IsAtLeast(V2_0_0_0) {
 		// Version 4 is the same as version 2.
 		request.Version = 4
-	} else if conf.Version.IsAtLeast(V0_11_0_0) {
+	} else if conf.version().IsAtLeast(V0_11_0_0) {
 		// Version 3 is the same as version 2.
 		request.Version = 3
-	} else if conf.Version.IsAtLeast(V0_9_0_0) {
+	} else if conf.version().IsAtLeast(V0_9_0_0) {
 		// Version 2 adds retention time and removes the commit timestamp from version 1.
 		request.Version = 2
 	} else {
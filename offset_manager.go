@@ -155,7 +155,7 @@ func (om *offsetManager) fetchInitialOffset(topic string, partition int32, retri
 	}
 
 	partitions := map[string][]int32{topic: {partition}}
-	req := NewOffsetFetchRequest(om.conf.Version, om.group, partitions)
+	req := NewOffsetFetchRequest(om.conf.version(), om.group, partitions)
 	resp, err := broker.FetchOffset(req)
 	if err != nil {
 		if retries <= 0 {
@@ -313,29 +313,29 @@ func (om *offsetManager) constructRequest() *OffsetCommitRequest {
 	// Version 1 adds timestamp and group membership information, as well as the commit timestamp.
 	//
 	// Version 2 adds retention time.  It removes the commit timestamp added in version 1.
-	if om.conf.Version.IsAtLeast(V0_9_0_0) {
+	if om.conf.version().IsAtLeast(V0_9_0_0) {
 		r.Version = 2
 	}
 	// Version 3 and 4 are the same as version 2.
-	if om.conf.Version.IsAtLeast(V0_11_0_0) {
+	if om.conf.version().IsAtLeast(V0_11_0_0) {
 		r.Version = 3
 	}
-	if om.conf.Version.IsAtLeast(V2_0_0_0) {
+	if om.conf.version().IsAtLeast(V2_0_0_0) {
 		r.Version = 4
 	}
 	// Version 5 removes the retention time, which is now controlled only by a broker configuration.
 	//
 	// Version 6 adds the leader epoch for fencing.
-	if om.conf.Version.IsAtLeast(V2_1_0_0) {
+	if om.conf.version().IsAtLeast(V2_1_0_0) {
 		r.Version = 6
 	}
 	// version 7 adds a new field called groupInstanceId to indicate member identity across restarts.
-	if om.conf.Version.IsAtLeast(V2_3_0_0) {
+	if om.conf.version().IsAtLeast(V2_3_0_0) {
 		r.Version = 7
 		r.GroupInstanceId = om.groupInstanceId
 	}
 	// Version 8 is the first flexible version.
-	if om.conf.Version.IsAtLeast(V2_4_0_0) {
+	if om.conf.version().IsAtLeast(V2_4_0_0) {
 		r.Version = 8
 	}
 
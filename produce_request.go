@@ -32,6 +32,25 @@ func (r *ProduceRequest) setVersion(v int16) {
 	r.Version = v
 }
 
+// minEncodableVersion is 3 when the request carries record batches, which
+// older versions cannot hold, and 7 when a batch is compressed with ZSTD,
+// which brokers reject below that version.
+func (r *ProduceRequest) minEncodableVersion() int16 {
+	var version int16
+	for _, partitions := range r.records {
+		for _, records := range partitions {
+			if records.recordsType != defaultRecords {
+				continue
+			}
+			if records.RecordBatch != nil && records.RecordBatch.Codec == CompressionZSTD {
+				return 7
+			}
+			version = 3
+		}
+	}
+	return version
+}
+
 func updateMsgSetMetrics(msgSet *MessageSet, compressionRatioMetric metrics.Histogram,
 	topicCompressionRatioMetric metrics.Histogram,
 ) int64 {
@@ -68,7 +68,7 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 
 	set := partitions[msg.Partition]
 	if set == nil {
-		if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
+		if ps.parent.conf.version().IsAtLeast(V0_11_0_0) {
 			batch := &RecordBatch{
 				FirstTimestamp:   timestamp,
 				Version:          2,
@@ -88,7 +88,7 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 		partitions[msg.Partition] = set
 	}
 
-	if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V0_11_0_0) {
 		if ps.parent.conf.Producer.Idempotent && msg.sequenceNumber < set.recordsToSend.RecordBatch.FirstSequence {
 			return errors.New("assertion failed: message out of sequence added to a batch")
 		}
@@ -97,7 +97,7 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 	// Past this point we can't return an error, because we've already added the message to the set.
 	set.msgs = append(set.msgs, msg)
 
-	if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V0_11_0_0) {
 		// We are being conservative here to avoid having to prep encode the record
 		size += maximumRecordOverhead
 		rec := &Record{
@@ -116,7 +116,7 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 		set.recordsToSend.RecordBatch.addRecord(rec)
 	} else {
 		msgToSend := &Message{Codec: CompressionNone, Key: key, Value: val}
-		if ps.parent.conf.Version.IsAtLeast(V0_10_0_0) {
+		if ps.parent.conf.version().IsAtLeast(V0_10_0_0) {
 			msgToSend.Timestamp = timestamp
 			msgToSend.Version = 1
 		}
@@ -182,31 +182,31 @@ func (ps *produceSet) buildRequest() *ProduceRequest {
 		RequiredAcks: ps.parent.conf.Producer.RequiredAcks,
 		Timeout:      int32(ps.parent.conf.Producer.Timeout / time.Millisecond),
 	}
-	if ps.parent.conf.Version.IsAtLeast(V0_10_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V0_10_0_0) {
 		req.Version = 2
 	}
-	if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V0_11_0_0) {
 		req.Version = 3
 		if ps.parent.IsTransactional() {
 			req.TransactionalID = &ps.parent.conf.Producer.Transaction.ID
 		}
 	}
-	if ps.parent.conf.Version.IsAtLeast(V1_0_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V1_0_0_0) {
 		req.Version = 5
 	}
-	if ps.parent.conf.Version.IsAtLeast(V2_0_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V2_0_0_0) {
 		req.Version = 6
 	}
-	if ps.parent.conf.Version.IsAtLeast(V2_1_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V2_1_0_0) {
 		req.Version = 7
 	}
-	if ps.parent.conf.Version.IsAtLeast(V2_4_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V2_4_0_0) {
 		req.Version = 8
 	}
-	if ps.parent.conf.Version.IsAtLeast(V2_8_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V2_8_0_0) {
 		req.Version = 9
 	}
-	if ps.parent.conf.Version.IsAtLeast(V3_7_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V3_7_0_0) {
 		req.Version = 10
 	}
 
@@ -246,7 +246,7 @@ func (ps *produceSet) buildRequest() *ProduceRequest {
 				// set and no key. When the server sees a message with a compression codec, it
 				// decompresses the payload and treats the result as its message set.
 
-				if ps.parent.conf.Version.IsAtLeast(V0_10_0_0) {
+				if ps.parent.conf.version().IsAtLeast(V0_10_0_0) {
 					// If our version is 0.10 or later, assign relative offsets
 					// to the inner messages. This lets the broker avoid
 					// recompressing the message set.
@@ -268,7 +268,7 @@ func (ps *produceSet) buildRequest() *ProduceRequest {
 					Value:            payload,
 					Set:              set.recordsToSend.MsgSet, // Provide the underlying message set for accurate metrics
 				}
-				if ps.parent.conf.Version.IsAtLeast(V0_10_0_0) {
+				if ps.parent.conf.version().IsAtLeast(V0_10_0_0) {
 					compMsg.Version = 1
 					compMsg.Timestamp = set.recordsToSend.MsgSet.Messages[0].Msg.Timestamp
 				}
@@ -315,7 +315,7 @@ func (ps *produceSet) dropPartition(topic string, partition int32) []*ProducerMe
 
 func (ps *produceSet) wouldOverflow(msg *ProducerMessage) bool {
 	version := 1
-	if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
+	if ps.parent.conf.version().IsAtLeast(V0_11_0_0) {
 		version = 2
 	}
 
@@ -0,0 +1,275 @@
+package sarama
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyRule selects the proxy used to connect to some brokers, see
+// Config.Net.Proxy.Rules. A broker matches a rule if it matches both its Hosts
+// and its Racks, an empty list matching every broker.
+type ProxyRule struct {
+	// Hosts matches the advertised host of the brokers: a host name or IP
+	// address, a domain suffix starting with a dot (".eu.example.com") or a
+	// CIDR block ("10.0.0.0/8").
+	Hosts []string
+	// Racks matches the racks of the brokers. Bootstrap brokers, whose rack is
+	// not known yet, never match a rule with Racks.
+	Racks []string
+	// URL is the proxy used for the matching brokers, see
+	// Config.Net.Proxy.URL. Empty means connecting directly.
+	URL string
+}
+
+func (r *ProxyRule) matches(endpoint BrokerEndpoint) bool {
+	if len(r.Racks) > 0 && (endpoint.Rack == "" || !slices.Contains(r.Racks, endpoint.Rack)) {
+		return false
+	}
+	if len(r.Hosts) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(endpoint.Addr)
+	if err != nil {
+		host = endpoint.Addr
+	}
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, pattern := range r.Hosts {
+		pattern = strings.ToLower(pattern)
+		switch {
+		case strings.HasPrefix(pattern, "."):
+			if strings.HasSuffix(host, pattern) {
+				return true
+			}
+		case strings.Contains(pattern, "/"):
+			if _, network, err := net.ParseCIDR(pattern); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		case host == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// validateProxy checks the Net.Proxy settings.
+func (c *Config) validateProxy() error {
+	if !c.Net.Proxy.Enable {
+		return nil
+	}
+	if c.Net.Proxy.Dialer == nil && c.Net.Proxy.URL == "" && len(c.Net.Proxy.Rules) == 0 {
+		return ConfigurationError("Net.Proxy.Enable requires a Net.Proxy.Dialer, URL or Rules")
+	}
+	if c.Net.Proxy.Dialer != nil && c.Net.Proxy.URL != "" {
+		return ConfigurationError("Net.Proxy.Dialer and Net.Proxy.URL are mutually exclusive")
+	}
+	if _, err := parseProxyURL(c.Net.Proxy.URL); err != nil {
+		return ConfigurationError(fmt.Sprintf("Net.Proxy.URL is invalid: %v", err))
+	}
+	for i, rule := range c.Net.Proxy.Rules {
+		if len(rule.Hosts) == 0 && len(rule.Racks) == 0 {
+			return ConfigurationError(fmt.Sprintf("Net.Proxy.Rules[%d] must have Hosts or Racks", i))
+		}
+		for _, host := range rule.Hosts {
+			if strings.Contains(host, "/") {
+				if _, _, err := net.ParseCIDR(host); err != nil {
+					return ConfigurationError(fmt.Sprintf("Net.Proxy.Rules[%d].Hosts is invalid: %v", i, err))
+				}
+			}
+		}
+		if _, err := parseProxyURL(rule.URL); err != nil {
+			return ConfigurationError(fmt.Sprintf("Net.Proxy.Rules[%d].URL is invalid: %v", i, err))
+		}
+	}
+	return nil
+}
+
+// parseProxyURL parses and checks a proxy URL, nil if it is empty.
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected socks5 or http", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy address %q must be host:port", u.Host)
+	}
+	return u, nil
+}
+
+// proxyDialer returns the dialer used to connect to a broker according to
+// the Net.Proxy settings. The rules match the advertised address of the
+// broker, even if a BrokerResolver rewrites the address to dial.
+func (c *Config) proxyDialer(endpoint BrokerEndpoint) (proxy.Dialer, error) {
+	direct := c.directDialer()
+	if !c.Net.Proxy.Enable {
+		return direct, nil
+	}
+	rawURL := c.Net.Proxy.URL
+	matched := false
+	for i := range c.Net.Proxy.Rules {
+		if rule := &c.Net.Proxy.Rules[i]; rule.matches(endpoint) {
+			rawURL, matched = rule.URL, true
+			break
+		}
+	}
+	if !matched && c.Net.Proxy.Dialer != nil {
+		return c.Net.Proxy.Dialer, nil
+	}
+
+	u, err := parseProxyURL(rawURL)
+	if err != nil || u == nil {
+		return direct, err
+	}
+	DebugLogger.Printf("broker/%d connecting to %s through the %s proxy %s\n", endpoint.ID, endpoint.Addr, u.Scheme, u.Host)
+	var user, password string
+	if u.User != nil {
+		user = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	if u.Scheme == "http" {
+		return &httpConnectDialer{
+			addr:     u.Host,
+			user:     user,
+			password: password,
+			timeout:  c.Net.DialTimeout,
+			forward:  direct,
+		}, nil
+	}
+	var auth *proxy.Auth
+	if u.User != nil {
+		auth = &proxy.Auth{User: user, Password: password}
+	}
+	socks, err := proxy.SOCKS5("tcp", u.Host, auth, direct)
+	if err != nil {
+		return nil, err
+	}
+	if contextDialer, ok := socks.(proxy.ContextDialer); ok {
+		return &timeoutDialer{dialer: contextDialer, timeout: c.Net.DialTimeout}, nil
+	}
+	return socks, nil
+}
+
+// timeoutDialer bounds the handshake with a proxy by the dial timeout, as
+// Dial is called without a context.
+type timeoutDialer struct {
+	dialer  proxy.ContextDialer
+	timeout time.Duration
+}
+
+func (d *timeoutDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *timeoutDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	return d.dialer.DialContext(ctx, network, addr)
+}
+
+// httpConnectDialer tunnels connections through an HTTP proxy with the
+// CONNECT method, see RFC 9110 section 9.3.6.
+type httpConnectDialer struct {
+	addr           string
+	user, password string
+	timeout        time.Duration
+	forward        proxy.Dialer
+}
+
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("unsupported network %s through an HTTP proxy", network)
+	}
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	if forward, ok := d.forward.(proxy.ContextDialer); ok {
+		conn, err = forward.DialContext(ctx, "tcp", d.addr)
+	} else {
+		conn, err = d.forward.Dial("tcp", d.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the HTTP proxy %s: %w", d.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	tunnel, err := d.connect(conn, addr)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+func (d *httpConnectDialer) connect(conn net.Conn, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.user != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.user + ":" + d.password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send the CONNECT request to the HTTP proxy %s: %w", d.addr, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CONNECT response of the HTTP proxy %s: %w", d.addr, err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP proxy %s failed to connect to %s: %s", d.addr, addr, res.Status)
+	}
+	if reader.Buffered() > 0 {
+		// data sent by the broker right after the tunnel was established
+		return &bufferedProxyConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedProxyConn reads the data buffered while reading the response of a
+// proxy before reading from the connection.
+type bufferedProxyConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedProxyConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
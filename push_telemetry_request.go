@@ -0,0 +1,93 @@
+package sarama
+
+// PushTelemetry Request (Version: 0) => client_instance_id subscription_id terminating compression_type metrics _tagged_fields
+//   client_instance_id => UUID
+//   subscription_id => INT32
+//   terminating => BOOLEAN
+//   compression_type => INT8
+//   metrics => COMPACT_BYTES
+
+// PushTelemetryRequest pushes client metrics, encoded as OTLP protobuf, to a
+// broker, see KIP-714.
+type PushTelemetryRequest struct {
+	Version int16
+
+	ClientInstanceID Uuid
+	SubscriptionID   int32
+	// Terminating is set on the last push before the client shuts down.
+	Terminating     bool
+	CompressionType CompressionCodec
+	// Metrics holds the compressed OTLP MetricsData message.
+	Metrics []byte
+}
+
+func (r *PushTelemetryRequest) setVersion(v int16) {
+	r.Version = v
+}
+
+func (r *PushTelemetryRequest) encode(pe packetEncoder) error {
+	if err := pe.putUuid(r.ClientInstanceID); err != nil {
+		return err
+	}
+	pe.putInt32(r.SubscriptionID)
+	pe.putBool(r.Terminating)
+	pe.putInt8(int8(r.CompressionType))
+	if err := pe.putBytes(r.Metrics); err != nil {
+		return err
+	}
+
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *PushTelemetryRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ClientInstanceID, err = pd.getUuid(); err != nil {
+		return err
+	}
+	if r.SubscriptionID, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.Terminating, err = pd.getBool(); err != nil {
+		return err
+	}
+	codec, err := pd.getInt8()
+	if err != nil {
+		return err
+	}
+	r.CompressionType = CompressionCodec(codec)
+	if r.Metrics, err = pd.getBytes(); err != nil {
+		return err
+	}
+
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *PushTelemetryRequest) key() int16 {
+	return apiKeyPushTelemetry
+}
+
+func (r *PushTelemetryRequest) version() int16 {
+	return r.Version
+}
+
+func (r *PushTelemetryRequest) headerVersion() int16 {
+	return 2
+}
+
+func (r *PushTelemetryRequest) isValidVersion() bool {
+	return r.Version == 0
+}
+
+func (r *PushTelemetryRequest) isFlexible() bool {
+	return r.isFlexibleVersion(r.Version)
+}
+
+func (r *PushTelemetryRequest) isFlexibleVersion(version int16) bool {
+	return version >= 0
+}
+
+func (r *PushTelemetryRequest) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
@@ -0,0 +1,26 @@
+//go:build !functional
+
+package sarama
+
+import "testing"
+
+var pushTelemetryRequestV0 = []byte{
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+	0, 0, 0, 7, // subscription id
+	1,                   // terminating
+	1,                   // compression type
+	4, 0xca, 0xfe, 0x00, // metrics
+	0, // empty tagged fields
+}
+
+func TestPushTelemetryRequest(t *testing.T) {
+	request := &PushTelemetryRequest{
+		ClientInstanceID: Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		SubscriptionID:   7,
+		Terminating:      true,
+		CompressionType:  CompressionGZIP,
+		Metrics:          []byte{0xca, 0xfe, 0x00},
+	}
+
+	testRequest(t, "v0", request, pushTelemetryRequestV0)
+}
@@ -0,0 +1,71 @@
+package sarama
+
+import "time"
+
+// PushTelemetry Response (Version: 0) => throttle_time_ms error_code _tagged_fields
+//   throttle_time_ms => INT32
+//   error_code => INT16
+
+type PushTelemetryResponse struct {
+	Version int16
+
+	ThrottleTimeMs int32
+	Err            KError
+}
+
+func (r *PushTelemetryResponse) setVersion(v int16) {
+	r.Version = v
+}
+
+func (r *PushTelemetryResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putKError(r.Err)
+
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *PushTelemetryResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.Err, err = pd.getKError(); err != nil {
+		return err
+	}
+
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *PushTelemetryResponse) key() int16 {
+	return apiKeyPushTelemetry
+}
+
+func (r *PushTelemetryResponse) version() int16 {
+	return r.Version
+}
+
+func (r *PushTelemetryResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *PushTelemetryResponse) isValidVersion() bool {
+	return r.Version == 0
+}
+
+func (r *PushTelemetryResponse) isFlexible() bool {
+	return r.isFlexibleVersion(r.Version)
+}
+
+func (r *PushTelemetryResponse) isFlexibleVersion(version int16) bool {
+	return version >= 0
+}
+
+func (r *PushTelemetryResponse) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
+
+func (r *PushTelemetryResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
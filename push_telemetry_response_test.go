@@ -0,0 +1,20 @@
+//go:build !functional
+
+package sarama
+
+import "testing"
+
+var pushTelemetryResponseV0 = []byte{
+	0, 0, 0, 100, // throttle time
+	0, 117, // error code
+	0, // empty tagged fields
+}
+
+func TestPushTelemetryResponse(t *testing.T) {
+	response := &PushTelemetryResponse{
+		ThrottleTimeMs: 100,
+		Err:            ErrUnknownSubscriptionId,
+	}
+
+	testResponse(t, "v0", response, pushTelemetryResponseV0)
+}
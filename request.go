@@ -240,6 +240,10 @@ func allocateBody(key, version int16) protocolBody {
 		return &ListTransactionsRequest{Version: version}
 		// 67: AllocateProducerIdsRequest
 		// 68: ConsumerGroupHeartbeatRequest
+	case apiKeyGetTelemetrySubscriptions:
+		return &GetTelemetrySubscriptionsRequest{Version: version}
+	case apiKeyPushTelemetry:
+		return &PushTelemetryRequest{Version: version}
 	}
 	return nil
 }
@@ -345,6 +349,10 @@ func allocateResponseBody(key, version int16) protocolBody {
 		return &DescribeTransactionsResponse{Version: version}
 	case apiKeyListTransactions:
 		return &ListTransactionsResponse{Version: version}
+	case apiKeyGetTelemetrySubscriptions:
+		return &GetTelemetrySubscriptionsResponse{Version: version}
+	case apiKeyPushTelemetry:
+		return &PushTelemetryResponse{Version: version}
 	}
 	return nil
 }
@@ -83,6 +83,8 @@ var names = map[int16]string{
 	apiKeyListTransactions:             "ListTransactionsRequest",
 	67:                                 "AllocateProducerIdsRequest",
 	68:                                 "ConsumerGroupHeartbeatRequest",
+	apiKeyGetTelemetrySubscriptions:    "GetTelemetrySubscriptionsRequest",
+	apiKeyPushTelemetry:                "PushTelemetryRequest",
 }
 
 // TestAllocateBodyProtocolVersions tests two related version expectations:
@@ -401,8 +403,10 @@ func TestAllocateBodyProtocolVersions(t *testing.T) {
 		{
 			V3_7_0_0,
 			map[int16]int16{
-				apiKeyDescribeCluster: 1,  // up from 0
-				apiKeyProduce:         10, // up from 9
+				apiKeyDescribeCluster:           1,  // up from 0
+				apiKeyProduce:                   10, // up from 9
+				apiKeyGetTelemetrySubscriptions: 0,  // new in 3.7
+				apiKeyPushTelemetry:             0,  // new in 3.7
 				// TODO: FetchRequest v16 is not supported, but expected for KafkaVersion 3.7.0
 				// apiKeyFetch:           16, // up from 15
 				// TODO: OffsetFetchRequest v9 is not supported, but expected for KafkaVersion 3.7.0
@@ -537,6 +541,8 @@ func TestAllocateBodyProtocolVersions(t *testing.T) {
 				apiKeyDescribeProducers:            maxVersion(&DescribeProducersRequest{}),
 				apiKeyDescribeTransactions:         maxVersion(&DescribeTransactionsRequest{}),
 				apiKeyListTransactions:             maxVersion(&ListTransactionsRequest{}),
+				apiKeyGetTelemetrySubscriptions:    maxVersion(&GetTelemetrySubscriptionsRequest{}),
+				apiKeyPushTelemetry:                maxVersion(&PushTelemetryRequest{}),
 			},
 		},
 	}
@@ -0,0 +1,76 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// RequestTimeoutError is returned when a broker does not answer a request in
+// time, see Config.Net.ReadTimeout and Config.Net.RequestTimeouts.
+type RequestTimeoutError struct {
+	// APIKey is the key of the request that timed out.
+	APIKey int16
+	// API is the name of the request in the Kafka protocol, e.g. "Fetch".
+	API string
+	// Timeout is the timeout that applied to the request.
+	Timeout time.Duration
+	// Err is the underlying network error.
+	Err error
+}
+
+func (e *RequestTimeoutError) Error() string {
+	return fmt.Sprintf("kafka: %s request (api key %d) timed out after %s: %v", e.API, e.APIKey, e.Timeout, e.Err)
+}
+
+func (e *RequestTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// requestTimeout returns how long to wait for the response to rb.
+func requestTimeout(conf *Config, rb protocolBody) time.Duration {
+	timeout := conf.Net.ReadTimeout
+	if t, ok := conf.Net.RequestTimeouts[apiKeyName(rb.key())]; ok {
+		timeout = t
+	}
+	// the broker holds fetch requests for up to their MaxWaitTime
+	if fetch, ok := rb.(*FetchRequest); ok && fetch.MaxWaitTime > 0 {
+		timeout += time.Duration(fetch.MaxWaitTime) * time.Millisecond
+	}
+	return timeout
+}
+
+// wrapRequestTimeout turns a read timeout on the response to promise into a
+// *RequestTimeoutError.
+func wrapRequestTimeout(promise *responsePromise, err error) error {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	return &RequestTimeoutError{
+		APIKey:  promise.apiKey,
+		API:     apiKeyName(promise.apiKey),
+		Timeout: promise.timeout,
+		Err:     err,
+	}
+}
+
+func validRequestTimeouts(timeouts map[string]time.Duration) bool {
+	for api, timeout := range timeouts {
+		if timeout <= 0 {
+			return false
+		}
+		known := false
+		for _, name := range apiKeyNames {
+			if name == api {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
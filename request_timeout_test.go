@@ -0,0 +1,74 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyName(t *testing.T) {
+	assert.Equal(t, "Produce", apiKeyName(apiKeyProduce))
+	assert.Equal(t, "Fetch", apiKeyName(apiKeyFetch))
+	assert.Equal(t, "DeleteRecords", apiKeyName(apiKeyDeleteRecords))
+	assert.Equal(t, "ApiKey(-2)", apiKeyName(-2))
+}
+
+func TestRequestTimeout(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Net.ReadTimeout = time.Second
+	conf.Net.RequestTimeouts = map[string]time.Duration{"Metadata": 50 * time.Millisecond}
+
+	assert.Equal(t, 50*time.Millisecond, requestTimeout(conf, &MetadataRequest{}))
+	assert.Equal(t, time.Second, requestTimeout(conf, &ApiVersionsRequest{}))
+	assert.Equal(t, 1500*time.Millisecond, requestTimeout(conf, &FetchRequest{MaxWaitTime: 500}))
+
+	conf.Net.RequestTimeouts["Fetch"] = 100 * time.Millisecond
+	assert.Equal(t, 600*time.Millisecond, requestTimeout(conf, &FetchRequest{MaxWaitTime: 500}))
+}
+
+func TestBrokerRequestTimeoutError(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+	mb.SetLatency(300 * time.Millisecond)
+
+	conf := NewTestConfig()
+	conf.Net.RequestTimeouts = map[string]time.Duration{"Metadata": 50 * time.Millisecond}
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	defer safeClose(t, broker)
+
+	_, err := broker.GetMetadata(&MetadataRequest{})
+	var timeoutErr *RequestTimeoutError
+	assert.True(t, errors.As(err, &timeoutErr), "expected a RequestTimeoutError, got %v", err)
+	assert.Equal(t, int16(apiKeyMetadata), timeoutErr.APIKey)
+	assert.Equal(t, "Metadata", timeoutErr.API)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func TestBrokerFetchWaitsForMaxWaitTime(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"FetchRequest": NewMockFetchResponse(t, 1),
+	})
+	mb.SetLatency(200 * time.Millisecond)
+
+	conf := NewTestConfig()
+	conf.Net.RequestTimeouts = map[string]time.Duration{"Fetch": 100 * time.Millisecond}
+	broker := NewBroker(mb.Addr())
+	assert.NoError(t, broker.Open(conf))
+	defer safeClose(t, broker)
+
+	_, err := broker.Fetch(&FetchRequest{MaxWaitTime: 500})
+	assert.NoError(t, err)
+}
@@ -47,6 +47,8 @@ Broker related metrics:
 	|                                                         |            | https://kafka.apache.org/protocol.html#protocol_api_keys      |                                        |
 	| protocol-requests-rate-<api-key>-for-broker-<broker-id> | meter      | Number of packets sent to the brokers by api-key for a given  |
 	|                                                         |            | broker                                                        |
+	| tls-client-certificate-not-after                        | gauge      | Expiry time, in seconds since the Unix epoch, of the client   |
+	|                                                         |            | certificate of the last TLS connection opened                 |
 	+---------------------------------------------------------+------------+---------------------------------------------------------------+
 
 Note that we do not gather specific metrics for seed brokers but they are part of the "all brokers" metrics.
@@ -76,10 +78,27 @@ Consumer related metrics:
 	| consumer-fetch-rate-for-broker-<broker>   | meter      | Fetch requests/second sent to a given broker                                         |
 	| consumer-fetch-rate-for-topic-<topic>     | meter      | Fetch requests/second sent for a given topic                                         |
 	| consumer-fetch-response-size              | histogram  | Distribution of the fetch response size in bytes                                     |
+	| consumer-fetch-same-rack-byte-rate        | meter      | Bytes/second fetched from brokers in the consumer's RackID                           |
+	| consumer-fetch-cross-rack-byte-rate       | meter      | Bytes/second fetched from brokers in another rack than the consumer's RackID         |
+	| consumer-aborted-records-skipped          | counter    | Records of aborted transactions skipped with ReadCommitted for all topics            |
+	| consumer-aborted-records-skipped-         | counter    | Records of aborted transactions skipped with ReadCommitted for a given topic         |
+	| for-topic-<topic>                         |            |                                                                                      |
+	| consumer-control-records                  | counter    | Transaction control records seen for all topics                                      |
+	| consumer-control-records-for-topic-       | counter    | Transaction control records seen for a given topic                                   |
+	| <topic>                                   |            |                                                                                      |
+	| consumer-lso-lag-for-topic-<topic>-       | gauge      | High water mark minus last stable offset of a given partition                        |
+	| partition-<partition>                     |            |                                                                                      |
+	| consumer-fetch-rate-limit-time-in-ms      | histogram  | Distribution of the time in ms fetches were deferred by Consumer.Fetch.RateLimit     |
+	| consumer-fetch-rate-limit-time-in-ms-     | histogram  | Distribution of the time in ms a given topic was left out of fetches by              |
+	| for-topic-<topic>                         |            | Consumer.Fetch.TopicRateLimits                                                       |
 	| consumer-group-join-total-<GroupID>       | counter    | Total count of consumer group join attempts                                          |
 	| consumer-group-join-failed-<GroupID>      | counter    | Total count of consumer group join failures                                          |
 	| consumer-group-sync-total-<GroupID>       | counter    | Total count of consumer group sync attempts                                          |
 	| consumer-group-sync-failed-<GroupID>      | counter    | Total count of consumer group sync failures                                          |
+	| consumer-group-lag-<GroupID>-for-topic-   | gauge      | High water mark minus the next offset to be delivered for a claimed partition        |
+	| <topic>-partition-<partition>             |            |                                                                                      |
+	| consumer-group-time-lag-in-ms-<GroupID>-  | gauge      | Age in ms of the last message delivered for a claimed partition, 0 when caught up    |
+	| for-topic-<topic>-partition-<partition>   |            |                                                                                      |
 	+-------------------------------------------+------------+--------------------------------------------------------------------------------------+
 */
 package sarama
@@ -0,0 +1,239 @@
+package sarama
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	awsMSKIAMVersion   = "2020_10_22"
+	awsMSKIAMAction    = "kafka-cluster:Connect"
+	awsMSKIAMService   = "kafka-cluster"
+	awsMSKIAMAlgorithm = "AWS4-HMAC-SHA256"
+	// awsMSKIAMExpiry is how long a signed payload is valid for. Brokers
+	// reject payloads presented after it.
+	awsMSKIAMExpiry = 5 * time.Minute
+	// awsMSKIAMUserAgent identifies Sarama in the authentication payload.
+	awsMSKIAMUserAgent = "sarama"
+)
+
+// AWSCredentials are the AWS credentials used to sign the AWS_MSK_IAM
+// authentication payload.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string // #nosec G117 -- public SASL config schema; callers set this credential explicitly.
+	// SessionToken is only set for temporary credentials, such as the ones
+	// of an assumed role.
+	SessionToken string
+}
+
+// AWSCredentialsProvider is the interface that encapsulates how implementers
+// retrieve the AWS credentials used for AWS_MSK_IAM authentication.
+type AWSCredentialsProvider interface {
+	// Credentials returns the current credentials. It is called every time a
+	// connection authenticates, including KIP-368 re-authentications, so
+	// implementations should cache them and refresh temporary credentials
+	// before they expire. This method should not block indefinitely.
+	Credentials() (*AWSCredentials, error)
+}
+
+// AWSCredentialsProviderFunc adapts a function to the AWSCredentialsProvider
+// interface.
+type AWSCredentialsProviderFunc func() (*AWSCredentials, error)
+
+// Credentials calls f.
+func (f AWSCredentialsProviderFunc) Credentials() (*AWSCredentials, error) {
+	return f()
+}
+
+// NewStaticAWSCredentialsProvider returns a provider that always returns the
+// given credentials.
+func NewStaticAWSCredentialsProvider(accessKeyID, secretAccessKey, sessionToken string) AWSCredentialsProvider {
+	credentials := &AWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}
+	return AWSCredentialsProviderFunc(func() (*AWSCredentials, error) {
+		return credentials, nil
+	})
+}
+
+// NewEnvAWSCredentialsProvider returns a provider reading the credentials
+// from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables each time they are needed.
+func NewEnvAWSCredentialsProvider() AWSCredentialsProvider {
+	return AWSCredentialsProviderFunc(func() (*AWSCredentials, error) {
+		credentials := &AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+		}
+		return credentials, nil
+	})
+}
+
+// AWSMSKIAMSigner produces the AWS_MSK_IAM authentication payload presented
+// to the broker at host.
+type AWSMSKIAMSigner interface {
+	Sign(host string) ([]byte, error)
+}
+
+// AWSMSKIAMConfig configures the AWS_MSK_IAM SASL mechanism of Amazon MSK.
+type AWSMSKIAMConfig struct {
+	// Region is the AWS region of the cluster. It is inferred from the broker
+	// host names, such as b-1.cluster.abc123.c2.kafka.us-east-1.amazonaws.com,
+	// when empty.
+	Region string
+	// CredentialsProvider supplies the credentials the payload is signed with.
+	CredentialsProvider AWSCredentialsProvider
+	// UserAgent is sent along with the payload (defaults to "sarama").
+	UserAgent string
+	// Signer replaces the default AWS Signature Version 4 signer, for example
+	// to sign with the AWS SDK or to authenticate against a MockBroker in
+	// tests. Region and CredentialsProvider are ignored when it is set.
+	Signer AWSMSKIAMSigner
+}
+
+func (c *AWSMSKIAMConfig) signer() AWSMSKIAMSigner {
+	if c.Signer != nil {
+		return c.Signer
+	}
+	return &awsMSKIAMSigV4Signer{config: c, now: time.Now}
+}
+
+// awsMSKIAMSigV4Signer signs the payload like aws-msk-iam-auth, the Java
+// library brokers expect clients to use: as the query string of a presigned
+// GET request for the kafka-cluster:Connect action.
+type awsMSKIAMSigV4Signer struct {
+	config *AWSMSKIAMConfig
+	now    func() time.Time
+}
+
+func (s *awsMSKIAMSigV4Signer) Sign(host string) ([]byte, error) {
+	credentials, err := s.config.CredentialsProvider.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	region := s.config.Region
+	if region == "" {
+		if region = awsRegionFromHost(host); region == "" {
+			return nil, fmt.Errorf("cannot infer the AWS region from %s, set Net.SASL.AWSMSKIAM.Region", host)
+		}
+	}
+	userAgent := s.config.UserAgent
+	if userAgent == "" {
+		userAgent = awsMSKIAMUserAgent
+	}
+
+	timestamp := s.now().UTC().Format("20060102T150405Z")
+	date := timestamp[:8]
+	scope := date + "/" + region + "/" + awsMSKIAMService + "/aws4_request"
+	expires := fmt.Sprint(int(awsMSKIAMExpiry.Seconds()))
+
+	query := url.Values{}
+	query.Set("Action", awsMSKIAMAction)
+	query.Set("X-Amz-Algorithm", awsMSKIAMAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", timestamp)
+	query.Set("X-Amz-Expires", expires)
+	query.Set("X-Amz-SignedHeaders", "host")
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// SigV4 escapes spaces as %20, and the query must be sorted, which
+	// Encode does
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		"host:" + host + "\n",
+		"host",
+		hexSHA256(""),
+	}, "\n")
+	stringToSign := strings.Join([]string{awsMSKIAMAlgorithm, timestamp, scope, hexSHA256(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	for _, part := range []string{region, awsMSKIAMService, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	payload := map[string]string{
+		"version":             awsMSKIAMVersion,
+		"host":                host,
+		"user-agent":          userAgent,
+		"action":              awsMSKIAMAction,
+		"x-amz-algorithm":     awsMSKIAMAlgorithm,
+		"x-amz-credential":    credentials.AccessKeyID + "/" + scope,
+		"x-amz-date":          timestamp,
+		"x-amz-expires":       expires,
+		"x-amz-signedheaders": "host",
+		"x-amz-signature":     signature,
+	}
+	if credentials.SessionToken != "" {
+		payload["x-amz-security-token"] = credentials.SessionToken
+	}
+	return json.Marshal(payload)
+}
+
+// awsRegionFromHost extracts the region of an MSK broker host name, which
+// ends with kafka.<region>.amazonaws.com (or amazonaws.com.cn in China).
+func awsRegionFromHost(host string) string {
+	labels := strings.Split(host, ".")
+	for i := 0; i+2 < len(labels); i++ {
+		if labels[i] == "kafka" && labels[i+2] == "amazonaws" {
+			return labels[i+1]
+		}
+	}
+	return ""
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendAndReceiveSASLAWSMSKIAM authenticates with a signed payload. The broker
+// replies with the session lifetime, after which the connection
+// re-authenticates with a freshly signed payload, see KIP-368.
+func (b *Broker) sendAndReceiveSASLAWSMSKIAM(authSendReceiver func(authBytes []byte) (*SaslAuthenticateResponse, error)) error {
+	host, _, err := net.SplitHostPort(b.addr)
+	if err != nil {
+		host = b.addr
+	}
+
+	payload, err := b.conf.Net.SASL.AWSMSKIAM.signer().Sign(host)
+	if err != nil {
+		return err
+	}
+
+	res, err := authSendReceiver(payload)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Version   string `json:"version"`
+		RequestID string `json:"request-id"`
+	}
+	if json.Unmarshal(res.SaslAuthBytes, &response) == nil && response.RequestID != "" {
+		DebugLogger.Printf("Authenticated with AWS_MSK_IAM to broker %s, request id %s\n", b.addr, response.RequestID)
+	}
+	return nil
+}
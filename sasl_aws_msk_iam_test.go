@@ -0,0 +1,199 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestAWSMSKIAMSigV4Signer(t *testing.T) {
+	host := "b-1.demo.abc123.c2.kafka.eu-west-1.amazonaws.com"
+	signer := &awsMSKIAMSigV4Signer{
+		config: &AWSMSKIAMConfig{
+			CredentialsProvider: NewStaticAWSCredentialsProvider("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "session token"),
+		},
+		now: func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+
+	payload, err := signer.Sign(host)
+	assert.NoError(t, err)
+
+	var fields map[string]string
+	assert.NoError(t, json.Unmarshal(payload, &fields))
+	assert.Equal(t, map[string]string{
+		"version":              "2020_10_22",
+		"host":                 host,
+		"user-agent":           "sarama",
+		"action":               "kafka-cluster:Connect",
+		"x-amz-algorithm":      "AWS4-HMAC-SHA256",
+		"x-amz-credential":     "AKIDEXAMPLE/20240102/eu-west-1/kafka-cluster/aws4_request",
+		"x-amz-date":           "20240102T030405Z",
+		"x-amz-expires":        "300",
+		"x-amz-signedheaders":  "host",
+		"x-amz-security-token": "session token",
+		"x-amz-signature":      "c98d51aedf39b474113b48f8427f8eadad36fe0aee2daf36c26a6a8f2810a41a",
+	}, fields)
+
+	signer.config.Region = "us-east-2"
+	payload, err = signer.Sign(host)
+	assert.NoError(t, err)
+	assert.Contains(t, string(payload), "/us-east-2/kafka-cluster/aws4_request", "an explicit region takes precedence")
+
+	_, err = signer.Sign("localhost")
+	assert.NoError(t, err)
+
+	signer.config.Region = ""
+	_, err = signer.Sign("localhost")
+	assert.ErrorContains(t, err, "cannot infer the AWS region")
+
+	signer.config.CredentialsProvider = AWSCredentialsProviderFunc(func() (*AWSCredentials, error) {
+		return nil, ErrTokenFailure
+	})
+	_, err = signer.Sign(host)
+	assert.ErrorIs(t, err, ErrTokenFailure)
+}
+
+func TestAWSRegionFromHost(t *testing.T) {
+	for host, region := range map[string]string{
+		"b-1.demo.abc123.c2.kafka.us-east-1.amazonaws.com":            "us-east-1",
+		"b-2-public.demo.abc123.c2.kafka.cn-north-1.amazonaws.com.cn": "cn-north-1",
+		"boot-abc123.c2.kafka-serverless.eu-west-1.amazonaws.com":     "",
+		"kafka.example.com": "",
+		"localhost":         "",
+	} {
+		assert.Equal(t, region, awsRegionFromHost(host), host)
+	}
+}
+
+func TestEnvAWSCredentialsProvider(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	_, err := NewEnvAWSCredentialsProvider().Credentials()
+	assert.Error(t, err)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+	credentials, err := NewEnvAWSCredentialsProvider().Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, &AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}, credentials)
+}
+
+// fakeAWSMSKIAMSigner returns an unsigned payload naming the host and how
+// many payloads it has produced.
+type fakeAWSMSKIAMSigner struct {
+	lock  sync.Mutex
+	hosts []string
+	err   error
+}
+
+func (s *fakeAWSMSKIAMSigner) Sign(host string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.hosts = append(s.hosts, host)
+	return json.Marshal(map[string]any{"version": awsMSKIAMVersion, "host": host, "n": len(s.hosts)})
+}
+
+func (s *fakeAWSMSKIAMSigner) signed() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.hosts)
+}
+
+func TestSASLAWSMSKIAM(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+
+	var (
+		lock     sync.Mutex
+		payloads []map[string]any
+	)
+	mockBroker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"SaslHandshakeRequest": func(req *request) encoderWithHeader {
+			handshake := req.body.(*SaslHandshakeRequest)
+			assert.Equal(t, SASLTypeAWSMSKIAM, handshake.Mechanism)
+			return &SaslHandshakeResponse{Version: handshake.Version, EnabledMechanisms: []string{SASLTypeAWSMSKIAM}}
+		},
+		"SaslAuthenticateRequest": func(req *request) encoderWithHeader {
+			authenticate := req.body.(*SaslAuthenticateRequest)
+			var payload map[string]any
+			if err := json.Unmarshal(authenticate.SaslAuthBytes, &payload); err != nil {
+				return &SaslAuthenticateResponse{Version: authenticate.Version, Err: ErrSASLAuthenticationFailed}
+			}
+			lock.Lock()
+			payloads = append(payloads, payload)
+			lock.Unlock()
+			return &SaslAuthenticateResponse{
+				Version:           authenticate.Version,
+				SaslAuthBytes:     []byte(`{"version":"2020_10_22","request-id":"req-1"}`),
+				SessionLifetimeMs: 100,
+			}
+		},
+		"ApiVersionsRequest": func(req *request) encoderWithHeader {
+			return NewMockApiVersionsResponse(t).For(req.body)
+		},
+	})
+
+	signer := &fakeAWSMSKIAMSigner{}
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypeAWSMSKIAM
+	conf.Net.SASL.AWSMSKIAM.Signer = signer
+
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(conf))
+	t.Cleanup(func() { _ = broker.Close() })
+	connected, err := broker.Connected()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+	assert.Equal(t, 1, signer.signed())
+
+	// the session expires after 100ms, after which the next request
+	// re-authenticates with a new payload
+	deadline := time.Now().Add(time.Second)
+	for signer.signed() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, err = broker.ApiVersions(&ApiVersionsRequest{})
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, signer.signed(), 2, "the connection should have re-authenticated")
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.GreaterOrEqual(t, len(payloads), 2)
+	assert.Equal(t, "127.0.0.1", payloads[0]["host"])
+	assert.EqualValues(t, 1, payloads[0]["n"])
+	assert.EqualValues(t, 2, payloads[1]["n"])
+}
+
+func TestSASLAWSMSKIAMSignerFailure(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"SaslHandshakeRequest":    NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypeAWSMSKIAM}),
+		"SaslAuthenticateRequest": NewMockSaslAuthenticateResponse(t),
+	})
+
+	signerErr := errors.New("no credentials")
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypeAWSMSKIAM
+	conf.Net.SASL.AWSMSKIAM.Signer = &fakeAWSMSKIAMSigner{err: signerErr}
+
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(conf))
+	t.Cleanup(func() { _ = broker.Close() })
+	_, err := broker.Connected()
+	assert.ErrorIs(t, err, signerErr)
+}
@@ -0,0 +1,244 @@
+package sarama
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// unsecuredJWSDefaultLifetime is the lifetime of the tokens minted when
+	// none is configured, the default of the Java client as well.
+	unsecuredJWSDefaultLifetime = time.Hour
+	// unsecuredJWSClockSkew is the clock skew tolerated when validating the
+	// expiry of a token.
+	unsecuredJWSClockSkew = 30 * time.Second
+)
+
+// UnsecuredJWSConfig configures the tokens minted by
+// NewUnsecuredJWSTokenProvider.
+type UnsecuredJWSConfig struct {
+	// Principal is the authenticated principal name, sent in the sub claim
+	// unless PrincipalClaimName is set.
+	Principal string
+	// PrincipalClaimName is the claim carrying the principal (defaults to
+	// "sub"), see the unsecuredLoginPrincipalClaimName broker option.
+	PrincipalClaimName string
+	// Scope is the optional list of scopes sent in the scope claim, or in
+	// ScopeClaimName when set.
+	Scope []string
+	// ScopeClaimName is the claim carrying the scope (defaults to "scope"),
+	// see the unsecuredLoginScopeClaimName broker option.
+	ScopeClaimName string
+	// Lifetime is how long each token is valid (defaults to 1h).
+	Lifetime time.Duration
+	// Claims are additional claims added to the tokens.
+	Claims map[string]any
+	// Extensions are the SASL/OAUTHBEARER extensions sent along with every
+	// token, see AccessToken.
+	Extensions map[string]string
+}
+
+type unsecuredJWSTokenProvider struct {
+	config UnsecuredJWSConfig
+	now    func() time.Time
+
+	lock      sync.Mutex
+	token     *AccessToken
+	refreshAt time.Time
+}
+
+// NewUnsecuredJWSTokenProvider returns an AccessTokenProvider minting
+// unsigned JWS tokens ("alg": "none") for SASL/OAUTHBEARER, as accepted by
+// brokers using the default unsecured validator of Kafka. They must only be
+// used for development and tests: anyone can mint them for any principal.
+// A token is reused until 80% of its lifetime has elapsed.
+func NewUnsecuredJWSTokenProvider(config UnsecuredJWSConfig) (AccessTokenProvider, error) {
+	switch {
+	case config.Principal == "":
+		return nil, ConfigurationError("UnsecuredJWSConfig.Principal must not be empty")
+	case config.Lifetime < 0:
+		return nil, ConfigurationError("UnsecuredJWSConfig.Lifetime must be >= 0")
+	}
+	if _, ok := config.Extensions[SASLExtKeyAuth]; ok {
+		return nil, ConfigurationError(fmt.Sprintf("UnsecuredJWSConfig.Extensions must not contain the `%s` extension", SASLExtKeyAuth))
+	}
+	if config.PrincipalClaimName == "" {
+		config.PrincipalClaimName = "sub"
+	}
+	if config.ScopeClaimName == "" {
+		config.ScopeClaimName = "scope"
+	}
+	if config.Lifetime == 0 {
+		config.Lifetime = unsecuredJWSDefaultLifetime
+	}
+	return &unsecuredJWSTokenProvider{config: config, now: time.Now}, nil
+}
+
+func (p *unsecuredJWSTokenProvider) Token() (*AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	if p.token != nil && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+
+	claims := make(map[string]any, len(p.config.Claims)+4)
+	for k, v := range p.config.Claims {
+		claims[k] = v
+	}
+	claims[p.config.PrincipalClaimName] = p.config.Principal
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(p.config.Lifetime).Unix()
+	if len(p.config.Scope) > 0 {
+		claims[p.config.ScopeClaimName] = p.config.Scope
+	}
+
+	token, err := encodeUnsecuredJWS(claims)
+	if err != nil {
+		return nil, err
+	}
+	p.token = &AccessToken{Token: token, Extensions: p.config.Extensions}
+	p.refreshAt = now.Add(time.Duration(float64(p.config.Lifetime) * oauth2RefreshWindowFactor))
+	return p.token, nil
+}
+
+func encodeUnsecuredJWS(claims map[string]any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	// the signature of an unsecured JWS is empty
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".", nil
+}
+
+// UnsecuredJWS holds the claims of an unsecured JWS token validated by
+// ParseUnsecuredJWS.
+type UnsecuredJWS struct {
+	Principal string
+	Scope     []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Claims    map[string]any
+}
+
+// ParseUnsecuredJWS parses and validates an unsecured JWS token the way the
+// default unsecured validator of Kafka brokers does: the header must have
+// "alg": "none", the signature must be empty, the token must not have expired
+// and the principal must be present in principalClaimName ("sub" if empty).
+// The scope is read from scopeClaimName ("scope" if empty), either a
+// space-delimited string or a list of strings.
+func ParseUnsecuredJWS(token, principalClaimName, scopeClaimName string, now time.Time) (*UnsecuredJWS, error) {
+	if principalClaimName == "" {
+		principalClaimName = "sub"
+	}
+	if scopeClaimName == "" {
+		scopeClaimName = "scope"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("unsecured JWS must have 3 dot-separated parts")
+	}
+	if parts[2] != "" {
+		return nil, errors.New("unsecured JWS must have an empty signature")
+	}
+
+	var header map[string]any
+	if err := decodeJWSPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid unsecured JWS header: %w", err)
+	}
+	if header["alg"] != "none" {
+		return nil, fmt.Errorf("unsecured JWS must have alg none, got %v", header["alg"])
+	}
+
+	jws := &UnsecuredJWS{}
+	if err := decodeJWSPart(parts[1], &jws.Claims); err != nil {
+		return nil, fmt.Errorf("invalid unsecured JWS claims: %w", err)
+	}
+
+	principal, ok := jws.Claims[principalClaimName].(string)
+	if !ok || principal == "" {
+		return nil, fmt.Errorf("unsecured JWS has no %s claim", principalClaimName)
+	}
+	jws.Principal = principal
+
+	if exp, ok := jws.Claims["exp"].(json.Number); ok {
+		seconds, err := exp.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsecured JWS exp claim: %w", err)
+		}
+		jws.ExpiresAt = time.UnixMilli(int64(seconds * 1000))
+	} else {
+		return nil, errors.New("unsecured JWS has no exp claim")
+	}
+	if !now.Before(jws.ExpiresAt.Add(unsecuredJWSClockSkew)) {
+		return nil, fmt.Errorf("unsecured JWS expired at %s", jws.ExpiresAt.Format(time.RFC3339))
+	}
+	if iat, ok := jws.Claims["iat"].(json.Number); ok {
+		if seconds, err := iat.Float64(); err == nil {
+			jws.IssuedAt = time.UnixMilli(int64(seconds * 1000))
+		}
+	}
+
+	switch scope := jws.Claims[scopeClaimName].(type) {
+	case string:
+		jws.Scope = strings.Fields(scope)
+	case []any:
+		for _, s := range scope {
+			if s, ok := s.(string); ok {
+				jws.Scope = append(jws.Scope, s)
+			}
+		}
+	}
+	return jws, nil
+}
+
+func decodeJWSPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// parseOAuthBearerClientFirstMessage splits a SASL/OAUTHBEARER initial
+// client response, as built by buildClientFirstMessage, into the bearer
+// token and the extensions (RFC 7628 section 3.1).
+func parseOAuthBearerClientFirstMessage(message []byte) (string, map[string]string, error) {
+	gs2Header, rest, ok := strings.Cut(string(message), "\x01")
+	if !ok || !strings.HasPrefix(gs2Header, "n,") || !strings.HasSuffix(rest, "\x01\x01") {
+		return "", nil, errors.New("malformed OAUTHBEARER initial client response")
+	}
+
+	var token string
+	extensions := map[string]string{}
+	for _, kv := range strings.Split(strings.TrimSuffix(rest, "\x01\x01"), "\x01") {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("malformed OAUTHBEARER key-value pair %q", kv)
+		}
+		if key == SASLExtKeyAuth {
+			scheme, credentials, ok := strings.Cut(value, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || credentials == "" {
+				return "", nil, errors.New("OAUTHBEARER auth value must be a bearer token")
+			}
+			token = credentials
+			continue
+		}
+		extensions[key] = value
+	}
+	if token == "" {
+		return "", nil, errors.New("OAUTHBEARER initial client response has no auth value")
+	}
+	return token, extensions, nil
+}
@@ -0,0 +1,196 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestUnsecuredJWSTokenProvider(t *testing.T) {
+	provider, err := NewUnsecuredJWSTokenProvider(UnsecuredJWSConfig{
+		Principal:  "alice",
+		Scope:      []string{"kafka", "read"},
+		Lifetime:   10 * time.Minute,
+		Claims:     map[string]any{"iss": "local"},
+		Extensions: map[string]string{"traceId": "1"},
+	})
+	assert.NoError(t, err)
+	now := time.Now()
+	p := provider.(*unsecuredJWSTokenProvider)
+	p.now = func() time.Time { return now }
+
+	token, err := provider.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"traceId": "1"}, token.Extensions)
+
+	jws, err := ParseUnsecuredJWS(token.Token, "", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", jws.Principal)
+	assert.Equal(t, []string{"kafka", "read"}, jws.Scope)
+	assert.Equal(t, now.Unix(), jws.IssuedAt.Unix())
+	assert.Equal(t, now.Add(10*time.Minute).Unix(), jws.ExpiresAt.Unix())
+	assert.Equal(t, "local", jws.Claims["iss"])
+
+	// reused until 80% of the lifetime has elapsed
+	now = now.Add(7 * time.Minute)
+	again, err := provider.Token()
+	assert.NoError(t, err)
+	assert.Same(t, token, again)
+
+	now = now.Add(2 * time.Minute)
+	again, err = provider.Token()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token.Token, again.Token)
+}
+
+func TestUnsecuredJWSTokenProviderClaimNames(t *testing.T) {
+	provider, err := NewUnsecuredJWSTokenProvider(UnsecuredJWSConfig{
+		Principal:          "bob",
+		PrincipalClaimName: "client_id",
+		Scope:              []string{"write"},
+		ScopeClaimName:     "scp",
+	})
+	assert.NoError(t, err)
+	token, err := provider.Token()
+	assert.NoError(t, err)
+
+	_, err = ParseUnsecuredJWS(token.Token, "", "", time.Now())
+	assert.ErrorContains(t, err, "unsecured JWS has no sub claim")
+
+	jws, err := ParseUnsecuredJWS(token.Token, "client_id", "scp", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", jws.Principal)
+	assert.Equal(t, []string{"write"}, jws.Scope)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), jws.ExpiresAt, time.Minute)
+}
+
+func TestUnsecuredJWSConfigValidation(t *testing.T) {
+	for _, test := range []struct {
+		config UnsecuredJWSConfig
+		err    string
+	}{
+		{UnsecuredJWSConfig{}, "UnsecuredJWSConfig.Principal must not be empty"},
+		{UnsecuredJWSConfig{Principal: "p", Lifetime: -1}, "UnsecuredJWSConfig.Lifetime must be >= 0"},
+		{UnsecuredJWSConfig{Principal: "p", Extensions: map[string]string{"auth": "x"}}, "UnsecuredJWSConfig.Extensions must not contain the `auth` extension"},
+	} {
+		_, err := NewUnsecuredJWSTokenProvider(test.config)
+		var target ConfigurationError
+		assert.ErrorAs(t, err, &target)
+		assert.Equal(t, test.err, string(target))
+	}
+}
+
+func TestParseUnsecuredJWS(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	exp := time.Now().Add(time.Hour).Unix()
+
+	valid, err := encodeUnsecuredJWS(map[string]any{"sub": "alice", "exp": exp, "scope": "a b"})
+	assert.NoError(t, err)
+	jws, err := ParseUnsecuredJWS(valid, "", "", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, jws.Scope, "space-delimited scopes")
+
+	expired, err := encodeUnsecuredJWS(map[string]any{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()})
+	assert.NoError(t, err)
+	noExpiry, err := encodeUnsecuredJWS(map[string]any{"sub": "alice"})
+	assert.NoError(t, err)
+	signed := encode(`{"alg":"RS256"}`) + "." + encode(`{"sub":"alice"}`) + "."
+
+	for token, expected := range map[string]string{
+		"a.b":         "unsecured JWS must have 3 dot-separated parts",
+		valid + "sig": "unsecured JWS must have an empty signature",
+		signed:        "unsecured JWS must have alg none, got RS256",
+		expired:       "unsecured JWS expired at",
+		noExpiry:      "unsecured JWS has no exp claim",
+	} {
+		_, err := ParseUnsecuredJWS(token, "", "", time.Now())
+		assert.ErrorContains(t, err, expected, token)
+	}
+}
+
+func TestParseOAuthBearerClientFirstMessage(t *testing.T) {
+	message, err := buildClientFirstMessage(&AccessToken{Token: "tok", Extensions: map[string]string{"a": "1", "b": "2"}})
+	assert.NoError(t, err)
+	token, extensions, err := parseOAuthBearerClientFirstMessage(message)
+	assert.NoError(t, err)
+	assert.Equal(t, "tok", token)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, extensions)
+
+	for _, malformed := range []string{"", "y,,\x01auth=Bearer tok\x01\x01", "n,,\x01auth=Bearer tok\x01", "n,,\x01a=1\x01\x01", "n,,\x01auth=Basic x\x01\x01"} {
+		_, _, err := parseOAuthBearerClientFirstMessage([]byte(malformed))
+		assert.Error(t, err, malformed)
+	}
+}
+
+func TestSASLOAuthBearerUnsecuredJWS(t *testing.T) {
+	newBroker := func(t *testing.T, handler *MockSaslOAuthBearerResponse, config UnsecuredJWSConfig) (*MockBroker, *Broker, error) {
+		mockBroker := NewMockBroker(t, 0)
+		t.Cleanup(mockBroker.Close)
+		mockBroker.SetHandlerByMap(map[string]MockResponse{
+			"SaslHandshakeRequest":    NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypeOAuth}),
+			"SaslAuthenticateRequest": handler,
+			"ApiVersionsRequest":      NewMockApiVersionsResponse(t),
+		})
+
+		provider, err := NewUnsecuredJWSTokenProvider(config)
+		assert.NoError(t, err)
+		conf := NewTestConfig()
+		conf.Version = V2_2_0_0
+		conf.Net.SASL.Enable = true
+		conf.Net.SASL.Mechanism = SASLTypeOAuth
+		conf.Net.SASL.TokenProvider = provider
+
+		broker := NewBroker(mockBroker.Addr())
+		assert.NoError(t, broker.Open(conf))
+		t.Cleanup(func() { _ = broker.Close() })
+		_, err = broker.Connected()
+		return mockBroker, broker, err
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		handler := NewMockSaslOAuthBearerResponse(t).
+			SetRequiredScopes("kafka").
+			SetExpectedExtensions(map[string]string{"traceId": "1"})
+		_, _, err := newBroker(t, handler, UnsecuredJWSConfig{
+			Principal:  "alice",
+			Scope:      []string{"kafka"},
+			Extensions: map[string]string{"traceId": "1"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, handler.Principals())
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		handler := NewMockSaslOAuthBearerResponse(t).SetRequiredScopes("kafka")
+		mockBroker, _, err := newBroker(t, handler, UnsecuredJWSConfig{Principal: "alice", Scope: []string{"other"}})
+		assert.ErrorIs(t, err, ErrSASLAuthenticationFailed)
+		assert.Empty(t, handler.Principals())
+
+		// the initial response and the acknowledgement of the error challenge
+		var authenticates int
+		for _, rr := range mockBroker.History() {
+			if _, ok := rr.Request.(*SaslAuthenticateRequest); ok {
+				authenticates++
+			}
+		}
+		assert.Equal(t, 2, authenticates)
+	})
+
+	t.Run("re-authentication", func(t *testing.T) {
+		handler := NewMockSaslOAuthBearerResponse(t).SetSessionLifetimeMs(100)
+		_, broker, err := newBroker(t, handler, UnsecuredJWSConfig{Principal: "alice"})
+		assert.NoError(t, err)
+
+		deadline := time.Now().Add(time.Second)
+		for len(handler.Principals()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			_, err = broker.ApiVersions(&ApiVersionsRequest{})
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, []string{"alice", "alice"}, handler.Principals()[:2])
+	})
+}
@@ -76,3 +76,29 @@ func (s scramFormatter) saltedPassword(password []byte, salt []byte, iterations
 
 	return result, nil
 }
+
+func (s scramFormatter) hash(data []byte) ([]byte, error) {
+	switch s.mechanism {
+	case SCRAM_MECHANISM_SHA_256:
+		sum := sha256.Sum256(data)
+		return sum[:], nil
+	case SCRAM_MECHANISM_SHA_512:
+		sum := sha512.Sum512(data)
+		return sum[:], nil
+	default:
+		return nil, ErrUnknownScramMechanism
+	}
+}
+
+// clientKey, storedKey and serverKey derive the keys of RFC 5802 section 3.
+func (s scramFormatter) clientKey(saltedPassword []byte) ([]byte, error) {
+	return s.hmac(saltedPassword, []byte("Client Key"))
+}
+
+func (s scramFormatter) storedKey(clientKey []byte) ([]byte, error) {
+	return s.hash(clientKey)
+}
+
+func (s scramFormatter) serverKey(saltedPassword []byte) ([]byte, error) {
+	return s.hmac(saltedPassword, []byte("Server Key"))
+}
@@ -4,6 +4,7 @@ package sarama
 
 import (
 	"bytes"
+	"encoding/base64"
 	"testing"
 )
 
@@ -80,3 +81,46 @@ func TestScramSaltedPasswordSha256(t *testing.T) {
 		t.Errorf("saltedPassword SHA-256 failed, expected: %v, result: %v", expected, result)
 	}
 }
+
+// TestScramProofAndSignature checks the keys against the SCRAM-SHA-256 example
+// of RFC 7677 section 3.
+func TestScramProofAndSignature(t *testing.T) {
+	formatter := scramFormatter{mechanism: SCRAM_MECHANISM_SHA_256}
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	authMessage := []byte("n=user,r=rOprNGfwEbeRWgbNEkqO," +
+		"r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096," +
+		"c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0")
+
+	saltedPassword, err := formatter.saltedPassword([]byte("pencil"), salt, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := formatter.clientKey(saltedPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storedKey, err := formatter.storedKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSignature, err := formatter.hmac(storedKey, authMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter.xor(clientKey, clientSignature)
+	if proof := base64.StdEncoding.EncodeToString(clientKey); proof != "dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=" {
+		t.Errorf("unexpected client proof %s", proof)
+	}
+
+	serverKey, err := formatter.serverKey(saltedPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSignature, err := formatter.hmac(serverKey, authMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature := base64.StdEncoding.EncodeToString(serverSignature); signature != "6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=" {
+		t.Errorf("unexpected server signature %s", signature)
+	}
+}
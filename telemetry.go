@@ -0,0 +1,248 @@
+package sarama
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+const (
+	// telemetryDefaultPushInterval is used when a broker does not request a
+	// push interval.
+	telemetryDefaultPushInterval = 5 * time.Minute
+	// telemetryRetryBackoff is how long to wait before asking for the
+	// subscription again after a failure.
+	telemetryRetryBackoff = 30 * time.Second
+)
+
+// telemetryAgent pushes the client's metrics to the brokers following the
+// subscription they hand out, see KIP-714 and Config.Telemetry.
+type telemetryAgent struct {
+	client *client
+	conf   *Config
+
+	closer, closed chan none
+
+	instanceID   Uuid
+	subscription *GetTelemetrySubscriptionsResponse
+	codec        CompressionCodec
+	start        time.Time
+	lastPush     time.Time
+	// previous holds the value of every sum at the last push, to report
+	// deltas when the subscription asks for them
+	previous map[string]int64
+}
+
+func newTelemetryAgent(client *client) *telemetryAgent {
+	return &telemetryAgent{
+		client: client,
+		conf:   client.conf,
+		closer: make(chan none),
+		closed: make(chan none),
+		start:  time.Now(),
+	}
+}
+
+// close stops the agent once it has sent its terminating push.
+func (a *telemetryAgent) close() {
+	if a == nil {
+		return
+	}
+	close(a.closer)
+	<-a.closed
+}
+
+func (a *telemetryAgent) run() {
+	defer close(a.closed)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-a.closer:
+			if a.subscribed() {
+				a.push(true)
+			}
+			return
+		}
+
+		var wait time.Duration
+		if a.subscription == nil {
+			wait = a.subscribe()
+		} else {
+			wait = a.push(false)
+		}
+		if wait < 0 {
+			Logger.Println("client/telemetry stopped pushing metrics")
+			<-a.closer
+			return
+		}
+		timer.Reset(wait)
+	}
+}
+
+// subscribed reports whether the agent has a subscription with metrics to push.
+func (a *telemetryAgent) subscribed() bool {
+	return a.subscription != nil && len(a.subscription.RequestedMetrics) > 0
+}
+
+// subscribe fetches the subscription from a broker and returns how long to
+// wait before the first push, or a negative duration if the cluster does not
+// support client telemetry.
+func (a *telemetryAgent) subscribe() time.Duration {
+	broker := a.client.LeastLoadedBroker()
+	if broker == nil {
+		return telemetryRetryBackoff
+	}
+	if !broker.supportsAPI(apiKeyGetTelemetrySubscriptions) {
+		Logger.Printf("client/telemetry broker %d does not support client telemetry\n", broker.ID())
+		return -1
+	}
+
+	response, err := broker.GetTelemetrySubscriptions(NewGetTelemetrySubscriptionsRequest(a.instanceID))
+	if err != nil {
+		DebugLogger.Printf("client/telemetry failed to get subscriptions from broker %d: %v\n", broker.ID(), err)
+		return telemetryRetryBackoff
+	}
+	if !errors.Is(response.Err, ErrNoError) {
+		DebugLogger.Printf("client/telemetry failed to get subscriptions from broker %d: %v\n", broker.ID(), response.Err)
+		return telemetryRetryBackoff
+	}
+
+	a.instanceID = response.ClientInstanceID
+	a.subscription = response
+	a.codec = telemetryCodec(response.AcceptedCompressionTypes)
+	a.lastPush = time.Now()
+	a.previous = nil
+
+	interval := a.interval()
+	if !a.subscribed() {
+		// nothing to push, check again later whether that changed
+		a.subscription = nil
+		return interval
+	}
+	DebugLogger.Printf("client/telemetry subscribed to %d metric prefixes as client instance %s\n", len(response.RequestedMetrics), a.instanceID)
+
+	// spread the first push of many clients started together
+	return time.Duration(float64(interval) * (0.5 + rand.Float64()))
+}
+
+// push sends the requested metrics and returns how long to wait before the
+// next push, 0 to fetch the subscription again, or a negative duration to
+// give up.
+func (a *telemetryAgent) push(terminating bool) time.Duration {
+	now := time.Now()
+	payload, err := compress(a.codec, CompressionLevelDefault, a.collect(now))
+	if err != nil {
+		Logger.Printf("client/telemetry failed to compress metrics: %v\n", err)
+		return a.interval()
+	}
+	if limit := a.subscription.TelemetryMaxBytes; limit > 0 && len(payload) > int(limit) {
+		Logger.Printf("client/telemetry dropping %d bytes of metrics, the limit is %d\n", len(payload), limit)
+		return a.interval()
+	}
+
+	broker := a.client.LeastLoadedBroker()
+	if broker == nil {
+		return a.interval()
+	}
+	response, err := broker.PushTelemetry(&PushTelemetryRequest{
+		ClientInstanceID: a.instanceID,
+		SubscriptionID:   a.subscription.SubscriptionID,
+		Terminating:      terminating,
+		CompressionType:  a.codec,
+		Metrics:          payload,
+	})
+	if err != nil {
+		DebugLogger.Printf("client/telemetry failed to push metrics to broker %d: %v\n", broker.ID(), err)
+		return a.interval()
+	}
+
+	switch response.Err {
+	case ErrNoError, ErrThrottlingQuotaExceeded, ErrTelemetryTooLarge:
+		return a.interval()
+	case ErrUnknownSubscriptionId, ErrUnsupportedCompressionType:
+		// the subscription changed on the broker
+		a.subscription = nil
+		return 0
+	case ErrInvalidRequest, ErrInvalidRecord:
+		Logger.Printf("client/telemetry broker %d rejected the pushed metrics: %v\n", broker.ID(), response.Err)
+		return -1
+	default:
+		DebugLogger.Printf("client/telemetry failed to push metrics to broker %d: %v\n", broker.ID(), response.Err)
+		return a.interval()
+	}
+}
+
+// collect encodes the requested metrics, turning sums into deltas since the
+// last push if the subscription asks for it.
+func (a *telemetryAgent) collect(now time.Time) []byte {
+	collected := collectTelemetryMetrics(a.conf.MetricRegistry, a.subscription.RequestedMetrics)
+
+	start := a.start
+	if a.subscription.DeltaTemporality {
+		start = a.lastPush
+		a.toDeltas(collected)
+	}
+	a.lastPush = now
+
+	return encodeOTLPMetrics(collected, start, now, a.subscription.DeltaTemporality)
+}
+
+// toDeltas replaces the value of every sum with its increase since the
+// previous push.
+func (a *telemetryAgent) toDeltas(collected []*telemetryMetric) {
+	current := make(map[string]int64, len(a.previous))
+	for _, m := range collected {
+		if m.kind != telemetrySum {
+			continue
+		}
+		key := telemetryMetricKey(m)
+		current[key] = m.intValue
+		m.intValue -= a.previous[key]
+	}
+	a.previous = current
+}
+
+func (a *telemetryAgent) interval() time.Duration {
+	if a.subscription == nil || a.subscription.PushIntervalMs <= 0 {
+		return telemetryDefaultPushInterval
+	}
+	return time.Duration(a.subscription.PushIntervalMs) * time.Millisecond
+}
+
+func telemetryMetricKey(m *telemetryMetric) string {
+	var key strings.Builder
+	key.WriteString(m.name)
+	for _, k := range sortedAttributeKeys(m.attributes) {
+		key.WriteString("," + k + "=" + m.attributes[k])
+	}
+	return key.String()
+}
+
+// telemetryCodec picks the first compression codec accepted by the broker.
+func telemetryCodec(accepted []CompressionCodec) CompressionCodec {
+	for _, codec := range accepted {
+		switch codec {
+		case CompressionNone, CompressionGZIP, CompressionSnappy, CompressionLZ4, CompressionZSTD:
+			return codec
+		}
+	}
+	return CompressionNone
+}
+
+// supportsAPI reports whether the broker advertised the API key in its
+// ApiVersions response. It assumes support when the versions are not known.
+func (b *Broker) supportsAPI(key int16) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.brokerAPIVersions == nil {
+		return true
+	}
+	_, ok := b.brokerAPIVersions[key]
+	return ok
+}
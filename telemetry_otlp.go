@@ -0,0 +1,259 @@
+package sarama
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// telemetryMetricPrefix is prepended to the name of every metric pushed to the
+// brokers, see telemetryMetricName.
+const telemetryMetricPrefix = "sarama."
+
+// telemetryQuantiles are the quantiles reported for histograms and timers.
+var telemetryQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// OTLP aggregation temporalities, see opentelemetry/proto/metrics/v1/metrics.proto
+const (
+	otlpTemporalityDelta      = 1
+	otlpTemporalityCumulative = 2
+)
+
+// protoBuffer is a minimal protocol buffers writer, just enough to encode the
+// OTLP MetricsData message without depending on the OpenTelemetry protos.
+type protoBuffer struct {
+	buf []byte
+}
+
+func (p *protoBuffer) key(field, wireType int) {
+	p.varint(uint64(field<<3 | wireType))
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	p.buf = binary.AppendUvarint(p.buf, v)
+}
+
+func (p *protoBuffer) uint64(field int, v uint64) {
+	p.key(field, 0)
+	p.varint(v)
+}
+
+func (p *protoBuffer) bool(field int, v bool) {
+	if v {
+		p.uint64(field, 1)
+	}
+}
+
+func (p *protoBuffer) fixed64(field int, v uint64) {
+	p.key(field, 1)
+	p.buf = binary.LittleEndian.AppendUint64(p.buf, v)
+}
+
+func (p *protoBuffer) double(field int, v float64) {
+	p.fixed64(field, math.Float64bits(v))
+}
+
+func (p *protoBuffer) bytes(field int, v []byte) {
+	p.key(field, 2)
+	p.varint(uint64(len(v)))
+	p.buf = append(p.buf, v...)
+}
+
+func (p *protoBuffer) string(field int, v string) {
+	if v != "" {
+		p.bytes(field, []byte(v))
+	}
+}
+
+func (p *protoBuffer) message(field int, encode func(m *protoBuffer)) {
+	m := &protoBuffer{}
+	encode(m)
+	p.bytes(field, m.buf)
+}
+
+// telemetryMetric is a single metric data point in the OTLP data model.
+type telemetryMetric struct {
+	name       string
+	attributes map[string]string
+	kind       telemetryMetricKind
+
+	intValue    int64
+	doubleValue float64
+	isDouble    bool
+
+	// summaries only
+	count     uint64
+	sum       float64
+	quantiles []float64
+}
+
+type telemetryMetricKind int
+
+const (
+	telemetryGauge telemetryMetricKind = iota
+	telemetrySum
+	telemetrySummary
+)
+
+// telemetryMetricName converts the name of a MetricRegistry metric into the
+// dotted name pushed to the brokers, moving the broker, topic and partition
+// the metric is scoped to into attributes. For example
+// "incoming-byte-rate-for-broker-1" becomes "sarama.incoming.byte.rate" with
+// the attribute broker.id=1.
+func telemetryMetricName(name string) (string, map[string]string) {
+	var attributes map[string]string
+	if i := strings.Index(name, "-for-broker-"); i >= 0 {
+		attributes = map[string]string{"broker.id": name[i+len("-for-broker-"):]}
+		name = name[:i]
+	} else if i := strings.Index(name, "-for-topic-"); i >= 0 {
+		topic := name[i+len("-for-topic-"):]
+		attributes = map[string]string{}
+		if j := strings.LastIndex(topic, "-partition-"); j >= 0 {
+			if _, err := strconv.ParseInt(topic[j+len("-partition-"):], 10, 32); err == nil {
+				attributes["partition"] = topic[j+len("-partition-"):]
+				topic = topic[:j]
+			}
+		}
+		attributes["topic"] = topic
+		name = name[:i]
+	}
+	return telemetryMetricPrefix + strings.ReplaceAll(name, "-", "."), attributes
+}
+
+// collectTelemetryMetrics snapshots the metrics of the registry whose pushed
+// name starts with one of the requested prefixes.
+func collectTelemetryMetrics(registry metrics.Registry, requested []string) []*telemetryMetric {
+	matches := func(name string) bool {
+		for _, prefix := range requested {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var collected []*telemetryMetric
+	add := func(m *telemetryMetric) {
+		if matches(m.name) {
+			collected = append(collected, m)
+		}
+	}
+
+	registry.Each(func(registered string, i any) {
+		name, attributes := telemetryMetricName(registered)
+		switch metric := i.(type) {
+		case metrics.Counter:
+			add(&telemetryMetric{name: name, attributes: attributes, kind: telemetryGauge, intValue: metric.Snapshot().Count()})
+		case metrics.Gauge:
+			add(&telemetryMetric{name: name, attributes: attributes, kind: telemetryGauge, intValue: metric.Snapshot().Value()})
+		case metrics.GaugeFloat64:
+			add(&telemetryMetric{name: name, attributes: attributes, kind: telemetryGauge, doubleValue: metric.Snapshot().Value(), isDouble: true})
+		case metrics.Meter:
+			// like the Java client, a rate is paired with the total it is
+			// computed from
+			snapshot := metric.Snapshot()
+			add(&telemetryMetric{name: name, attributes: attributes, kind: telemetryGauge, doubleValue: snapshot.Rate1(), isDouble: true})
+			add(&telemetryMetric{name: strings.TrimSuffix(name, ".rate") + ".total", attributes: attributes, kind: telemetrySum, intValue: snapshot.Count()})
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			add(&telemetryMetric{
+				name: name, attributes: attributes, kind: telemetrySummary,
+				count: uint64(snapshot.Count()), sum: float64(snapshot.Sum()), quantiles: snapshot.Percentiles(telemetryQuantiles),
+			})
+		case metrics.Timer:
+			snapshot := metric.Snapshot()
+			add(&telemetryMetric{
+				name: name, attributes: attributes, kind: telemetrySummary,
+				count: uint64(snapshot.Count()), sum: float64(snapshot.Sum()), quantiles: snapshot.Percentiles(telemetryQuantiles),
+			})
+		}
+	})
+
+	sort.SliceStable(collected, func(i, j int) bool { return collected[i].name < collected[j].name })
+	return collected
+}
+
+// encodeOTLPMetrics encodes the metrics as an OTLP MetricsData message. Sums
+// start at start and are reported as deltas since then if delta is true.
+func encodeOTLPMetrics(collected []*telemetryMetric, start, now time.Time, delta bool) []byte {
+	startNano, nowNano := uint64(start.UnixNano()), uint64(now.UnixNano())
+	temporality := uint64(otlpTemporalityCumulative)
+	if delta {
+		temporality = otlpTemporalityDelta
+	}
+
+	dataPoint := func(p *protoBuffer, m *telemetryMetric) {
+		for _, k := range sortedAttributeKeys(m.attributes) {
+			p.message(7, func(kv *protoBuffer) { // attributes
+				kv.string(1, k)
+				kv.message(2, func(v *protoBuffer) { v.string(1, m.attributes[k]) })
+			})
+		}
+		p.fixed64(2, startNano) // start_time_unix_nano
+		p.fixed64(3, nowNano)   // time_unix_nano
+	}
+	numberDataPoint := func(p *protoBuffer, m *telemetryMetric) {
+		dataPoint(p, m)
+		if m.isDouble {
+			p.double(4, m.doubleValue) // as_double
+		} else {
+			p.fixed64(6, uint64(m.intValue)) // as_int
+		}
+	}
+
+	out := &protoBuffer{}
+	out.message(1, func(rm *protoBuffer) { // MetricsData.resource_metrics
+		rm.message(1, func(*protoBuffer) {})  // ResourceMetrics.resource
+		rm.message(2, func(sm *protoBuffer) { // ResourceMetrics.scope_metrics
+			sm.message(1, func(scope *protoBuffer) { // ScopeMetrics.scope
+				scope.string(1, "github.com/IBM/sarama")
+			})
+			for _, m := range collected {
+				sm.message(2, func(metric *protoBuffer) { // ScopeMetrics.metrics
+					metric.string(1, m.name)
+					switch m.kind {
+					case telemetryGauge:
+						metric.message(5, func(gauge *protoBuffer) {
+							gauge.message(1, func(p *protoBuffer) { numberDataPoint(p, m) })
+						})
+					case telemetrySum:
+						metric.message(7, func(sum *protoBuffer) {
+							sum.message(1, func(p *protoBuffer) { numberDataPoint(p, m) })
+							sum.uint64(2, temporality) // aggregation_temporality
+							sum.bool(3, true)          // is_monotonic
+						})
+					case telemetrySummary:
+						metric.message(11, func(summary *protoBuffer) {
+							summary.message(1, func(p *protoBuffer) {
+								dataPoint(p, m)
+								p.fixed64(4, m.count) // count
+								p.double(5, m.sum)    // sum
+								for i, q := range telemetryQuantiles {
+									p.message(6, func(vq *protoBuffer) { // quantile_values
+										vq.double(1, q)
+										vq.double(2, m.quantiles[i])
+									})
+								}
+							})
+						})
+					}
+				})
+			}
+		})
+	})
+	return out.buf
+}
+
+func sortedAttributeKeys(attributes map[string]string) []string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
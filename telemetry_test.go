@@ -0,0 +1,184 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestTelemetryMetricName(t *testing.T) {
+	for registered, expected := range map[string]struct {
+		name       string
+		attributes map[string]string
+	}{
+		"request-rate":                       {"sarama.request.rate", nil},
+		"incoming-byte-rate-for-broker-1":    {"sarama.incoming.byte.rate", map[string]string{"broker.id": "1"}},
+		"record-send-rate-for-topic-my_t":    {"sarama.record.send.rate", map[string]string{"topic": "my_t"}},
+		"batch-size-for-topic-t-partition-2": {"sarama.batch.size", map[string]string{"topic": "t", "partition": "2"}},
+	} {
+		name, attributes := telemetryMetricName(registered)
+		assert.Equal(t, expected.name, name, registered)
+		assert.Equal(t, expected.attributes, attributes, registered)
+	}
+}
+
+func TestCollectTelemetryMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterMeter("request-rate", registry).Mark(3)
+	metrics.GetOrRegisterCounter("requests-in-flight", registry).Inc(2)
+	getOrRegisterHistogram("request-latency-in-ms", registry).Update(10)
+	metrics.GetOrRegisterMeter("incoming-byte-rate", registry).Mark(100)
+
+	collected := collectTelemetryMetrics(registry, []string{"sarama.request"})
+	names := make([]string, 0, len(collected))
+	for _, m := range collected {
+		names = append(names, m.name)
+	}
+	assert.Equal(t, []string{"sarama.request.latency.in.ms", "sarama.request.rate", "sarama.request.total", "sarama.requests.in.flight"}, names)
+	assert.Equal(t, telemetrySummary, collected[0].kind)
+	assert.Equal(t, uint64(1), collected[0].count)
+	assert.Equal(t, telemetrySum, collected[2].kind)
+	assert.Equal(t, int64(3), collected[2].intValue)
+	assert.Equal(t, int64(2), collected[3].intValue)
+
+	assert.Empty(t, collectTelemetryMetrics(registry, nil))
+	assert.Len(t, collectTelemetryMetrics(registry, []string{""}), 6)
+}
+
+func TestEncodeOTLPMetrics(t *testing.T) {
+	gauge := &telemetryMetric{name: "sarama.x", kind: telemetryGauge, intValue: 5}
+
+	expected := []byte{
+		0x0a, 72, // MetricsData.resource_metrics
+		0x0a, 0, // ResourceMetrics.resource
+		0x12, 68, // ResourceMetrics.scope_metrics
+		0x0a, 23, // ScopeMetrics.scope
+		0x0a, 21, 'g', 'i', 't', 'h', 'u', 'b', '.', 'c', 'o', 'm', '/', 'I', 'B', 'M', '/', 's', 'a', 'r', 'a', 'm', 'a', // name
+		0x12, 41, // ScopeMetrics.metrics
+		0x0a, 8, 's', 'a', 'r', 'a', 'm', 'a', '.', 'x', // Metric.name
+		0x2a, 29, // Metric.gauge
+		0x0a, 27, // Gauge.data_points
+		0x11, 1, 0, 0, 0, 0, 0, 0, 0, // start_time_unix_nano
+		0x19, 2, 0, 0, 0, 0, 0, 0, 0, // time_unix_nano
+		0x31, 5, 0, 0, 0, 0, 0, 0, 0, // as_int
+	}
+	assert.Equal(t, expected, encodeOTLPMetrics([]*telemetryMetric{gauge}, time.Unix(0, 1), time.Unix(0, 2), false))
+}
+
+func TestTelemetryAgentDeltas(t *testing.T) {
+	agent := &telemetryAgent{}
+	sum := func(v int64) []*telemetryMetric {
+		return []*telemetryMetric{
+			{name: "sarama.request.total", kind: telemetrySum, intValue: v},
+			{name: "sarama.requests.in.flight", kind: telemetryGauge, intValue: v},
+		}
+	}
+
+	first := sum(5)
+	agent.toDeltas(first)
+	assert.Equal(t, int64(5), first[0].intValue)
+
+	second := sum(8)
+	agent.toDeltas(second)
+	assert.Equal(t, int64(3), second[0].intValue)
+	assert.Equal(t, int64(8), second[1].intValue, "gauges are never turned into deltas")
+}
+
+func TestTelemetryCodec(t *testing.T) {
+	assert.Equal(t, CompressionNone, telemetryCodec(nil))
+	assert.Equal(t, CompressionZSTD, telemetryCodec([]CompressionCodec{CompressionZSTD, CompressionGZIP}))
+	assert.Equal(t, CompressionGZIP, telemetryCodec([]CompressionCodec{42, CompressionGZIP}))
+}
+
+func TestClientTelemetryPush(t *testing.T) {
+	instanceID := Uuid{1, 2, 3}
+	var (
+		lock   sync.Mutex
+		pushes []*PushTelemetryRequest
+	)
+	pushed := make(chan none, 10)
+
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerFuncByMap(map[string]requestHandlerFunc{
+		"MetadataRequest": func(req *request) encoderWithHeader {
+			return NewMockMetadataResponse(t).SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).For(req.body)
+		},
+		"GetTelemetrySubscriptionsRequest": func(req *request) encoderWithHeader {
+			return &GetTelemetrySubscriptionsResponse{
+				ClientInstanceID: instanceID,
+				SubscriptionID:   7,
+				PushIntervalMs:   20,
+				RequestedMetrics: []string{"sarama.request."},
+			}
+		},
+		"PushTelemetryRequest": func(req *request) encoderWithHeader {
+			lock.Lock()
+			pushes = append(pushes, req.body.(*PushTelemetryRequest))
+			lock.Unlock()
+			select {
+			case pushed <- none{}:
+			default:
+			}
+			return &PushTelemetryResponse{}
+		},
+	})
+
+	config := NewTestConfig()
+	config.Version = V3_7_0_0
+	config.Telemetry.Enabled = true
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	assert.NoError(t, err)
+
+	for range 2 {
+		select {
+		case <-pushed:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a telemetry push")
+		}
+	}
+	assert.NoError(t, client.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.GreaterOrEqual(t, len(pushes), 3)
+	for _, push := range pushes[:len(pushes)-1] {
+		assert.Equal(t, instanceID, push.ClientInstanceID)
+		assert.Equal(t, int32(7), push.SubscriptionID)
+		assert.Equal(t, CompressionNone, push.CompressionType)
+		assert.False(t, push.Terminating)
+		assert.True(t, bytes.Contains(push.Metrics, []byte("sarama.request.rate")))
+		assert.False(t, bytes.Contains(push.Metrics, []byte("sarama.incoming.byte.rate")))
+	}
+	assert.True(t, pushes[len(pushes)-1].Terminating, "the last push should be terminating")
+}
+
+func TestClientTelemetryUnsupported(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":    NewMockMetadataResponse(t).SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V3_7_0_0
+	config.ApiVersionsRequest = true
+	config.Telemetry.Enabled = true
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	assert.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, client.Close())
+
+	for _, rr := range seedBroker.History() {
+		_, ok := rr.Request.(*GetTelemetrySubscriptionsRequest)
+		assert.False(t, ok, "telemetry requests should not be sent to brokers that do not support them")
+	}
+}
@@ -0,0 +1,299 @@
+package sarama
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// tlsReloadDefaultInterval is how often the files of a TLSFileReloader
+	// are checked for changes when no interval is configured.
+	tlsReloadDefaultInterval = 30 * time.Second
+	// tlsExpiryWarningFraction is the fraction of the validity of a client
+	// certificate left when a TLSFileReloader starts warning that it is
+	// about to expire, unless an explicit ExpiryWarning is configured.
+	tlsExpiryWarningFraction = 0.1
+)
+
+// TLSConfigProvider is the interface that encapsulates how implementers can
+// supply a TLS configuration that changes over time, such as one whose client
+// certificate is rotated.
+type TLSConfigProvider interface {
+	// TLSConfig returns the configuration of a new connection. It is called
+	// every time a connection to a broker is opened, so it should return
+	// quickly. The returned configuration must not be modified afterwards.
+	TLSConfig() (*tls.Config, error)
+}
+
+// TLSFileReloaderConfig configures a TLSFileReloader.
+type TLSFileReloaderConfig struct {
+	// CertFile and KeyFile are the PEM encoded client certificate, optionally
+	// followed by its intermediates, and its private key. Both are optional
+	// if the brokers do not require client authentication.
+	CertFile string
+	KeyFile  string
+	// CAFile is the optional PEM bundle of the CAs trusted to verify the
+	// brokers (defaults to the RootCAs of Base, or the system pool).
+	CAFile string
+	// Base is the optional configuration the certificates are added to, it is
+	// cloned and left untouched (defaults to a TLS 1.2+ configuration).
+	Base *tls.Config
+	// ReloadInterval is how often the files are checked for changes
+	// (defaults to 30s).
+	ReloadInterval time.Duration
+	// ExpiryWarning is how long before the client certificate expires a
+	// warning is logged (defaults to a tenth of the certificate's validity).
+	ExpiryWarning time.Duration
+	// Logger receives the reloads, the failures to reload and the expiry
+	// warnings (defaults to the package Logger at creation).
+	Logger StdLogger
+}
+
+// TLSFileReloader is a TLSConfigProvider loading the client certificate and
+// the CA bundle from files and reloading them when they change, so that
+// certificates rotated by tools like cert-manager are used by new broker
+// connections without recreating the client. Existing connections keep the
+// certificate they were opened with.
+//
+// The files are polled every ReloadInterval. If they cannot be loaded, for
+// example while a rotation is in progress, the previous certificates are kept
+// and the load is retried on the next poll. A warning is logged once per
+// certificate when it is close to expiring.
+type TLSFileReloader struct {
+	config TLSFileReloaderConfig
+	now    func() time.Time
+
+	lock     sync.RWMutex
+	current  *tls.Config
+	notAfter time.Time
+	warning  time.Duration
+	warned   time.Time
+	stamps   map[string]tlsFileStamp
+
+	closer    chan none
+	closeOnce sync.Once
+	done      chan none
+}
+
+type tlsFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewTLSFileReloader loads the configured files and starts watching them for
+// changes until Close is called. Set it as Config.Net.TLS.ConfigProvider.
+func NewTLSFileReloader(config TLSFileReloaderConfig) (*TLSFileReloader, error) {
+	switch {
+	case (config.CertFile == "") != (config.KeyFile == ""):
+		return nil, ConfigurationError("TLSFileReloaderConfig.CertFile and KeyFile must be set together")
+	case config.CertFile == "" && config.CAFile == "":
+		return nil, ConfigurationError("TLSFileReloaderConfig requires a CertFile and KeyFile or a CAFile")
+	case config.ReloadInterval < 0:
+		return nil, ConfigurationError("TLSFileReloaderConfig.ReloadInterval must be >= 0")
+	case config.ExpiryWarning < 0:
+		return nil, ConfigurationError("TLSFileReloaderConfig.ExpiryWarning must be >= 0")
+	}
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = tlsReloadDefaultInterval
+	}
+	if config.Logger == nil {
+		config.Logger = Logger
+	}
+
+	r := &TLSFileReloader{
+		config: config,
+		now:    time.Now,
+		closer: make(chan none),
+		done:   make(chan none),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	go withRecover(r.watch)
+	return r, nil
+}
+
+// TLSConfig returns the configuration built from the last successfully
+// loaded files.
+func (r *TLSFileReloader) TLSConfig() (*tls.Config, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.current, nil
+}
+
+// NotAfter returns when the current client certificate expires, or the zero
+// time if there is none.
+func (r *TLSFileReloader) NotAfter() time.Time {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.notAfter
+}
+
+// Reload loads the files immediately. On error the previous configuration is
+// kept.
+func (r *TLSFileReloader) Reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.config.Base != nil {
+		config = r.config.Base.Clone()
+	}
+
+	var notAfter time.Time
+	var validity time.Duration
+	if r.config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load the TLS client certificate: %w", err)
+		}
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return fmt.Errorf("failed to parse the TLS client certificate: %w", err)
+			}
+		}
+		notAfter = cert.Leaf.NotAfter
+		validity = cert.Leaf.NotAfter.Sub(cert.Leaf.NotBefore)
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if r.config.CAFile != "" {
+		pem, err := os.ReadFile(r.config.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in the TLS CA file %s", r.config.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	warning := r.config.ExpiryWarning
+	if warning == 0 {
+		warning = time.Duration(float64(validity) * tlsExpiryWarningFraction)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.current != nil {
+		r.config.Logger.Printf("Reloaded the TLS configuration from %s\n", r.files())
+	}
+	r.current, r.notAfter, r.warning, r.stamps = config, notAfter, warning, stamps
+	r.checkExpiry()
+	return nil
+}
+
+// Close stops watching the files. The last configuration is still returned
+// by TLSConfig.
+func (r *TLSFileReloader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closer)
+		<-r.done
+	})
+	return nil
+}
+
+func (r *TLSFileReloader) watch() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.closer:
+			return
+		case <-ticker.C:
+		}
+
+		if r.changed() {
+			if err := r.Reload(); err != nil {
+				r.config.Logger.Printf("Failed to reload the TLS configuration from %s, keeping the previous one: %v\n", r.files(), err)
+			}
+		}
+		r.lock.Lock()
+		r.checkExpiry()
+		r.lock.Unlock()
+	}
+}
+
+// changed reports whether any file was modified since it was last loaded.
+// Files that cannot be read count as changed so that the error is logged.
+func (r *TLSFileReloader) changed() bool {
+	stamps, err := r.stat()
+	if err != nil {
+		return true
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for name, stamp := range stamps {
+		if previous, ok := r.stamps[name]; !ok || !previous.modTime.Equal(stamp.modTime) || previous.size != stamp.size {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TLSFileReloader) stat() (map[string]tlsFileStamp, error) {
+	stamps := make(map[string]tlsFileStamp, 3)
+	for _, name := range []string{r.config.CertFile, r.config.KeyFile, r.config.CAFile} {
+		if name == "" {
+			continue
+		}
+		// os.Stat follows symlinks, so the atomic swaps of Kubernetes
+		// secret volumes are seen as changes
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps[name] = tlsFileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *TLSFileReloader) files() string {
+	if r.config.CertFile == "" {
+		return r.config.CAFile
+	}
+	return r.config.CertFile
+}
+
+// checkExpiry logs once per certificate that it is about to expire. It must
+// be called with the lock held.
+func (r *TLSFileReloader) checkExpiry() {
+	if r.notAfter.IsZero() || r.warned.Equal(r.notAfter) {
+		return
+	}
+	remaining := r.notAfter.Sub(r.now())
+	if remaining > r.warning {
+		return
+	}
+	r.warned = r.notAfter
+	if remaining <= 0 {
+		r.config.Logger.Printf("The TLS client certificate %s expired at %s\n", r.config.CertFile, r.notAfter.Format(time.RFC3339))
+		return
+	}
+	r.config.Logger.Printf("The TLS client certificate %s expires in %s, at %s\n", r.config.CertFile, remaining.Round(time.Second), r.notAfter.Format(time.RFC3339))
+}
+
+// clientCertificateNotAfter returns when the first client certificate of the
+// configuration expires.
+func clientCertificateNotAfter(config *tls.Config) (time.Time, error) {
+	if config == nil || len(config.Certificates) == 0 || len(config.Certificates[0].Certificate) == 0 {
+		return time.Time{}, errors.New("no client certificate")
+	}
+	leaf := config.Certificates[0].Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(config.Certificates[0].Certificate[0]); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return leaf.NotAfter, nil
+}
@@ -0,0 +1,238 @@
+//go:build !functional
+
+package sarama
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	assert "github.com/stretchr/testify/require"
+)
+
+// tlsTestCA issues PEM encoded certificates for the TLS reloader tests.
+type tlsTestCA struct {
+	*MockTLSCA
+}
+
+func newTLSTestCA(t *testing.T) *tlsTestCA {
+	return &tlsTestCA{NewMockTLSCA(t)}
+}
+
+// issue returns a PEM encoded certificate and key for name, valid until
+// notAfter, usable by a client or a 127.0.0.1 server.
+func (ca *tlsTestCA) issue(t *testing.T, name string, notAfter time.Time) ([]byte, []byte) {
+	cert := ca.IssueCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    notAfter,
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	})
+	keyDer, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+}
+
+// writeTLSFiles writes the files and bumps their modification time so that
+// rewrites within the timestamp granularity are seen as changes.
+func writeTLSFiles(t *testing.T, files map[string][]byte) {
+	t.Helper()
+	modTime := time.Now().Add(time.Duration(len(files)) * time.Second)
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(name, content, 0o600))
+		assert.NoError(t, os.Chtimes(name, modTime, modTime))
+	}
+}
+
+func tlsClientCommonName(t *testing.T, provider TLSConfigProvider) string {
+	t.Helper()
+	config, err := provider.TLSConfig()
+	assert.NoError(t, err)
+	return config.Certificates[0].Leaf.Subject.CommonName
+}
+
+func TestTLSFileReloader(t *testing.T) {
+	ca := newTLSTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+
+	cert, key := ca.issue(t, "client-1", time.Now().Add(time.Hour))
+	writeTLSFiles(t, map[string][]byte{certFile: cert, keyFile: key, caFile: ca.PEM()})
+
+	reloader, err := NewTLSFileReloader(TLSFileReloaderConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		CAFile:         caFile,
+		Base:           &tls.Config{MinVersion: tls.VersionTLS13},
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer reloader.Close()
+
+	config, err := reloader.TLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion, "the base configuration is kept")
+	assert.NotNil(t, config.RootCAs)
+	assert.Equal(t, "client-1", tlsClientCommonName(t, reloader))
+
+	// a rotation in progress with a certificate not matching the key keeps
+	// the previous configuration
+	cert, key = ca.issue(t, "client-2", time.Now().Add(2*time.Hour))
+	writeTLSFiles(t, map[string][]byte{certFile: cert})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "client-1", tlsClientCommonName(t, reloader))
+
+	writeTLSFiles(t, map[string][]byte{keyFile: key})
+	assert.Eventually(t, func() bool {
+		return tlsClientCommonName(t, reloader) == "client-2"
+	}, time.Second, 10*time.Millisecond)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), reloader.NotAfter(), time.Minute)
+
+	// removed files are reported but the last configuration is kept
+	assert.NoError(t, os.Remove(caFile))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "client-2", tlsClientCommonName(t, reloader))
+}
+
+func TestTLSFileReloaderExpiryWarning(t *testing.T) {
+	ca := newTLSTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert, key := ca.issue(t, "client", time.Now().Add(time.Minute))
+	writeTLSFiles(t, map[string][]byte{certFile: cert, keyFile: key})
+
+	var buf bytes.Buffer
+	reloader, err := NewTLSFileReloader(TLSFileReloaderConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: 10 * time.Millisecond,
+		ExpiryWarning:  time.Hour,
+		Logger:         log.New(&buf, "", 0),
+	})
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, reloader.Close())
+
+	// the watcher has stopped, so the buffer is no longer written to
+	messages := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, messages, 1, "the warning is logged once per certificate")
+	assert.Contains(t, messages[0], "The TLS client certificate "+certFile+" expires in")
+}
+
+func TestTLSFileReloaderConfigValidation(t *testing.T) {
+	for _, test := range []struct {
+		config TLSFileReloaderConfig
+		err    string
+	}{
+		{TLSFileReloaderConfig{CertFile: "c"}, "TLSFileReloaderConfig.CertFile and KeyFile must be set together"},
+		{TLSFileReloaderConfig{}, "TLSFileReloaderConfig requires a CertFile and KeyFile or a CAFile"},
+		{TLSFileReloaderConfig{CAFile: "ca", ReloadInterval: -1}, "TLSFileReloaderConfig.ReloadInterval must be >= 0"},
+		{TLSFileReloaderConfig{CAFile: "ca", ExpiryWarning: -1}, "TLSFileReloaderConfig.ExpiryWarning must be >= 0"},
+	} {
+		_, err := NewTLSFileReloader(test.config)
+		var target ConfigurationError
+		assert.ErrorAs(t, err, &target)
+		assert.Equal(t, test.err, string(target))
+	}
+
+	_, err := NewTLSFileReloader(TLSFileReloaderConfig{CAFile: filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBrokerTLSConfigProvider(t *testing.T) {
+	ca := newTLSTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	cert, key := ca.issue(t, "client-1", time.Now().Add(time.Hour))
+	writeTLSFiles(t, map[string][]byte{certFile: cert, keyFile: key, caFile: ca.PEM()})
+
+	var (
+		lock    sync.Mutex
+		clients []string
+	)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{ca.IssueServerCertificate(t)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.CertPool(),
+		MinVersion:   tls.VersionTLS12,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			lock.Lock()
+			defer lock.Unlock()
+			clients = append(clients, state.PeerCertificates[0].Subject.CommonName)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	mockBroker := NewMockBrokerListener(t, 1, listener)
+	defer mockBroker.Close()
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+
+	reloader, err := NewTLSFileReloader(TLSFileReloaderConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		CAFile:         caFile,
+		ReloadInterval: time.Hour,
+	})
+	assert.NoError(t, err)
+	defer reloader.Close()
+
+	conf := NewTestConfig()
+	conf.Version = V1_0_0_0
+	conf.Net.TLS.Enable = true
+	conf.Net.TLS.ConfigProvider = reloader
+
+	connect := func() {
+		broker := NewBroker(mockBroker.Addr())
+		assert.NoError(t, broker.Open(conf))
+		defer broker.Close()
+		_, err := broker.ApiVersions(&ApiVersionsRequest{})
+		assert.NoError(t, err)
+		notAfter := metrics.GetOrRegisterGauge("tls-client-certificate-not-after", conf.MetricRegistry).Value()
+		assert.Equal(t, reloader.NotAfter().Unix(), notAfter)
+	}
+
+	connect()
+	cert, key = ca.issue(t, "client-2", time.Now().Add(2*time.Hour))
+	writeTLSFiles(t, map[string][]byte{certFile: cert, keyFile: key})
+	assert.NoError(t, reloader.Reload())
+	connect()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"client-1", "client-2"}, clients, "new connections use the rotated certificate")
+}
+
+func TestBrokerTLSConfigProviderFailure(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+
+	providerErr := errors.New("no certificate")
+	conf := NewTestConfig()
+	conf.Net.TLS.Enable = true
+	conf.Net.TLS.ConfigProvider = tlsConfigProviderFunc(func() (*tls.Config, error) { return nil, providerErr })
+
+	broker := NewBroker(mockBroker.Addr())
+	assert.NoError(t, broker.Open(conf))
+	_, err := broker.Connected()
+	assert.ErrorIs(t, err, providerErr)
+}
+
+type tlsConfigProviderFunc func() (*tls.Config, error)
+
+func (f tlsConfigProviderFunc) TLSConfig() (*tls.Config, error) { return f() }
@@ -311,7 +311,10 @@ var (
 	}
 	MinVersion = V0_8_2_0
 	MaxVersion = V4_3_1_0
-	// DefaultVersion is 2.8 as Sarama has full protocol coverage for that version
+	// DefaultVersion is 2.8 as Sarama has full protocol coverage for that
+	// version. It is no longer the default of Config.Version, which is left
+	// unset to negotiate up to MaxVersion with each broker, and is only the
+	// fallback of ParseKafkaVersion and of the examples.
 	DefaultVersion = V2_8_0_0
 )
 